@@ -0,0 +1,84 @@
+package areas
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const updateAreaQuery = `
+	UPDATE mrbs.areas SET
+		display_name = ?,
+		resolution = ?::integer * interval '1 minute',
+		default_duration = ?::integer * interval '1 minute',
+		morning_starts = ?::time,
+		evening_ends = ?::time,
+		max_per_day = ?,
+		max_per_week = ?,
+		max_duration = ?::integer * interval '1 minute',
+		buffer_duration = ?::integer * interval '1 minute'
+	WHERE area_id = ?`
+
+// HandleEditArea replaces the policy of an area. Existing bookings are not re-checked against the new policy.
+func HandleEditArea(c *gin.Context) {
+	areaID, err := strconv.ParseUint(c.Param("area-id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid area_id provided",
+		})
+		return
+	}
+
+	area, ok := models.GetArea(uint(areaID))
+	if !ok {
+		log.Warn().Uint64("area_id", areaID).Msg("Area not found")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Area not found",
+		})
+		return
+	}
+
+	var req AreaRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		log.Error().Err(err).Msg("Error binding request to area")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		log.Warn().Interface("area request", req).Msg(msg)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	before := *area
+	if err = db.GormDB.WithContext(c).Exec(updateAreaQuery, append(req.args(), areaID)...).Error; err != nil {
+		log.Error().Err(err).Uint64("area_id", areaID).Msg("Error editing area")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	refreshAreas()
+	if after, ok := models.GetArea(uint(areaID)); ok {
+		audit.Record(c, audit.ActionAreaEdit, audit.TargetArea, areaID, before, *after)
+	}
+
+	log.Info().Uint64("area_id", areaID).Interface("area", req).Msg("Area details edited successfully")
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Area %s updated successfully", req.DisplayName),
+	})
+}
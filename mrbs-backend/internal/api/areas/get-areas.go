@@ -0,0 +1,13 @@
+package areas
+
+import (
+	"net/http"
+
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HandleGetAllAreas(c *gin.Context) {
+	c.JSON(http.StatusOK, models.GetAreas())
+}
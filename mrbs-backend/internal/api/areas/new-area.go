@@ -0,0 +1,119 @@
+package areas
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// AreaRequest is used to create an area, and to replace the policy of an existing area.
+// Units follow models.Area: durations in minutes, quotas in hours, times as HH:MM. Omit a limit for no limit.
+type AreaRequest struct {
+	DisplayName     string `json:"display_name" binding:"required"`
+	Resolution      int    `json:"resolution" binding:"required"`
+	DefaultDuration int    `json:"default_duration" binding:"required"`
+	MorningStarts   string `json:"morning_starts" binding:"required"`
+	EveningEnds     string `json:"evening_ends" binding:"required"`
+	MaxPerDay       *int   `json:"max_per_day"`
+	MaxPerWeek      *int   `json:"max_per_week"`
+	MaxDuration     *int   `json:"max_duration"`
+	BufferDuration  int    `json:"buffer_duration"`
+}
+
+// validate checks the request for values the booking checks cannot handle. Returns the error message for the client.
+func (r *AreaRequest) validate() string {
+	r.DisplayName = strings.TrimSpace(r.DisplayName)
+	if r.DisplayName == "" {
+		return "Area name cannot be empty"
+	}
+	if r.Resolution <= 0 || r.DefaultDuration <= 0 {
+		return "resolution and default_duration must be positive"
+	}
+	if r.DefaultDuration%r.Resolution != 0 {
+		return "default_duration must be a multiple of resolution"
+	}
+	if _, err := time.Parse("15:04", r.MorningStarts); err != nil {
+		return "morning_starts must be in HH:MM format"
+	}
+	if _, err := time.Parse("15:04", r.EveningEnds); err != nil {
+		return "evening_ends must be in HH:MM format"
+	}
+	for _, limit := range []*int{r.MaxPerDay, r.MaxPerWeek, r.MaxDuration} {
+		if limit != nil && *limit <= 0 {
+			return "Limits must be positive, omit the limit to remove it"
+		}
+	}
+	if r.BufferDuration < 0 {
+		return "buffer_duration cannot be negative"
+	}
+	return ""
+}
+
+// args returns the request in the order of the columns used by insertAreaQuery and updateAreaQuery.
+func (r *AreaRequest) args() []any {
+	return []any{r.DisplayName, r.Resolution, r.DefaultDuration, r.MorningStarts, r.EveningEnds,
+		r.MaxPerDay, r.MaxPerWeek, r.MaxDuration, r.BufferDuration}
+}
+
+// Minutes are converted back into intervals, NULL * interval is NULL so omitted limits are stored as NULL.
+const insertAreaQuery = `
+	INSERT INTO mrbs.areas (display_name, resolution, default_duration, morning_starts, evening_ends,
+		max_per_day, max_per_week, max_duration, buffer_duration)
+	VALUES (?, ?::integer * interval '1 minute', ?::integer * interval '1 minute', ?::time, ?::time,
+		?, ?, ?::integer * interval '1 minute', ?::integer * interval '1 minute')
+	RETURNING area_id`
+
+func HandleNewArea(c *gin.Context) {
+	var req AreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error().Err(err).Msg("Error binding request to area")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		log.Warn().Interface("area request", req).Msg(msg)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	var areaID uint
+	if err := db.GormDB.WithContext(c).Raw(insertAreaQuery, req.args()...).Scan(&areaID).Error; err != nil {
+		log.Error().Err(err).Interface("area request", req).Msg("Error creating area")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	refreshAreas()
+	if area, ok := models.GetArea(areaID); ok {
+		audit.Record(c, audit.ActionAreaCreate, audit.TargetArea, areaID, nil, *area)
+	}
+
+	log.Info().Uint("area_id", areaID).Str("display_name", req.DisplayName).Msg("Area created")
+	c.JSON(http.StatusCreated, gin.H{
+		"message": fmt.Sprintf("%s created successfully", req.DisplayName),
+		"area_id": areaID,
+	})
+}
+
+// refreshAreas reloads the area cache after a change. A failed reload is only logged, see rooms.refreshRooms.
+func refreshAreas() {
+	if err := models.InitAreas(); err != nil {
+		log.Error().Err(err).Msg("Area changed but area cache could not be refreshed")
+	}
+}
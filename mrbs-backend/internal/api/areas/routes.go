@@ -0,0 +1,14 @@
+// Package areas defines functions for admins to manage areas and their booking policy.
+package areas
+
+import (
+	"rep-mrbs/internal/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAreaRoutes(router *gin.RouterGroup) {
+	router.GET("/", api.AuthGuard(2), HandleGetAllAreas)
+	router.POST("/new", api.AuthGuard(2), HandleNewArea)
+	router.POST("/:area-id", api.AuthGuard(2), HandleEditArea)
+}
@@ -0,0 +1,160 @@
+package auditlog
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type GetAuditLogResponse struct {
+	Entries  []models.AuditEntry `json:"entries"`
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+}
+
+// HandleGetAuditLog returns a page of the audit log, newest first. Query parameters, all optional:
+//   - actor_id, action (e.g. booking.delete), target_type (e.g. booking), target_id
+//   - from, to: dates (YYYY-MM-DD), to is inclusive
+//   - page (from 1), page_size (default 50, max. 200)
+func HandleGetAuditLog(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid page",
+		})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "page_size must be between 1 and " + strconv.Itoa(maxPageSize),
+		})
+		return
+	}
+
+	entries, total, err := audit.Find(c, filter, page, pageSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching audit log")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetAuditLogResponse{
+		Entries:  entries,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// HandleExportAuditLog returns every entry matching the same filters as HandleGetAuditLog as CSV, oldest first.
+func HandleExportAuditLog(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit-log-`+time.Now().Format("20060102")+`.csv"`)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"audit_id", "time", "actor_id", "actor_name", "action", "target_type", "target_id", "before", "after", "ip_address"})
+
+	// Rows are streamed, an error after the first batch can only be logged
+	err = audit.Each(c, filter, func(entries []models.AuditEntry) error {
+		for _, e := range entries {
+			actorID := ""
+			if e.ActorID != nil {
+				actorID = strconv.FormatUint(uint64(*e.ActorID), 10)
+			}
+			_ = w.Write([]string{
+				strconv.FormatUint(e.AuditID, 10),
+				e.TimeCreated.Format(time.RFC3339),
+				actorID,
+				csvSafe(e.ActorName),
+				e.Action,
+				e.TargetType,
+				csvSafe(e.TargetID),
+				string(e.Before),
+				string(e.After),
+				e.IPAddress,
+			})
+		}
+		w.Flush()
+		return w.Error()
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error exporting audit log")
+	}
+	w.Flush()
+}
+
+func parseFilter(c *gin.Context) (audit.Filter, error) {
+	filter := audit.Filter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	if actor := c.Query("actor_id"); actor != "" {
+		actorID, err := strconv.ParseUint(actor, 10, 32)
+		if err != nil {
+			return filter, errors.New("Invalid actor_id")
+		}
+		filter.ActorID = uint(actorID)
+	}
+
+	loc := time.FixedZone("GMT", 8*3600)
+	if from := c.Query("from"); from != "" {
+		t, err := time.ParseInLocation(models.DateFormat, from, loc)
+		if err != nil {
+			return filter, errors.New("Invalid from date, use YYYY-MM-DD")
+		}
+		filter.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.ParseInLocation(models.DateFormat, to, loc)
+		if err != nil {
+			return filter, errors.New("Invalid to date, use YYYY-MM-DD")
+		}
+		filter.To = t.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+// csvSafe stops spreadsheet programs from running values as formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
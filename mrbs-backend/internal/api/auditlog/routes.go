@@ -0,0 +1,13 @@
+// Package auditlog lets admins browse and export the audit log of privileged changes.
+package auditlog
+
+import (
+	"rep-mrbs/internal/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAuditLogRoutes(router *gin.RouterGroup) {
+	router.GET("/", api.AuthGuard(2), HandleGetAuditLog)
+	router.GET("/export.csv", api.AuthGuard(2), HandleExportAuditLog)
+}
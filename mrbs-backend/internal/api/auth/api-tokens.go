@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+type NewAPITokenRequest struct {
+	Name          string `json:"name" binding:"required,max=50"`
+	Scope         string `json:"scope" binding:"required,oneof=bookings:read bookings:write admin"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"min=0"` // 0: token does not expire
+}
+
+// HandleGetAPITokens lists the personal access tokens of the user. The tokens themselves are never returned.
+func HandleGetAPITokens(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	tokens, err := gorm.G[models.APIToken](db.GormDB).Where("user_id = ?", userID).Order("time_created DESC").Find(context.Background())
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching API tokens")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error fetching API tokens, please try again later.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// HandleNewAPIToken creates a personal access token. The token is only shown in this response.
+func HandleNewAPIToken(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+	userLevel := api.GetUserLevelFromContext(c)
+
+	var req NewAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn().Err(err).Msg("Error binding new API token request")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A name (max. 50 characters) and a scope (bookings:read, bookings:write or admin) are required.",
+		})
+		return
+	}
+
+	// A token can never do more than its owner
+	if models.ScopeLevels[req.Scope] > userLevel {
+		log.Warn().Uint("user_id", userID).Str("scope", req.Scope).Msg("User requested an API token above their level")
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You cannot create a token with the " + req.Scope + " scope.",
+		})
+		return
+	}
+
+	token, err := models.GenerateAPIToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Error generating API token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating API token, please try again later.",
+		})
+		return
+	}
+
+	apiToken := models.APIToken{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenHash:   models.HashToken(token),
+		Scope:       req.Scope,
+		TimeCreated: time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiToken.ExpiresAt = &expires
+	}
+
+	if err = gorm.G[models.APIToken](db.GormDB).Create(context.Background(), &apiToken); err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error saving API token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating API token, please try again later.",
+		})
+		return
+	}
+
+	log.Info().Uint("user_id", userID).Uint("token_id", apiToken.TokenID).Str("scope", apiToken.Scope).Msg("API token created")
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "API token created. Copy it now, it will not be shown again.",
+		"token":     token,
+		"api_token": apiToken,
+	})
+}
+
+// HandleDeleteAPIToken revokes one of the user's personal access tokens.
+func HandleDeleteAPIToken(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	tokenID, err := strconv.ParseUint(c.Param("token-id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid token_id provided",
+		})
+		return
+	}
+
+	rows, err := gorm.G[models.APIToken](db.GormDB).Where("token_id = ? AND user_id = ?", tokenID, userID).Delete(context.Background())
+	if err != nil {
+		log.Error().Err(err).Uint64("token_id", tokenID).Msg("Error deleting API token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking API token, please try again later.",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "API token not found",
+		})
+		return
+	}
+
+	log.Info().Uint("user_id", userID).Uint64("token_id", tokenID).Msg("API token revoked")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API token revoked",
+	})
+}
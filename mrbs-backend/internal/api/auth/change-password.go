@@ -17,6 +17,8 @@ import (
 type ChangePasswordRequest struct {
 	OldPassword string `json:"current_password" binding:"required"`
 	NewPassword string `json:"new_password" binding:"required"`
+
+	RevokeOtherSessions bool `json:"revoke_other_sessions"` // Log out every other session of the user
 }
 
 func HandleChangePassword(c *gin.Context) {
@@ -96,10 +98,28 @@ func HandleChangePassword(c *gin.Context) {
 		return
 	}
 
+	revoked := 0
+	if changePasswordRequest.RevokeOtherSessions {
+		revoked, err = revokeOtherSessions(tx, userID, api.GetSessionIDFromContext(c))
+		if err != nil {
+			log.Error().Err(err).Msg("Error revoking other sessions")
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while updating password. Please try again later.",
+			})
+			return
+		}
+	}
+
 	tx.Commit()
-	log.Debug().Int("Rows updated", rowsUpdated).Msg("Password updated")
+	log.Debug().Int("Rows updated", rowsUpdated).Int("sessions revoked", revoked).Msg("Password updated")
 
+	message := "Password changed successfully!"
+	if changePasswordRequest.RevokeOtherSessions {
+		message = "Password changed successfully! You have been logged out of all other sessions."
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Password changed successfully!",
+		"message": message,
+		"revoked": revoked,
 	})
 }
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// challengeTTL - time to enter the code after the password was accepted
+	challengeTTL = 5 * time.Minute
+	// maxChallengeAttempts - wrong codes accepted for one challenge, after which the user has to log in again
+	maxChallengeAttempts = 5
+)
+
+type LoginTwoFactorRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
+
+// startTwoFactorChallenge is called once the password is correct for a user who needs a second factor. Users who
+// must use 2FA but have not enrolled are given a new secret to add to their authenticator.
+func startTwoFactorChallenge(c *gin.Context, user *models.User) {
+	purpose := models.ChallengeVerify
+	if user.TOTPEnabledAt == nil {
+		purpose = models.ChallengeEnrol
+	}
+
+	challenge, err := newLoginChallenge(user.UserID, purpose)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error creating login challenge")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+		return
+	}
+
+	res := LoginResponse{
+		Success:           false,
+		TwoFactorRequired: true,
+		Challenge:         challenge,
+	}
+
+	if purpose == models.ChallengeEnrol {
+		secret, err := startEnrolment(context.Background(), user)
+		if err != nil {
+			log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error starting 2FA enrolment")
+			c.JSON(http.StatusInternalServerError, LoginResponse{
+				Success: false,
+				Error:   defaultInternalErrorMsg,
+			})
+			return
+		}
+		res.TwoFactorSetupRequired = true
+		res.Secret = secret
+		res.OTPAuthURI = totp.URI(totpIssuer, user.Name, secret)
+	}
+
+	log.Info().Str("event", "login_2fa_challenged").Str("username", user.Name).Str("purpose", purpose).Msg("Password accepted, waiting for 2FA code")
+	c.JSON(http.StatusOK, res)
+}
+
+// newLoginChallenge creates a challenge for the second step of a login and returns it.
+func newLoginChallenge(userID uint, purpose string) (string, error) {
+	challenge, err := models.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	DeleteExpiredChallenges()
+	err = gorm.G[models.LoginChallenge](db.GormDB).Create(context.Background(), &models.LoginChallenge{
+		ChallengeHash: models.HashToken(challenge),
+		UserID:        userID,
+		Purpose:       purpose,
+		ExpiresAt:     time.Now().Add(challengeTTL),
+	})
+	return challenge, err
+}
+
+// HandleLoginTwoFactor is the second step of a login: the challenge from HandleLogin and a code from the
+// authenticator (or a recovery code). Users enrolling as part of the login receive their recovery codes here.
+func HandleLoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, LoginResponse{
+			Success: false,
+			Error:   "challenge and code are required",
+		})
+		return
+	}
+
+	challenge, err := gorm.G[models.LoginChallenge](db.GormDB).
+		Where("challenge_hash = ? AND expires_at > now()", models.HashToken(req.Challenge)).
+		Take(context.Background())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Success: false,
+			Error:   "Your login has expired, please log in again.",
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching login challenge")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+		return
+	}
+
+	user, err := gorm.G[models.User](db.GormDB).Where("user_id = ?", challenge.UserID).Take(context.Background())
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", challenge.UserID).Msg("Error fetching user for login challenge")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	ip := c.ClientIP()
+	blockedUntil, err := loginBlockedUntil(context.Background(), user.Name, ip)
+	if err != nil {
+		log.Error().Err(err).Msg("Error checking failed logins")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+		return
+	}
+	if blockedUntil != nil {
+		wait := time.Until(*blockedUntil).Round(time.Second) + time.Second
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())))
+		c.JSON(http.StatusTooManyRequests, LoginResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Too many failed login attempts, please try again in %s.", formatWait(wait)),
+		})
+		return
+	}
+
+	// Count the attempt before checking the code, so that parallel requests cannot exceed the limit
+	rows, err := gorm.G[models.LoginChallenge](db.GormDB).
+		Where("challenge_hash = ? AND attempts < ?", challenge.ChallengeHash, maxChallengeAttempts).
+		Update(context.Background(), "attempts", gorm.Expr("attempts + 1"))
+	if err != nil {
+		log.Error().Err(err).Msg("Error counting 2FA attempt")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Success: false,
+			Error:   "Too many invalid codes, please log in again.",
+		})
+		return
+	}
+
+	var recoveryCodes []string
+	err = db.GormDB.Transaction(func(tx *gorm.DB) error {
+		if challenge.Purpose == models.ChallengeEnrol {
+			if user.TOTPSecret == nil {
+				return errInvalidCode
+			}
+			var err error
+			recoveryCodes, err = confirmEnrolment(tx, &user, req.Code)
+			return err
+		}
+		return checkSecondFactor(tx, &user, req.Code)
+	})
+	if errors.Is(err, errInvalidCode) {
+		recordLoginFailure(context.Background(), user.Name, ip, "wrong_2fa_code")
+		msg := "Invalid code."
+		if challenge.Attempts+1 >= maxChallengeAttempts {
+			msg = "Too many invalid codes, please log in again."
+			_, _ = gorm.G[models.LoginChallenge](db.GormDB).Where("challenge_hash = ?", challenge.ChallengeHash).Delete(context.Background())
+		}
+		c.JSON(http.StatusOK, LoginResponse{
+			Success: false,
+			Error:   msg,
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error checking 2FA code")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+		return
+	}
+
+	_, err = gorm.G[models.LoginChallenge](db.GormDB).Where("challenge_hash = ?", challenge.ChallengeHash).Delete(context.Background())
+	if err != nil {
+		log.Warn().Err(err).Msg("Error deleting used login challenge")
+	}
+	if recoveryCodes != nil {
+		log.Info().Str("event", "2fa_enabled").Uint("user_id", user.UserID).Msg("2FA turned on at login")
+	}
+
+	completeLogin(c, &user, recoveryCodes)
+}
+
+// DeleteExpiredChallenges removes login challenges that can no longer be used.
+func DeleteExpiredChallenges() {
+	_, err := gorm.G[models.LoginChallenge](db.GormDB).Where("expires_at < now()").Delete(context.Background())
+	if err != nil {
+		log.Warn().Err(err).Msg("Error deleting expired login challenges")
+	}
+}
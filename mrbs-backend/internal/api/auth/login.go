@@ -2,8 +2,12 @@ package auth
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"rep-mrbs/internal/db"
 	"rep-mrbs/internal/models"
@@ -27,10 +31,29 @@ type LoginResponse struct {
 	DisplayName string `json:"display_name"`
 	Email       string `json:"email"`
 	Level       int    `json:"level"`
+
+	// Set when the password was correct but a second factor is needed, see HandleLoginTwoFactor
+	TwoFactorRequired      bool     `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool     `json:"two_factor_setup_required,omitempty"`
+	Challenge              string   `json:"challenge,omitempty"`
+	Secret                 string   `json:"secret,omitempty"`
+	OTPAuthURI             string   `json:"otpauth_uri,omitempty"`
+	RecoveryCodes          []string `json:"recovery_codes,omitempty"`
 }
 
 const defaultInternalErrorMsg = "Error encountered when logging in, please try again later."
 
+// dummyPasswordHash - argon2id hash of a password no account uses, compared against when the username does not exist.
+var dummyPasswordHash, _ = argon2id.CreateHash("not-a-real-password", argon2id.DefaultParams)
+
+// formatWait formats a wait for users, e.g. "30 seconds" or "5 minutes".
+func formatWait(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d seconds", int(d.Seconds()))
+	}
+	return fmt.Sprintf("%d minutes", int(math.Ceil(d.Minutes())))
+}
+
 func HandleLogin(c *gin.Context) {
 	log.Info().Msg("Login request received")
 
@@ -49,11 +72,36 @@ func HandleLogin(c *gin.Context) {
 
 	// Strip email from username
 	name, _, _ := strings.Cut(form.Username, "@")
+	name = strings.ToUpper(name)
+	ip := c.ClientIP()
+
+	// Usernames that do not exist are throttled the same way, so the response does not reveal which usernames exist
+	blockedUntil, err := loginBlockedUntil(context.Background(), name, ip)
+	if err != nil {
+		log.Error().Err(err).Msg("Error checking failed logins")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+		return
+	}
+	if blockedUntil != nil {
+		wait := time.Until(*blockedUntil).Round(time.Second) + time.Second
+		log.Warn().Str("event", "login_blocked").Str("username", name).Str("ip", ip).Time("blocked_until", *blockedUntil).Msg("Login attempt rejected, too many failed logins")
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())))
+		c.JSON(http.StatusTooManyRequests, LoginResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Too many failed login attempts, please try again in %s.", formatWait(wait)),
+		})
+		return
+	}
 
 	// Verify password
-	user, err := gorm.G[models.User](db).Table("mrbs.users").Where("name = ?", strings.ToUpper(name)).Take(context.Background())
+	user, err := gorm.G[models.User](db).Table("mrbs.users").Where("name = ?", name).Take(context.Background())
 	if err == gorm.ErrRecordNotFound {
-		log.Warn().Err(err).Msg("username not found")
+		// Compare against a dummy hash so that unknown usernames take as long as wrong passwords
+		_, _ = argon2id.ComparePasswordAndHash(form.Password, dummyPasswordHash)
+		recordLoginFailure(context.Background(), name, ip, "unknown_user")
 		c.JSON(http.StatusOK, LoginResponse{
 			Success: false,
 			Error:   "Invalid username/password",
@@ -81,7 +129,7 @@ func HandleLogin(c *gin.Context) {
 	}
 
 	if !isMatch {
-		log.Info().Msgf("Login attempt for %s failed", form.Username)
+		recordLoginFailure(context.Background(), name, ip, "wrong_password")
 		c.JSON(http.StatusOK, LoginResponse{
 			Success: false,
 			Error:   "Invalid username/password",
@@ -89,15 +137,31 @@ func HandleLogin(c *gin.Context) {
 		return
 	}
 
+	// Failed logins are only cleared once the second factor is also correct
+	if user.TOTPEnabledAt != nil || twoFactorRequired(&user) {
+		startTwoFactorChallenge(c, &user)
+		return
+	}
+
+	completeLogin(c, &user, nil)
+}
+
+// completeLogin starts a session for a user who has passed every step of the login. recoveryCodes is only set
+// when the user enrolled in 2FA during the login.
+func completeLogin(c *gin.Context, user *models.User, recoveryCodes []string) {
+	clearLoginFailures(context.Background(), user.Name)
+	log.Info().Str("event", "login_succeeded").Str("username", user.Name).Str("ip", c.ClientIP()).Msg("User logged in")
+
 	// Generate new session key and attach it to cookie
-	NewSession(&user, c)
+	NewSession(user, c)
 
 	// c.SetCookie("session", sessionKey, 604800, "/", "localhost", true, true)
 	c.JSON(http.StatusOK, LoginResponse{
-		Success:     true,
-		Username:    user.Name,
-		DisplayName: user.DisplayName,
-		Email:       user.Email,
-		Level:       user.Level,
+		Success:       true,
+		Username:      user.Name,
+		DisplayName:   user.DisplayName,
+		Email:         user.Email,
+		Level:         user.Level,
+		RecoveryCodes: recoveryCodes,
 	})
 }
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+type SessionResponse struct {
+	models.Session
+	Current bool `json:"current"` // The session making the request
+}
+
+// HandleGetSessions lists the user's active sessions, most recently used first.
+func HandleGetSessions(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+	currentID := api.GetSessionIDFromContext(c)
+
+	sessions, err := gorm.G[models.Session](db.GormDB).
+		Where("user_id = ? AND time_created >= ?", userID, time.Now().Add(-time.Duration(sessionKeyLifetime)*time.Second)).
+		Order("last_seen DESC").
+		Find(context.Background())
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error fetching sessions, please try again later.",
+		})
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{Session: session, Current: session.SessionID == currentID})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleRevokeSession logs out one of the user's sessions. Use logout for the current session.
+func HandleRevokeSession(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	sessionID, err := strconv.ParseUint(c.Param("session-id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid session_id provided",
+		})
+		return
+	}
+	if uint(sessionID) == api.GetSessionIDFromContext(c) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "This is your current session, log out instead.",
+		})
+		return
+	}
+
+	rows, err := gorm.G[models.Session](db.GormDB).Where("session_id = ? AND user_id = ?", sessionID, userID).Delete(context.Background())
+	if err != nil {
+		log.Error().Err(err).Uint64("session_id", sessionID).Msg("Error revoking session")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking session, please try again later.",
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	log.Info().Uint("user_id", userID).Uint64("session_id", sessionID).Msg("Session revoked")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session logged out",
+	})
+}
+
+// HandleRevokeOtherSessions logs out every session of the user except the current one.
+func HandleRevokeOtherSessions(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	rows, err := revokeOtherSessions(db.GormDB, userID, api.GetSessionIDFromContext(c))
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error revoking other sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking sessions, please try again later.",
+		})
+		return
+	}
+
+	log.Info().Uint("user_id", userID).Int("sessions", rows).Msg("Other sessions revoked")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out of all other sessions",
+		"revoked": rows,
+	})
+}
+
+// revokeOtherSessions deletes every session of the user except currentID, returning the number of sessions deleted.
+func revokeOtherSessions(tx *gorm.DB, userID uint, currentID uint) (int, error) {
+	return gorm.G[models.Session](tx).Where("user_id = ? AND session_id != ?", userID, currentID).Delete(context.Background())
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"rep-mrbs/internal/api"
 	"rep-mrbs/internal/db"
 	"rep-mrbs/internal/models"
 
@@ -59,6 +60,8 @@ func HandleGetCurrentUser(c *gin.Context) {
 		return
 	}
 
+	api.TouchSession(c, &sessionObj)
+
 	if time.Since(sessionObj.TimeCreated) > rotationThreshold {
 		log.Debug().Msg("Session stale, rotating key")
 
@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/oidc"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// SSO login with an OpenID Connect provider, set in config/.env:
+//   - OIDC_ISSUER_URL, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET: the SSO login is off unless all three are set
+//   - OIDC_REDIRECT_URL: callback registered with the provider (default <website>/api/auth/oidc/callback)
+//   - OIDC_PROVISION_DOMAINS: comma separated email domains whose users are created at their first SSO login.
+//     Users of other domains must already exist.
+//   - OIDC_DISPLAY_NAME: name of the provider on the login page (default "University account")
+//
+// Password login stays available for all users.
+var (
+	oidcProvider         *oidc.Provider // nil: SSO login is off
+	oidcProvisionDomains []string
+	oidcDisplayName      = "University account"
+)
+
+const (
+	// oidcLoginTTL - time the user has to log in at the identity provider
+	oidcLoginTTL     = 10 * time.Minute
+	oidcStateCookie  = "oidc_state"
+	oidcCallbackPath = "/api/auth/oidc/callback"
+)
+
+var (
+	errSSOUserNotFound = errors.New("no account with this email")
+	errSSONameTaken    = errors.New("username is taken by another account")
+)
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	issuer, clientID, clientSecret := os.Getenv("OIDC_ISSUER_URL"), os.Getenv("OIDC_CLIENT_ID"), os.Getenv("OIDC_CLIENT_SECRET")
+	if issuer == "" || clientID == "" || clientSecret == "" {
+		log.Info().Msg("OIDC_ISSUER_URL, OIDC_CLIENT_ID or OIDC_CLIENT_SECRET not set in /config/.env, SSO login is off.")
+		return
+	}
+
+	redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = constants.MRBSWebsiteURL + oidcCallbackPath
+	}
+	oidcProvider = oidc.NewProvider(oidc.Config{
+		IssuerURL:    issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	})
+
+	for domain := range strings.SplitSeq(os.Getenv("OIDC_PROVISION_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			oidcProvisionDomains = append(oidcProvisionDomains, domain)
+		}
+	}
+	if name := os.Getenv("OIDC_DISPLAY_NAME"); name != "" {
+		oidcDisplayName = name
+	}
+	log.Info().Str("issuer", issuer).Strs("provision_domains", oidcProvisionDomains).Msg("SSO login is on")
+}
+
+// HandleGetOIDC tells the login page whether to show the SSO button.
+func HandleGetOIDC(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": oidcProvider != nil,
+		"name":    oidcDisplayName,
+	})
+}
+
+// HandleOIDCLogin sends the user to the identity provider. The optional redirect query parameter is the date to open
+// after logging in, same as the login page.
+func HandleOIDCLogin(c *gin.Context) {
+	if oidcProvider == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "SSO login is not set up.",
+		})
+		return
+	}
+
+	state, err1 := oidc.RandomString()
+	nonce, err2 := oidc.RandomString()
+	verifier, err3 := oidc.RandomString()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		log.Error().Err(err).Msg("Error generating SSO login state")
+		redirectSSOError(c, defaultInternalErrorMsg)
+		return
+	}
+
+	redirect := "/"
+	if date := c.Query("redirect"); date != "" {
+		redirect = "/?date=" + url.QueryEscape(date)
+	}
+
+	authURL, err := oidcProvider.AuthCodeURL(c, state, nonce, verifier)
+	if err != nil {
+		log.Error().Err(err).Msg("Error contacting identity provider")
+		redirectSSOError(c, "SSO login is unavailable, please log in with your password.")
+		return
+	}
+
+	DeleteExpiredOIDCLogins()
+	err = gorm.G[models.OIDCLogin](db.GormDB).Create(context.Background(), &models.OIDCLogin{
+		StateHash:    models.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Redirect:     redirect,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error saving SSO login")
+		redirectSSOError(c, defaultInternalErrorMsg)
+		return
+	}
+
+	// Lax, as the identity provider redirects back to the callback from another site
+	c.SetCookieData(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		Path:     oidcCallbackPath,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		HttpOnly: true,
+	})
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// HandleOIDCCallback completes the SSO login when the identity provider sends the user back with a code. The email
+// of the ID token is mapped to a user, who may be created if their email domain is allowed.
+func HandleOIDCCallback(c *gin.Context) {
+	if oidcProvider == nil {
+		redirectSSOError(c, "SSO login is not set up.")
+		return
+	}
+
+	state := c.Query("state")
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetCookieData(&http.Cookie{Name: oidcStateCookie, Path: oidcCallbackPath, MaxAge: -1, Secure: true, HttpOnly: true})
+	if state == "" || state != cookie {
+		log.Warn().Str("ip", c.ClientIP()).Msg("SSO callback with missing or mismatched state")
+		redirectSSOError(c, "Your SSO login has expired, please try again.")
+		return
+	}
+
+	var login models.OIDCLogin
+	err := db.GormDB.Raw("DELETE FROM mrbs.oidc_logins WHERE state_hash = ? AND expires_at > now() RETURNING *", models.HashToken(state)).
+		Scan(&login).Error
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching SSO login")
+		redirectSSOError(c, defaultInternalErrorMsg)
+		return
+	}
+	if login.StateHash == "" {
+		redirectSSOError(c, "Your SSO login has expired, please try again.")
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		log.Warn().Str("error", errCode).Str("description", c.Query("error_description")).Msg("Identity provider returned an error")
+		redirectSSOError(c, "SSO login was cancelled or failed, please try again.")
+		return
+	}
+
+	claims, err := oidcProvider.Exchange(c, c.Query("code"), login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Error().Err(err).Msg("Error completing SSO login")
+		redirectSSOError(c, "SSO login failed, please try again or log in with your password.")
+		return
+	}
+	// Users are matched by email, an email the provider has not verified could belong to anyone
+	if claims.Email == "" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		log.Warn().Str("subject", claims.Subject).Str("email", claims.Email).Msg("SSO login without a verified email")
+		redirectSSOError(c, "Your account has no verified email address.")
+		return
+	}
+
+	user, err := findOrProvisionSSOUser(context.Background(), claims)
+	if errors.Is(err, errSSOUserNotFound) || errors.Is(err, errSSONameTaken) {
+		log.Warn().Err(err).Str("email", claims.Email).Msg("SSO login rejected")
+		redirectSSOError(c, "There is no account for "+claims.Email+", please contact the admin.")
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("email", claims.Email).Msg("Error finding user for SSO login")
+		redirectSSOError(c, defaultInternalErrorMsg)
+		return
+	}
+
+	// The second factor is still needed, the login page continues with the challenge
+	if user.TOTPEnabledAt != nil {
+		challenge, err := newLoginChallenge(user.UserID, models.ChallengeVerify)
+		if err != nil {
+			log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error creating login challenge")
+			redirectSSOError(c, defaultInternalErrorMsg)
+			return
+		}
+		log.Info().Str("event", "login_2fa_challenged").Str("username", user.Name).Str("method", "oidc").Msg("SSO accepted, waiting for 2FA code")
+		c.Redirect(http.StatusFound, "/login#challenge="+url.QueryEscape(challenge))
+		return
+	}
+	if twoFactorRequired(user) {
+		redirectSSOError(c, "2FA is required for your account. Log in with your password once to set it up.")
+		return
+	}
+
+	if err := createSession(user, c); err != nil {
+		redirectSSOError(c, defaultInternalErrorMsg)
+		return
+	}
+	log.Info().Str("event", "login_succeeded").Str("username", user.Name).Str("ip", c.ClientIP()).Str("method", "oidc").Msg("User logged in")
+	c.Redirect(http.StatusFound, login.Redirect)
+}
+
+// findOrProvisionSSOUser returns the user with the email of the claims, and creates the user if the email domain
+// is allowed.
+func findOrProvisionSSOUser(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	user, err := gorm.G[models.User](db.GormDB).Where("LOWER(email) = LOWER(?)", claims.Email).Take(ctx)
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	localPart, domain, _ := strings.Cut(claims.Email, "@")
+	if localPart == "" || !slices.Contains(oidcProvisionDomains, strings.ToLower(domain)) {
+		return nil, errSSOUserNotFound
+	}
+
+	// Same username as HandleLogin derives from an email address
+	name := strings.ToUpper(localPart)
+	if _, err := gorm.G[models.User](db.GormDB).Where("name = ?", name).Take(ctx); err == nil {
+		return nil, errSSONameTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// The user has no password until they reset it, this hash matches no password
+	unusable, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	pwhash, err := argon2id.CreateHash(unusable, argon2id.DefaultParams)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := claims.Name
+	if displayName == "" {
+		displayName = name
+	}
+	user = models.User{
+		PublicUser: models.PublicUser{
+			Name:        name,
+			DisplayName: displayName,
+			Email:       claims.Email,
+			Level:       1,
+			TimeCreated: time.Now(),
+		},
+		PasswordHash: pwhash,
+	}
+	if err := gorm.G[models.User](db.GormDB).Create(ctx, &user); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("event", "user_provisioned").Str("username", name).Str("email", claims.Email).Msg("User created at first SSO login")
+	return &user, nil
+}
+
+// redirectSSOError sends the user back to the login page, which shows the message.
+func redirectSSOError(c *gin.Context, message string) {
+	c.Redirect(http.StatusFound, "/login?sso_error="+url.QueryEscape(message))
+}
+
+// DeleteExpiredOIDCLogins removes SSO logins the user never came back from.
+func DeleteExpiredOIDCLogins() {
+	_, err := gorm.G[models.OIDCLogin](db.GormDB).Where("expires_at < now()").Delete(context.Background())
+	if err != nil {
+		log.Warn().Err(err).Msg("Error deleting expired SSO logins")
+	}
+}
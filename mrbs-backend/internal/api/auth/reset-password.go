@@ -2,69 +2,206 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"rep-mrbs/internal/constants"
 	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/mail"
 	"rep-mrbs/internal/models"
 
 	"github.com/alexedwards/argon2id"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
+// resetTokenLifetime - how long a password reset link is valid, set with RESET_TOKEN_LIFETIME (minutes) in config/.env
+var resetTokenLifetime = 30 * time.Minute
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	lifetime, err := strconv.Atoi(os.Getenv("RESET_TOKEN_LIFETIME"))
+	if err != nil || lifetime <= 0 {
+		log.Warn().Msg("RESET_TOKEN_LIFETIME not set in /config/.env, default value of 30 minutes is used.")
+		return
+	}
+	resetTokenLifetime = time.Duration(lifetime) * time.Minute
+}
+
 type ResetPasswordForm struct {
 	Email string `form:"email" binding:"required"`
 }
 
+type ConfirmResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+const resetPasswordMessage = "If the email belongs to an account, a password reset link has been sent to it."
+
+// HandleResetPassword emails a single-use password reset link to the user. The response is the same whether or not
+// the email exists, so that it cannot be used to find out which emails have accounts.
 func HandleResetPassword(c *gin.Context) {
 	// Retrieve email from body
 	var form ResetPasswordForm
 	if err := c.ShouldBindWith(&form, binding.Form); err != nil {
 		log.Warn().Err(err).Msg("Error binding to form")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error resetting password, please try again later.",
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Email is required.",
 		})
 		return
 	}
-	log.Info().Msgf("Reset password request received from %v", form.Email)
+	email := strings.ToLower(strings.TrimSpace(form.Email))
+	ip := c.ClientIP()
+	log.Info().Str("email", email).Str("ip", ip).Msg("Reset password request received")
 
-	defaultPassword, exists := os.LookupEnv("DEFAULT_PASSWORD")
-	if !exists {
-		log.Error().Msg("DEFAULT_PASSWORD missing in config/.env")
+	// Emails without an account are throttled the same way, so the response does not reveal which emails exist
+	blockedUntil, err := resetBlockedUntil(context.Background(), email, ip)
+	if err != nil {
+		log.Error().Err(err).Msg("Error checking password reset requests")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error resetting password, please try again later.",
 		})
 		return
 	}
+	if blockedUntil != nil {
+		wait := time.Until(*blockedUntil).Round(time.Second) + time.Second
+		log.Warn().Str("email", email).Str("ip", ip).Msg("Password reset blocked after too many requests")
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": fmt.Sprintf("Too many password reset requests, please try again in %s.", formatWait(wait)),
+		})
+		return
+	}
+	recordResetRequest(context.Background(), email, ip)
+
+	// The lookup and the email run after the response, so its timing does not reveal whether the email has an account
+	go issueResetToken(email)
 
-	pwhash, err := argon2id.CreateHash(defaultPassword, argon2id.DefaultParams)
+	c.JSON(http.StatusOK, gin.H{
+		"message": resetPasswordMessage,
+	})
+}
+
+// issueResetToken sends a reset link to the account with the email, if there is one. Errors are only logged, as the
+// response has already been sent.
+func issueResetToken(email string) {
+	ctx := context.Background()
+	user, err := gorm.G[models.User](db.GormDB).Where("LOWER(email) = ?", email).Take(ctx)
+	if err == gorm.ErrRecordNotFound {
+		log.Warn().Str("email", email).Msg("Email does not exist.")
+		return
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("Error occurred trying to hash password")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error resetting password, please try again later.",
+		log.Error().Err(err).Msg("Error fetching user for password reset")
+		return
+	}
+
+	if err = sendResetToken(ctx, user); err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error sending password reset email")
+		return
+	}
+	log.Info().Uint("user_id", user.UserID).Msg("Password reset token issued")
+}
+
+// sendResetToken issues a new reset token for the user and emails the reset link to them.
+func sendResetToken(ctx context.Context, user models.User) error {
+	token, err := models.GenerateToken()
+	if err != nil {
+		return fmt.Errorf("generating token: %w", err)
+	}
+
+	// Only the hash is stored, a new request replaces any earlier token.
+	hash := models.HashToken(token)
+	expires := time.Now().Add(resetTokenLifetime)
+	_, err = gorm.G[models.User](db.GormDB).
+		Where("user_id = ?", user.UserID).
+		Select("reset_key_hash", "reset_key_expires_at").
+		Updates(ctx, models.User{ResetKeyHash: &hash, ResetKeyExpiresAt: &expires})
+	if err != nil {
+		return fmt.Errorf("saving token: %w", err)
+	}
+
+	msg, err := mail.Render(mail.TemplateResetPassword, gin.H{
+		"Name":     user.DisplayName,
+		"Username": user.Name,
+		"Minutes":  int(resetTokenLifetime.Minutes()),
+		"Link":     fmt.Sprintf("%s/reset-password?token=%s", constants.MRBSWebsiteURL, token),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	return mail.Send(ctx, msg)
+}
+
+// HandleConfirmResetPassword sets the password chosen by the user, consumes the token and logs out every session of the user.
+func HandleConfirmResetPassword(c *gin.Context) {
+	var req ConfirmResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn().Err(err).Msg("Error binding password reset confirmation")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Token and new password (min. 8 characters) are required.",
 		})
 		return
 	}
 
-	rowsUpdated, err := gorm.G[models.User](db.GormDB).Where("email = ?", form.Email).Update(context.Background(), "password_hash", pwhash)
+	pwhash, err := argon2id.CreateHash(req.NewPassword, argon2id.DefaultParams)
 	if err != nil {
-		log.Error().Msg("Error occurred updating password in database")
+		log.Error().Err(err).Msg("Error hashing password")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error resetting password, please try again later.",
 		})
 		return
 	}
 
-	if rowsUpdated == 0 {
-		log.Warn().Str("email", form.Email).Msg("Email does not exist.")
-	} else {
-		log.Info().Int("rows updated", rowsUpdated).Msgf("Password updated for user %v", form.Email)
+	var userID uint
+	err = db.GormDB.Transaction(func(tx *gorm.DB) error {
+		user, err := gorm.G[models.User](tx).
+			Where("reset_key_hash = ? AND reset_key_expires_at > ?", models.HashToken(req.Token), time.Now()).
+			Take(context.Background())
+		if err != nil {
+			return err
+		}
+		userID = user.UserID
+
+		// Clearing the hash makes the token single-use
+		_, err = gorm.G[models.User](tx).
+			Where("user_id = ?", user.UserID).
+			Select("password_hash", "reset_key_hash", "reset_key_expires_at").
+			Updates(context.Background(), models.User{PasswordHash: pwhash})
+		if err != nil {
+			return err
+		}
+
+		_, err = gorm.G[models.Session](tx).Where("user_id = ?", user.UserID).Delete(context.Background())
+		return err
+	})
+	if err == gorm.ErrRecordNotFound {
+		log.Warn().Msg("Invalid or expired password reset token")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "This reset link is invalid or has expired. Please request a new one.",
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error resetting password")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error resetting password, please try again later.",
+		})
+		return
 	}
 
+	log.Info().Uint("user_id", userID).Msg("Password reset, all sessions revoked")
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Password reset successful. If the email is valid, you can now log in with the default password.",
+		"message": "Password reset successful. You can now log in with your new password.",
 	})
 }
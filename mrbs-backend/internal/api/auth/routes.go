@@ -9,8 +9,32 @@ import (
 
 func RegisterAuthRoutes(router *gin.RouterGroup) {
 	router.POST("/login", HandleLogin)
+	router.POST("/login/2fa", HandleLoginTwoFactor)
+
+	// SSO login with an OpenID Connect provider
+	router.GET("/oidc", HandleGetOIDC)
+	router.GET("/oidc/login", HandleOIDCLogin)
+	router.GET("/oidc/callback", HandleOIDCCallback)
 	router.POST("/logout", api.AuthGuard(1), HandleLogout)
-	router.POST("/change-password", api.AuthGuard(1), HandleChangePassword)
+	router.POST("/change-password", api.AuthGuard(1), api.RequireSession(), HandleChangePassword)
 	router.POST("/reset-password", HandleResetPassword)
+	router.POST("/reset-password/confirm", HandleConfirmResetPassword)
 	router.GET("/me", HandleGetCurrentUser)
+
+	// Active sessions of the user
+	router.GET("/sessions", api.AuthGuard(1), api.RequireSession(), HandleGetSessions)
+	router.DELETE("/sessions", api.AuthGuard(1), api.RequireSession(), HandleRevokeOtherSessions)
+	router.DELETE("/sessions/:session-id", api.AuthGuard(1), api.RequireSession(), HandleRevokeSession)
+
+	// Personal access tokens can only be managed after logging in
+	router.GET("/tokens", api.AuthGuard(1), api.RequireSession(), HandleGetAPITokens)
+	router.POST("/tokens", api.AuthGuard(1), api.RequireSession(), HandleNewAPIToken)
+	router.DELETE("/tokens/:token-id", api.AuthGuard(1), api.RequireSession(), HandleDeleteAPIToken)
+
+	// Two-factor authentication
+	router.GET("/2fa", api.AuthGuard(1), api.RequireSession(), HandleGetTwoFactor)
+	router.POST("/2fa/setup", api.AuthGuard(1), api.RequireSession(), HandleSetupTwoFactor)
+	router.POST("/2fa/confirm", api.AuthGuard(1), api.RequireSession(), HandleConfirmTwoFactor)
+	router.POST("/2fa/recovery-codes", api.AuthGuard(1), api.RequireSession(), HandleNewRecoveryCodes)
+	router.POST("/2fa/disable", api.AuthGuard(1), api.RequireSession(), HandleDisableTwoFactor)
 }
@@ -34,22 +34,29 @@ func init() {
 }
 
 func NewSession(user *models.User, c *gin.Context) {
+	if err := createSession(user, c); err != nil {
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Error:   defaultInternalErrorMsg,
+		})
+	}
+}
+
+// createSession creates a session for the user and sets the session cookie.
+func createSession(user *models.User, c *gin.Context) error {
 	DeleteExpiredSessions()
 
 	sessionKey, err := generateSessionKey()
 	if err != nil {
 		log.Error().Err(err).Msg("Error generating session key, returning 500 as session key cannot be empty.")
-		c.JSON(http.StatusInternalServerError, LoginResponse{
-			Success: false,
-			Error:   defaultInternalErrorMsg,
-		})
-		return
+		return err
 	}
 
 	// Update last login async.
+	// Only last_login is written, saving the whole user could overwrite a password reset made in the meantime.
 	go func() {
 		user.LastLogin = time.Now()
-		db.GormDB.Save(&user)
+		_, _ = gorm.G[models.User](db.GormDB).Where("user_id = ?", user.UserID).Update(context.Background(), "last_login", user.LastLogin)
 
 		log.Debug().Msgf("Last login updated for user %s", user.Name)
 	}()
@@ -59,14 +66,13 @@ func NewSession(user *models.User, c *gin.Context) {
 		SessionKey:  sessionKey,
 		UserID:      user.UserID,
 		TimeCreated: time.Now(),
+		UserAgent:   c.Request.UserAgent(),
+		IPAddress:   c.ClientIP(),
+		LastSeen:    time.Now(),
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Error creating new session")
-		c.JSON(http.StatusInternalServerError, LoginResponse{
-			Success: false,
-			Error:   defaultInternalErrorMsg,
-		})
-		return
+		return err
 	}
 	log.Info().Int64("num rows inserted", result.RowsAffected).Msg("New session created")
 
@@ -79,6 +85,7 @@ func NewSession(user *models.User, c *gin.Context) {
 		SameSite: http.SameSiteStrictMode,
 		HttpOnly: true,
 	})
+	return nil
 }
 
 // DeleteExpiredSessions remove expired sessions from the database
@@ -96,23 +103,10 @@ func DeleteExpiredSessions() {
 func RotateSession(user *models.User, c *gin.Context, oldKey string) {
 	newKey, _ := generateSessionKey()
 
-	err := db.GormDB.Transaction(func(tx *gorm.DB) error {
-		// Delete old session
-		if err := tx.Table("mrbs.sessions").Where("session_key = ?", oldKey).Delete(&models.Session{}).Error; err != nil {
-			return err
-		}
-
-		// 2. Create new session
-		newSession := models.Session{
-			SessionKey:  newKey,
-			UserID:      user.UserID,
-			TimeCreated: time.Now(),
-		}
-		if err := tx.Create(&newSession).Error; err != nil {
-			return err
-		}
-		return nil
-	})
+	// The key is replaced in place so that the session keeps its id and details in the list of sessions
+	err := db.GormDB.
+		Exec("UPDATE mrbs.sessions SET session_key = ?, time_created = now(), last_seen = now() WHERE session_key = ? AND user_id = ?", newKey, oldKey, user.UserID).
+		Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to rotate session")
 		return
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Login throttling, set in config/.env:
+//   - LOGIN_FREE_ATTEMPTS: failed logins allowed before each further attempt is delayed (default 3)
+//   - LOGIN_LOCKOUT_THRESHOLD: failed logins for a username before it is locked out (default 10)
+//   - LOGIN_IP_LOCKOUT_THRESHOLD: failed logins from an IP before it is locked out (default 50)
+//   - LOGIN_LOCKOUT_MINUTES: length of a lockout (default 30)
+//
+// The delay doubles with every failure after the free attempts, up to maxLoginBackoff. Failures are forgotten
+// loginFailureWindow after the last one, and the username's failures are cleared by a successful login.
+var (
+	loginFreeAttempts       = 3
+	loginLockoutThreshold   = 10
+	loginIPLockoutThreshold = 50
+	loginLockoutDuration    = 30 * time.Minute
+)
+
+const (
+	maxLoginBackoff    = 5 * time.Minute
+	loginFailureWindow = 24 * time.Hour
+)
+
+// Password reset requests are counted like failed logins under their own keys, so that the reset form cannot be used
+// to flood an inbox. Requests for emails without an account are counted too.
+const (
+	resetLockoutThreshold   = 5  // Requests for an email before it is locked out
+	resetIPLockoutThreshold = 20 // Requests from an IP before it is locked out
+)
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	loginFreeAttempts = lookupLoginSetting("LOGIN_FREE_ATTEMPTS", loginFreeAttempts)
+	loginLockoutThreshold = lookupLoginSetting("LOGIN_LOCKOUT_THRESHOLD", loginLockoutThreshold)
+	loginIPLockoutThreshold = lookupLoginSetting("LOGIN_IP_LOCKOUT_THRESHOLD", loginIPLockoutThreshold)
+	loginLockoutDuration = time.Duration(lookupLoginSetting("LOGIN_LOCKOUT_MINUTES", 30)) * time.Minute
+}
+
+func lookupLoginSetting(key string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n <= 0 {
+		log.Warn().Int("default", fallback).Msgf("%s not set in /config/.env, using default.", key)
+		return fallback
+	}
+	return n
+}
+
+// loginBlockedUntil returns when the username or the IP may try to log in again, or nil if they may do so now.
+func loginBlockedUntil(ctx context.Context, username string, ip string) (*time.Time, error) {
+	return blockedUntil(ctx, models.ThrottleUserPrefix+username, models.ThrottleIPPrefix+ip)
+}
+
+// resetBlockedUntil returns when a password reset may be requested again for the email or from the IP, or nil if it
+// may be requested now.
+func resetBlockedUntil(ctx context.Context, email string, ip string) (*time.Time, error) {
+	return blockedUntil(ctx, models.ThrottleResetPrefix+strings.ToLower(email), models.ThrottleResetIPPrefix+ip)
+}
+
+// blockedUntil returns the latest time any of the keys is blocked until, or nil if none of them is blocked.
+func blockedUntil(ctx context.Context, keys ...string) (*time.Time, error) {
+	var until *time.Time
+	err := db.GormDB.WithContext(ctx).Raw(`
+		SELECT MAX(blocked_until) FROM mrbs.login_throttles
+		WHERE throttle_key IN ? AND blocked_until > now()`, keys).
+		Scan(&until).Error
+	return until, err
+}
+
+// recordResetRequest counts a password reset request against the email and the IP.
+func recordResetRequest(ctx context.Context, email string, ip string) {
+	addFailure(ctx, models.ThrottleResetPrefix+strings.ToLower(email), resetLockoutThreshold)
+	addFailure(ctx, models.ThrottleResetIPPrefix+ip, resetIPLockoutThreshold)
+}
+
+// recordLoginFailure counts a failed login against the username and the IP, whether or not the username exists,
+// and delays or locks out further attempts.
+func recordLoginFailure(ctx context.Context, username string, ip string, reason string) {
+	userFailures := addFailure(ctx, models.ThrottleUserPrefix+username, loginLockoutThreshold)
+	ipFailures := addFailure(ctx, models.ThrottleIPPrefix+ip, loginIPLockoutThreshold)
+
+	log.Warn().
+		Str("event", "login_failed").
+		Str("username", username).
+		Str("ip", ip).
+		Str("reason", reason).
+		Int("user_failures", userFailures).
+		Int("ip_failures", ipFailures).
+		Msg("Failed login")
+}
+
+// addFailure increments the failures of key and sets when it may be used again. Returns the number of failures.
+func addFailure(ctx context.Context, key string, lockoutThreshold int) int {
+	var failures int
+	err := db.GormDB.WithContext(ctx).Raw(`
+		INSERT INTO mrbs.login_throttles (throttle_key, failures, last_failure) VALUES (?, 1, now())
+		ON CONFLICT (throttle_key) DO UPDATE SET
+			failures = CASE WHEN mrbs.login_throttles.last_failure < now() - (? * interval '1 second') THEN 1
+				ELSE mrbs.login_throttles.failures + 1 END,
+			last_failure = now()
+		RETURNING failures`, key, int(loginFailureWindow.Seconds())).
+		Scan(&failures).Error
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Error recording failed login")
+		return 0
+	}
+
+	delay := loginDelay(failures, lockoutThreshold)
+	if delay == 0 {
+		return failures
+	}
+
+	blockedUntil := time.Now().Add(delay)
+	_, err = gorm.G[models.LoginThrottle](db.GormDB).Where("throttle_key = ?", key).Update(ctx, "blocked_until", blockedUntil)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Error delaying further logins")
+	}
+
+	if failures == lockoutThreshold {
+		log.Warn().Str("event", "login_locked").Str("key", key).Int("failures", failures).Time("blocked_until", blockedUntil).Msg("Login locked out")
+	}
+	return failures
+}
+
+// loginDelay returns how long to wait after the given number of consecutive failures.
+func loginDelay(failures int, lockoutThreshold int) time.Duration {
+	if failures >= lockoutThreshold {
+		return loginLockoutDuration
+	}
+	if failures < loginFreeAttempts {
+		return 0
+	}
+	delay := time.Duration(math.Pow(2, float64(failures-loginFreeAttempts))) * time.Second
+	return min(delay, maxLoginBackoff)
+}
+
+// clearLoginFailures forgets the failed logins of the username after a successful login. Failures from the IP are
+// kept, otherwise logging in to one account would allow guessing the passwords of others.
+func clearLoginFailures(ctx context.Context, username string) {
+	_, err := gorm.G[models.LoginThrottle](db.GormDB).Where("throttle_key = ?", models.ThrottleUserPrefix+username).Delete(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("username", username).Msg("Error clearing failed logins")
+	}
+}
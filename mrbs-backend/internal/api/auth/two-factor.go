@@ -0,0 +1,417 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/totp"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// requireAdmin2FA - set REQUIRE_ADMIN_2FA=true in config/.env to make every level 2 user enrol in 2FA at their next login.
+var requireAdmin2FA bool
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	requireAdmin2FA = os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+	if !requireAdmin2FA {
+		log.Warn().Msg("REQUIRE_ADMIN_2FA not set to true in /config/.env, 2FA is optional for admins.")
+	}
+}
+
+const (
+	totpIssuer = "REP MRBS"
+
+	numRecoveryCodes = 10
+	// recoveryCodeAlphabet leaves out characters that are easily confused (0/o, 1/l/i)
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var errInvalidCode = errors.New("invalid 2FA code")
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// twoFactorRequired returns true if the user cannot turn 2FA off.
+func twoFactorRequired(user *models.User) bool {
+	return requireAdmin2FA && user.Level >= 2
+}
+
+// HandleGetTwoFactor returns whether the user has 2FA on, and how many recovery codes are left.
+func HandleGetTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	remaining, err := gorm.G[models.RecoveryCode](db.GormDB).Where("user_id = ? AND used_at IS NULL", user.UserID).Count(context.Background(), "code_id")
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error counting recovery codes")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error fetching 2FA status, please try again later.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabledAt != nil,
+		"enabled_at":               user.TOTPEnabledAt,
+		"required":                 twoFactorRequired(user),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// HandleSetupTwoFactor starts enrolment: a new secret is generated and returned with its otpauth URI. 2FA is only
+// turned on once a code from the authenticator is confirmed with HandleConfirmTwoFactor.
+func HandleSetupTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "2FA is already on. Turn it off first to set up a new authenticator.",
+		})
+		return
+	}
+
+	secret, err := startEnrolment(context.Background(), user)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error starting 2FA enrolment")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error setting up 2FA, please try again later.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer, user.Name, secret),
+	})
+}
+
+// HandleConfirmTwoFactor turns 2FA on once the user has entered a code from their authenticator. The recovery codes
+// are only shown in this response.
+func HandleConfirmTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "code is required",
+		})
+		return
+	}
+	if user.TOTPEnabledAt != nil || user.TOTPSecret == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Set up 2FA first.",
+		})
+		return
+	}
+
+	var codes []string
+	err := db.GormDB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = confirmEnrolment(tx, user, req.Code)
+		return err
+	})
+	if errors.Is(err, errInvalidCode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid code, check that the time on your device is correct.",
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error confirming 2FA enrolment")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error setting up 2FA, please try again later.",
+		})
+		return
+	}
+
+	log.Info().Str("event", "2fa_enabled").Uint("user_id", user.UserID).Msg("2FA turned on")
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "2FA is on. Store the recovery codes somewhere safe, each can be used once if you lose your authenticator.",
+		"recovery_codes": codes,
+	})
+}
+
+// HandleNewRecoveryCodes replaces the user's recovery codes after checking a code from their authenticator.
+func HandleNewRecoveryCodes(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "code is required",
+		})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "2FA is not on.",
+		})
+		return
+	}
+
+	var codes []string
+	err := db.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := checkTOTP(tx, user, req.Code); err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.UserID)
+		return err
+	})
+	if errors.Is(err, errInvalidCode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid code.",
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error replacing recovery codes")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating recovery codes, please try again later.",
+		})
+		return
+	}
+
+	log.Info().Str("event", "2fa_recovery_codes_replaced").Uint("user_id", user.UserID).Msg("Recovery codes replaced")
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "New recovery codes created, the old codes no longer work.",
+		"recovery_codes": codes,
+	})
+}
+
+// HandleDisableTwoFactor turns 2FA off after checking the password and a code (or recovery code).
+func HandleDisableTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "password and code are required",
+		})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "2FA is not on.",
+		})
+		return
+	}
+	if twoFactorRequired(user) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "2FA is mandatory for admins and cannot be turned off.",
+		})
+		return
+	}
+
+	isMatch, err := argon2id.ComparePasswordAndHash(req.Password, user.PasswordHash)
+	if err != nil || !isMatch {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Password is incorrect.",
+		})
+		return
+	}
+
+	err = db.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := checkSecondFactor(tx, user, req.Code); err != nil {
+			return err
+		}
+		if _, err := gorm.G[models.RecoveryCode](tx).Where("user_id = ?", user.UserID).Delete(context.Background()); err != nil {
+			return err
+		}
+		_, err := gorm.G[models.User](tx).
+			Where("user_id = ?", user.UserID).
+			Select("totp_secret", "totp_enabled_at", "totp_last_step").
+			Updates(context.Background(), models.User{})
+		return err
+	})
+	if errors.Is(err, errInvalidCode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid code.",
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error turning off 2FA")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error turning off 2FA, please try again later.",
+		})
+		return
+	}
+
+	log.Info().Str("event", "2fa_disabled").Uint("user_id", user.UserID).Msg("2FA turned off")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "2FA is off.",
+	})
+}
+
+// currentUser fetches the user making the request. Responds with an error and returns false if that fails.
+func currentUser(c *gin.Context) (*models.User, bool) {
+	userID := api.GetUIDFromContext(c)
+	user, err := gorm.G[models.User](db.GormDB).Where("user_id = ?", userID).Take(context.Background())
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching user")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error fetching user, please try again later.",
+		})
+		return nil, false
+	}
+	return &user, true
+}
+
+// startEnrolment stores a new, unconfirmed secret for the user and returns it.
+func startEnrolment(ctx context.Context, user *models.User) (string, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = gorm.G[models.User](db.GormDB).
+		Where("user_id = ? AND totp_enabled_at IS NULL", user.UserID).
+		Select("totp_secret", "totp_last_step").
+		Updates(ctx, models.User{TOTPSecret: &secret})
+	if err != nil {
+		return "", err
+	}
+	user.TOTPSecret = &secret
+	return secret, nil
+}
+
+// confirmEnrolment turns 2FA on if code matches the unconfirmed secret, and returns new recovery codes.
+func confirmEnrolment(tx *gorm.DB, user *models.User, code string) ([]string, error) {
+	step, ok := totp.Validate(*user.TOTPSecret, code, time.Now(), 0)
+	if !ok {
+		return nil, errInvalidCode
+	}
+
+	now := time.Now()
+	_, err := gorm.G[models.User](tx).
+		Where("user_id = ?", user.UserID).
+		Select("totp_enabled_at", "totp_last_step").
+		Updates(context.Background(), models.User{TOTPEnabledAt: &now, TOTPLastStep: step})
+	if err != nil {
+		return nil, err
+	}
+	return replaceRecoveryCodes(tx, user.UserID)
+}
+
+// checkTOTP checks a code from the authenticator, and records its time step so that it cannot be used again.
+func checkTOTP(tx *gorm.DB, user *models.User, code string) error {
+	if user.TOTPSecret == nil {
+		return errInvalidCode
+	}
+	step, ok := totp.Validate(*user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return errInvalidCode
+	}
+
+	// The condition on the last step stops two requests from using the same code at the same time
+	rows, err := gorm.G[models.User](tx).
+		Where("user_id = ? AND totp_last_step < ?", user.UserID, step).
+		Update(context.Background(), "totp_last_step", step)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errInvalidCode
+	}
+	return nil
+}
+
+// checkSecondFactor accepts either a code from the authenticator or an unused recovery code, which is then used up.
+func checkSecondFactor(tx *gorm.DB, user *models.User, code string) error {
+	err := checkTOTP(tx, user, code)
+	if !errors.Is(err, errInvalidCode) {
+		return err
+	}
+
+	rows, err := gorm.G[models.RecoveryCode](tx).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.UserID, hashRecoveryCode(code)).
+		Update(context.Background(), "used_at", time.Now())
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errInvalidCode
+	}
+
+	log.Info().Str("event", "2fa_recovery_code_used").Uint("user_id", user.UserID).Msg("Recovery code used")
+	return nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and returns new ones. Only their hashes are stored.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if _, err := gorm.G[models.RecoveryCode](tx).Where("user_id = ?", userID).Delete(context.Background()); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, numRecoveryCodes)
+	rows := make([]models.RecoveryCode, 0, numRecoveryCodes)
+	for range numRecoveryCodes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code), TimeCreated: time.Now()})
+	}
+
+	if err := gorm.G[models.RecoveryCode](tx).CreateInBatches(context.Background(), &rows, numRecoveryCodes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code such as "k7rm2-xq9fa".
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, v := range b {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which users tend to get wrong when typing codes.
+func hashRecoveryCode(code string) string {
+	return models.HashToken(strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code)))
+}
@@ -0,0 +1,41 @@
+package bookings
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+func HandleCheckIn(c *gin.Context) {
+	bookingID, err := strconv.ParseUint(c.Param("booking-id"), 10, 32)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid booking id provided")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid booking ID",
+		})
+		return
+	}
+
+	userID := api.GetUIDFromContext(c)
+	userLevel := api.GetUserLevelFromContext(c)
+
+	checkedIn, bookingErr := booking.CheckIn(c, uint(bookingID), userID, userLevel)
+	if bookingErr != nil {
+		c.JSON(bookingErr.HTTPStatusCode, gin.H{
+			"error": bookingErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       fmt.Sprintf("Checked in to %s.", models.GetRoomNameFromID(int(checkedIn.RoomID))),
+		"checked_in_at": checkedIn.CheckedInAt,
+	})
+}
@@ -1,17 +1,19 @@
 package bookings
 
 import (
-	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"rep-mrbs/internal/api"
-	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/booking"
 	"rep-mrbs/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
-	"gorm.io/gorm"
 )
 
 func HandleDeleteBooking(c *gin.Context) {
@@ -28,7 +30,7 @@ func HandleDeleteBooking(c *gin.Context) {
 		return
 	}
 
-	bookingID, err := strconv.ParseInt(bookingIDStr, 10, 64)
+	bookingID, err := strconv.ParseUint(bookingIDStr, 10, 32)
 	if err != nil {
 		log.Warn().Err(err).Msg("Invalid booking id provided")
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -37,31 +39,40 @@ func HandleDeleteBooking(c *gin.Context) {
 		return
 	}
 
-	query := gorm.G[models.Booking](db.GormDB).Where("booking_id = ?", bookingID)
+	// Recurring bookings only: "this" (default), "following" or "series"
+	scope := models.SeriesScope(c.DefaultQuery("scope", string(models.ScopeOccurrence)))
 
-	userLevel := api.GetUserLevelFromContext(c)
-	if userLevel < 2 {
-		query.Where("user_id = ?", userID)
-	}
-
-	result, err := query.Delete(context.Background())
-	if err != nil {
-		log.Error().Err(err).Msg("Error deleting record")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error in database. Please try again later.",
+	// Optional, shown to admins in the list of deleted bookings
+	reason := strings.TrimSpace(c.Query("reason"))
+	if utf8.RuneCountInString(reason) > models.MaxDeleteReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Reason cannot be longer than %d characters.", models.MaxDeleteReasonLength),
 		})
 		return
 	}
 
-	if result == 0 {
-		log.Warn().Msg("No rows deleted. Booking id may be wrong or user may not have sufficient permissions")
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Booking not found or not authorized.",
+	userLevel := api.GetUserLevelFromContext(c)
+	deleted, bookingErr := booking.DeleteBooking(c, uint(bookingID), userID, userLevel, scope, reason)
+	if bookingErr != nil {
+		if bookingErr == booking.ErrBookingNotFound {
+			log.Warn().Msg("No rows deleted. Booking id may be wrong or user may not have sufficient permissions")
+		}
+		c.JSON(bookingErr.HTTPStatusCode, gin.H{
+			"error": bookingErr.Message,
 		})
 		return
 	}
 
+	if userLevel >= 2 {
+		audit.Record(c, audit.ActionBookingDelete, audit.TargetBooking, bookingID, deleted, gin.H{"reason": reason})
+	}
+
+	message := "Booking deleted successfully."
+	if len(deleted) > 1 {
+		message = strconv.Itoa(len(deleted)) + " bookings deleted successfully."
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Booking deleted successfully.",
+		"message": message,
 	})
 }
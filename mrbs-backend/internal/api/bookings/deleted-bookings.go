@@ -0,0 +1,145 @@
+package bookings
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultDeletedPageSize = 50
+	maxDeletedPageSize     = 200
+)
+
+type DeletedBookingResponse struct {
+	BookingID     uint      `json:"booking_id"`
+	BookedBy      string    `json:"booked_by"`
+	UserID        uint      `json:"user_id"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	RoomID        uint      `json:"room_id"`
+	RoomName      string    `json:"room_name"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	SeriesID      *uint     `json:"series_id"` // null if the booking is not recurring
+	DeletedAt     time.Time `json:"deleted_at"`
+	DeletedBy     *uint     `json:"deleted_by"` // null if the user who deleted it has been removed
+	DeletedByName string    `json:"deleted_by_name"`
+	DeleteReason  string    `json:"delete_reason"`
+}
+
+type GetDeletedBookingsResponse struct {
+	Bookings []DeletedBookingResponse `json:"bookings"`
+	Total    int64                    `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+// HandleGetDeletedBookings returns a page of the deleted bookings that can still be restored, most recently deleted
+// first. Query parameters, all optional:
+//   - user_id: owner of the bookings, room_id
+//   - page (from 1), page_size (default 50, max. 200)
+func HandleGetDeletedBookings(c *gin.Context) {
+	var filter booking.DeletedFilter
+	for param, field := range map[string]*uint{"user_id": &filter.UserID, "room_id": &filter.RoomID} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid " + param,
+			})
+			return
+		}
+		*field = uint(id)
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid page",
+		})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultDeletedPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxDeletedPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "page_size must be between 1 and " + strconv.Itoa(maxDeletedPageSize),
+		})
+		return
+	}
+
+	deleted, total, err := booking.FindDeleted(c, filter, page, pageSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching deleted bookings")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	response := GetDeletedBookingsResponse{
+		Bookings: make([]DeletedBookingResponse, 0, len(deleted)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, b := range deleted {
+		response.Bookings = append(response.Bookings, DeletedBookingResponse{
+			BookingID:     b.BookingID,
+			BookedBy:      b.BookedBy,
+			UserID:        b.UserID,
+			StartTime:     b.StartTime,
+			EndTime:       b.EndTime,
+			RoomID:        b.RoomID,
+			RoomName:      models.GetRoomNameFromID(int(b.RoomID)),
+			Title:         b.Title,
+			Description:   b.Description,
+			SeriesID:      b.SeriesID,
+			DeletedAt:     b.DeletedAt.Time,
+			DeletedBy:     b.DeletedBy,
+			DeletedByName: b.DeletedByName,
+			DeleteReason:  b.DeleteReason,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleRestoreBooking brings back a deleted booking, unless its slot has been booked since.
+func HandleRestoreBooking(c *gin.Context) {
+	bookingID, err := strconv.ParseUint(c.Param("booking-id"), 10, 32)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid booking id provided")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid booking ID",
+		})
+		return
+	}
+
+	restored, bookingErr := booking.RestoreBooking(c, uint(bookingID), api.GetUIDFromContext(c), api.GetUserLevelFromContext(c))
+	if bookingErr != nil {
+		c.JSON(bookingErr.HTTPStatusCode, gin.H{
+			"error": bookingErr.Message,
+		})
+		return
+	}
+
+	audit.Record(c, audit.ActionBookingRestore, audit.TargetBooking, bookingID, nil, restored)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Booking restored in %s.", models.GetRoomNameFromID(int(restored.RoomID))),
+	})
+}
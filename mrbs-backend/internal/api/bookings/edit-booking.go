@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/audit"
 	"rep-mrbs/internal/booking"
 	"rep-mrbs/internal/db"
 	"rep-mrbs/internal/models"
@@ -22,8 +22,10 @@ type EditBookingRequest struct {
 	Description string `json:"description" binding:"required"`
 	RoomID      string `json:"room_id" binding:"required"`
 	StartTime   string `json:"start_time" binding:"required"`
-	Duration    int    `json:"duration" binding:"required"`
+	Duration    int    `json:"duration"`         // in periods of models.BookingPeriodSize
+	Minutes     int    `json:"duration_minutes"` // used instead of duration if set, for areas with a finer resolution
 	Colour      int    `json:"colour"`
+	Scope       string `json:"scope"` // recurring bookings only: "this" (default), "following" or "series"
 }
 
 func HandleEditBooking(c *gin.Context) {
@@ -69,6 +71,14 @@ func HandleEditBooking(c *gin.Context) {
 		return
 	}
 
+	if !models.SeriesScope(editedBookingReq.Scope).IsValid() {
+		log.Warn().Str("scope", editedBookingReq.Scope).Msg("Invalid scope provided")
+		c.JSON(booking.ErrInvalidScope.HTTPStatusCode, gin.H{
+			"error": booking.ErrInvalidScope.Message,
+		})
+		return
+	}
+
 	parsedStartTime, err := models.ParseDateTime(&editedBookingReq.StartTime)
 	if err != nil {
 		log.Error().Err(err).Msg("Error parsing start time")
@@ -77,16 +87,25 @@ func HandleEditBooking(c *gin.Context) {
 		})
 		return
 	}
-	endTime := parsedStartTime.Add(time.Duration(editedBookingReq.Duration) * 30 * time.Minute)
+
+	duration, ok := requestDuration(editedBookingReq.Duration, editedBookingReq.Minutes)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "duration or duration_minutes is required",
+		})
+		return
+	}
 
 	parsedRoomID, err := strconv.ParseUint(editedBookingReq.RoomID, 10, 32)
-	if err != nil || parsedRoomID > 9 {
-		log.Warn().Err(err).Msg("Invalid roomid provided.")
+	// Bookings in a disabled room can still be edited, but cannot be moved into a disabled room.
+	if err != nil || (uint(parsedRoomID) != originalBooking.RoomID && !models.IsBookableRoom(uint(parsedRoomID))) {
+		log.Warn().Err(err).Str("room_id", editedBookingReq.RoomID).Msg("Invalid or disabled roomid provided.")
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid room_id provided",
 		})
 		return
 	}
+	endTime := parsedStartTime.Add(duration)
 
 	if editedBookingReq.Colour < 1 || editedBookingReq.Colour > models.MaxBookingColours {
 		log.Warn().Err(err).Int("requested colour", editedBookingReq.Colour).Msgf("Invalid colour chosen. Valid colour range: 1-%d", models.MaxBookingColours)
@@ -104,97 +123,35 @@ func HandleEditBooking(c *gin.Context) {
 	editedBooking.Description = editedBookingReq.Description
 	editedBooking.Colour = editedBookingReq.Colour
 
-	numPeriods := int(editedBooking.EndTime.Sub(editedBooking.StartTime).Minutes()) / models.BookingPeriodSize
-
-	// Begin transaction to make sure other users cannot make booking while we check and update current booking.
-	// We begin the transaction as late as possible to minimize time spent under lock.
-	tx := db.GormDB.Begin()
-
-	if userLevel < 2 {
-		clashes, err := booking.CheckClashes(&editedBooking, tx, int(originalBooking.BookingID))
-		if err != nil {
-			log.Error().Err(err).Msg("Error encountered when checking for clashes")
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": booking.ErrInternal.Message,
-			})
-			return
-		}
-		log.Trace().Interface("Clashes", clashes).Msg("")
-		if clashes.RoomClashes > 0 {
-			log.Warn().Msg("Booking clashes with existing booking. Please try another time.")
-			tx.Rollback()
-			c.JSON(http.StatusConflict, gin.H{
-				"error": booking.ErrRoomClash.Message,
-			})
-			return
-		}
-		if clashes.UserClashes > 0 {
-			log.Warn().Msg("User has already made a booking for the same time at another room.")
-			tx.Rollback()
-			c.JSON(http.StatusConflict, gin.H{
-				"error": booking.ErrUserClash.Message,
+	scope := models.SeriesScope(editedBookingReq.Scope)
+	if scope == models.ScopeFollowing || scope == models.ScopeSeries {
+		result, bookingErr := booking.UpdateSeries(c, &originalBooking, &editedBooking, userID, userLevel, scope)
+		if bookingErr != nil {
+			c.JSON(bookingErr.HTTPStatusCode, gin.H{
+				"error": bookingErr.Message,
 			})
 			return
 		}
-
-		if (clashes.ExistingPeriods + numPeriods) > models.DailyBookingLimit {
-			log.Warn().Interface("user_id", userID).Msg("Daily booking for user")
-			tx.Rollback()
-			c.JSON(http.StatusConflict, gin.H{
-				"error": booking.ErrDailyLimit.Message,
-			})
-			return
+		if userLevel >= 2 {
+			audit.Record(c, audit.ActionBookingEdit, audit.TargetBooking, originalBooking.BookingID, originalBooking, result.Bookings)
 		}
 
-		// Check if users are abusing the system by making multiple small bookings in close proximity
-		if clashes.ProximityClashes > 0 {
-			log.Warn().Interface("user_id", userID).Msg("User attempting to book too close to existing booking")
-			tx.Rollback()
-			c.JSON(http.StatusConflict, gin.H{
-				"error": booking.ErrProximityClash.Message,
-			})
-			return
-		}
-
-	} else {
-		// Check if new booking clashes with existing bookings
-		numClashes, err := gorm.G[int](tx).Table("mrbs.bookings").Select("count(1)").Where("room_id = ? AND end_time > ? AND start_time < ? AND booking_id != ?", editedBookingReq.RoomID, parsedStartTime, endTime, bookingID).Take(context.Background())
-		if err != nil {
-			log.Error().Err(err).Msg("Error encountered when checking for clashes")
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": booking.ErrInternal.Message,
-			})
-			return
-		}
-		log.Trace().Int("num clashes", numClashes).Msg("")
-		if numClashes > 0 {
-			log.Warn().Msg("Booking clashes with existing booking. Please try another time.")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": booking.ErrRoomClash.Message,
-			})
-			return
-		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": fmt.Sprintf("Booking updated successfully, %d of %d occurrences have been updated.", len(result.Bookings), len(result.Bookings)+len(result.Clashes)),
+			"clashes": result.Clashes,
+		})
+		return
 	}
 
-	// Update booking by ID
-	rows, err := gorm.G[models.Booking](tx).Where("booking_id = ?", bookingID).Updates(context.Background(), models.Booking{
-		Title:       editedBookingReq.Title,
-		Description: editedBookingReq.Description,
-		RoomID:      uint(parsedRoomID),
-		StartTime:   parsedStartTime,
-		EndTime:     endTime,
-		Colour:      editedBookingReq.Colour,
-	})
-	if err != nil {
-		log.Error().Err(err).Msg("Error updating booking")
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": booking.ErrInternal.Message,
+	if bookingErr := booking.UpdateBooking(c, &editedBooking, userID, userLevel); bookingErr != nil {
+		c.JSON(bookingErr.HTTPStatusCode, gin.H{
+			"error": bookingErr.Message,
 		})
+		return
+	}
+	if userLevel >= 2 {
+		audit.Record(c, audit.ActionBookingEdit, audit.TargetBooking, originalBooking.BookingID, originalBooking, editedBooking)
 	}
-	tx.Commit()
-
-	log.Trace().Int("rows affected", rows).Msg("Booking updated.")
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": fmt.Sprintf("Booking updated successfully, %v has been booked from %v to %v.", models.GetRoomNameFromID(int(parsedRoomID)), parsedStartTime.Format(models.DateTimeFormat), endTime.Format(models.DateTimeFormat)),
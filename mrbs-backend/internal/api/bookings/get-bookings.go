@@ -14,16 +14,19 @@ import (
 )
 
 type GetBookingResponse struct {
-	BookingID        string    `json:"booking_id"`
-	BookedBy         string    `json:"booked_by"`
-	BookedByUsername string    `json:"booked_by_username"` // username of the person who made the booking.
-	StartTime        time.Time `json:"start_time"`
-	EndTime          time.Time `json:"end_time"`
-	RoomName         string    `json:"room_name"`
-	Title            string    `json:"title"`
-	Description      string    `json:"description"`
-	RoomID           string    `json:"room_id"`
-	Colour           int       `json:"colour"`
+	BookingID        string     `json:"booking_id"`
+	BookedBy         string     `json:"booked_by"`
+	BookedByUsername string     `json:"booked_by_username"` // username of the person who made the booking.
+	StartTime        time.Time  `json:"start_time"`
+	EndTime          time.Time  `json:"end_time"`
+	RoomName         string     `json:"room_name"`
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	RoomID           string     `json:"room_id"`
+	Colour           int        `json:"colour"`
+	SeriesID         *string    `json:"series_id"`     // null if the booking is not recurring
+	CheckedInAt      *time.Time `json:"checked_in_at"` // null if the user has not checked in
+	ReleasedAt       *time.Time `json:"released_at"`   // set if the booking was shortened after a no-show
 }
 
 func HandleGetBookings(c *gin.Context) {
@@ -34,17 +37,7 @@ func HandleGetBookings(c *gin.Context) {
 		dateStr = time.Now().Format("2006-01-02")
 	}
 
-	// Hardcoded opening time of 8am and hardcoded closing time of 2am.
-	// Possible to use Gorm instead of raw SQL query.
-	query := `
-	SELECT b.booking_id, u.display_name booked_by, u.name booked_by_username, b.start_time,b.end_time, r.display_name room_name, b.title, b.description, b.room_id, b.colour
-	FROM mrbs.BOOKINGS b 
-	INNER JOIN mrbs.USERS u ON b.user_id = u.user_id 
-	INNER JOIN mrbs.ROOMS r ON b.room_id = r.room_id 
-	WHERE b.start_time >= ($1 || ' 08:00+08')::timestamptz AND b.start_time < (($1::date + 1) || ' 02:00+08')::timestamptz;`
-
-	rows, _ := db.Pool.Query(context.Background(), query, dateStr)
-	bookings, err := pgx.CollectRows(rows, pgx.RowToStructByName[GetBookingResponse])
+	bookings, err := GetBookingsForDate(context.Background(), dateStr)
 	if err != nil {
 		log.Error().Err(err).Msgf("Error fetching bookings for %s", dateStr)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -56,6 +49,30 @@ func HandleGetBookings(c *gin.Context) {
 	c.JSON(http.StatusOK, bookings)
 }
 
+// GetBookingsForDate returns the bookings made during the business day starting on date (YYYY-MM-DD),
+// ordered by room and start time. Opening hours are taken from the area of each room.
+func GetBookingsForDate(ctx context.Context, date string) ([]GetBookingResponse, error) {
+	// Areas that close at or before they open (e.g. 08:00 - 02:00) close on the following day.
+	query := `
+	SELECT b.booking_id, u.display_name booked_by, u.name booked_by_username, b.start_time,b.end_time, r.display_name room_name, b.title, b.description, b.room_id, b.colour, b.series_id, b.checked_in_at, b.released_at
+	FROM mrbs.BOOKINGS b 
+	INNER JOIN mrbs.USERS u ON b.user_id = u.user_id 
+	INNER JOIN mrbs.ROOMS r ON b.room_id = r.room_id 
+	INNER JOIN mrbs.AREAS a ON r.area_id = a.area_id
+	WHERE b.deleted_at IS NULL
+	AND b.start_time >= (($1::date + a.morning_starts) AT TIME ZONE 'Asia/Singapore')
+	AND b.start_time < ((($1::date + CASE WHEN a.evening_ends <= a.morning_starts THEN 1 ELSE 0 END) + a.evening_ends) AT TIME ZONE 'Asia/Singapore')
+	ORDER BY room_name ASC, b.start_time ASC;`
+
+	rows, err := db.Pool.Query(ctx, query, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[GetBookingResponse])
+}
+
 // returns true if the date format is correct
 func isValidFormat(dateStr string) bool {
 	_, err := time.Parse(models.DateFormat, dateStr)
@@ -0,0 +1,127 @@
+package bookings
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/ical"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// maxImportSize - largest .ics file accepted by HandleImportBookings (1 MB)
+const maxImportSize = 1 << 20
+
+// HandleImportBookings books the events of an uploaded .ics file for the admin. Multipart form fields:
+//   - file: the .ics file
+//   - room_mapping: JSON object mapping the LOCATION of events to room ids, e.g. {"Seminar Room 1": 3}
+//   - default_room_id: room for events whose location is not mapped (optional, unmapped events are skipped otherwise)
+//   - colour: colour of the bookings (optional, defaults to 1)
+//   - dry_run: "true" to only report clashes without creating anything
+func HandleImportBookings(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+	userLevel := api.GetUserLevelFromContext(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+64*1024)
+	fileHeader, err := c.FormFile("file")
+	if err != nil || fileHeader.Size > maxImportSize {
+		log.Warn().Err(err).Msg("Missing or oversized calendar file in import request")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "An .ics file (max. 1 MB) is required.",
+		})
+		return
+	}
+
+	opts := booking.ImportOptions{
+		UserID:      userID,
+		UserLevel:   userLevel,
+		RoomMapping: map[string]uint{},
+		Colour:      1,
+		DryRun:      c.PostForm("dry_run") == "true",
+	}
+
+	if mapping := c.PostForm("room_mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.RoomMapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "room_mapping must be a JSON object of locations to room ids",
+			})
+			return
+		}
+	}
+	if defaultRoom := c.PostForm("default_room_id"); defaultRoom != "" {
+		roomID, err := strconv.ParseUint(defaultRoom, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid default_room_id provided",
+			})
+			return
+		}
+		opts.DefaultRoom = uint(roomID)
+	}
+	for location, roomID := range opts.RoomMapping {
+		if _, ok := models.GetRoom(roomID); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Room not found for location " + location,
+			})
+			return
+		}
+	}
+	if opts.DefaultRoom != 0 {
+		if _, ok := models.GetRoom(opts.DefaultRoom); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid default_room_id provided",
+			})
+			return
+		}
+	}
+
+	if colour := c.PostForm("colour"); colour != "" {
+		opts.Colour, err = strconv.Atoi(colour)
+		if err != nil || opts.Colour < 1 || opts.Colour > models.MaxBookingColours {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid colour",
+			})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Error().Err(err).Msg("Error opening uploaded calendar file")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Could not read the uploaded file.",
+		})
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close uploaded calendar file")
+		}
+	}()
+
+	events, err := ical.Parse(file)
+	if err != nil {
+		log.Warn().Err(err).Msg("Error parsing uploaded calendar file")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Could not read the calendar: " + err.Error(),
+		})
+		return
+	}
+
+	result := booking.ImportEvents(c, events, opts)
+	if !opts.DryRun {
+		audit.Record(c, audit.ActionBookingImport, audit.TargetBooking, "", nil, result)
+	}
+
+	status := http.StatusCreated
+	if opts.DryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, result)
+}
@@ -18,10 +18,21 @@ import (
 type NewBookingRequest struct {
 	RoomID      string `json:"room_id" binding:"required"`
 	StartTime   string `json:"start_time" binding:"required"` // pass as string and parse into time object later.
-	NumPeriods  int    `json:"duration" binding:"required"`
+	NumPeriods  int    `json:"duration"`                      // in periods of models.BookingPeriodSize
+	Minutes     int    `json:"duration_minutes"`              // used instead of duration if set, for areas with a finer resolution
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description" binding:"required"`
 	Colour      int    `json:"colour"`
+
+	Recurrence *RecurrenceRequest `json:"recurrence"` // optional, creates a recurring booking when set
+}
+
+// RecurrenceRequest - repeat the booking daily or every N weeks, until a date or for a number of occurrences.
+type RecurrenceRequest struct {
+	Frequency string `json:"frequency" binding:"required"` // "daily" or "weekly"
+	Interval  int    `json:"interval"`                     // defaults to 1
+	Until     string `json:"until"`                        // YYYY-MM-DD, inclusive
+	Count     int    `json:"count"`
 }
 
 func HandleNewBooking(c *gin.Context) {
@@ -46,9 +57,17 @@ func HandleNewBooking(c *gin.Context) {
 		return
 	}
 
+	duration, ok := requestDuration(newBookingReq.NumPeriods, newBookingReq.Minutes)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "duration or duration_minutes is required",
+		})
+		return
+	}
+
 	parsedRoomID, err := strconv.ParseUint(newBookingReq.RoomID, 10, 32)
-	if err != nil || parsedRoomID > 9 {
-		log.Warn().Err(err).Msg("Invalid roomid provided.")
+	if err != nil || !models.IsBookableRoom(uint(parsedRoomID)) {
+		log.Warn().Err(err).Str("room_id", newBookingReq.RoomID).Msg("Invalid or disabled roomid provided.")
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid room_id provided",
 		})
@@ -67,7 +86,7 @@ func HandleNewBooking(c *gin.Context) {
 	newBooking := models.Booking{
 		UserID:      userID,
 		StartTime:   parsedStartTime,
-		EndTime:     parsedStartTime.Add(time.Duration(newBookingReq.NumPeriods) * 30 * time.Minute),
+		EndTime:     parsedStartTime.Add(duration),
 		TimeCreated: time.Now(),
 		RoomID:      uint(parsedRoomID),
 		Title:       newBookingReq.Title,
@@ -75,8 +94,21 @@ func HandleNewBooking(c *gin.Context) {
 		Colour:      newBookingReq.Colour,
 	}
 
+	if newBookingReq.Recurrence != nil {
+		handleNewRecurringBooking(c, &newBooking, newBookingReq.Recurrence)
+		return
+	}
+
 	bookingError := booking.CreateBooking(c, &newBooking)
 
+	if bookingError == booking.ErrRoomClash {
+		// The user can join the waitlist (POST /api/bookings/waitlist) for the same slot
+		c.JSON(bookingError.HTTPStatusCode, gin.H{
+			"error":        bookingError.Message,
+			"can_waitlist": true,
+		})
+		return
+	}
 	if bookingError != nil {
 		c.JSON(bookingError.HTTPStatusCode, gin.H{
 			"error": bookingError.Message,
@@ -89,3 +121,66 @@ func HandleNewBooking(c *gin.Context) {
 		"booking_id": newBooking.BookingID,
 	})
 }
+
+func handleNewRecurringBooking(c *gin.Context, newBooking *models.Booking, recurrence *RecurrenceRequest) {
+	rule := models.RecurrenceRule{
+		Frequency: recurrence.Frequency,
+		Interval:  recurrence.Interval,
+		Count:     recurrence.Count,
+	}
+	if rule.Interval == 0 {
+		rule.Interval = 1
+	}
+	if recurrence.Until != "" {
+		until, err := time.ParseInLocation(models.DateFormat, recurrence.Until, newBooking.StartTime.Location())
+		if err != nil {
+			log.Warn().Err(err).Str("until", recurrence.Until).Msg("Error parsing recurrence end date. Layout should be YYYY-MM-DD")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Incorrect recurrence end date format provided",
+			})
+			return
+		}
+		// Occurrences may start any time on the last day
+		rule.Until = until.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	result, bookingError := booking.CreateSeries(c, newBooking, rule)
+	if bookingError != nil {
+		c.JSON(bookingError.HTTPStatusCode, gin.H{
+			"error": bookingError.Message,
+		})
+		return
+	}
+
+	if len(result.Bookings) == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "None of the occurrences could be booked.",
+			"clashes": result.Clashes,
+		})
+		return
+	}
+
+	bookingIDs := make([]uint, 0, len(result.Bookings))
+	for _, b := range result.Bookings {
+		bookingIDs = append(bookingIDs, b.BookingID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     fmt.Sprintf("Booking success, %v has been booked for %d of %d occurrences.", models.GetRoomNameFromID(int(newBooking.RoomID)), len(result.Bookings), len(result.Bookings)+len(result.Clashes)),
+		"series_id":   result.SeriesID,
+		"booking_ids": bookingIDs,
+		"clashes":     result.Clashes,
+	})
+}
+
+// requestDuration returns the duration of a booking request, given in periods of models.BookingPeriodSize or in
+// minutes. Minutes take precedence. Returns false if neither was given.
+func requestDuration(periods int, minutes int) (time.Duration, bool) {
+	switch {
+	case minutes > 0:
+		return time.Duration(minutes) * time.Minute, true
+	case periods > 0:
+		return time.Duration(periods*models.BookingPeriodSize) * time.Minute, true
+	}
+	return 0, false
+}
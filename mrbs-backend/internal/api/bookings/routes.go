@@ -14,4 +14,18 @@ func RegisterBookingRoutes(router *gin.RouterGroup) {
 	router.POST("/new", api.AuthGuard(1), HandleNewBooking)
 	router.DELETE("/", api.AuthGuard(1), HandleDeleteBooking)
 	router.POST("/:booking-id/edit", api.AuthGuard(1), HandleEditBooking)
+	router.POST("/:booking-id/check-in", api.AuthGuard(1), HandleCheckIn)
+
+	// Bulk import of an .ics file by admins
+	router.POST("/import", api.AuthGuard(2), HandleImportBookings)
+
+	// Deleted bookings are kept for a while so that admins can restore them
+	router.GET("/deleted", api.AuthGuard(2), HandleGetDeletedBookings)
+	router.POST("/:booking-id/restore", api.AuthGuard(2), HandleRestoreBooking)
+
+	// Waitlist for slots that are already booked
+	router.GET("/waitlist", api.AuthGuard(1), HandleGetWaitlist)
+	router.POST("/waitlist", api.AuthGuard(1), HandleJoinWaitlist)
+	router.DELETE("/waitlist/:waitlist-id", api.AuthGuard(1), HandleLeaveWaitlist)
+	router.POST("/waitlist/:waitlist-id/claim", api.AuthGuard(1), HandleClaimWaitlistOffer)
 }
@@ -0,0 +1,154 @@
+package bookings
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// JoinWaitlistRequest - same fields as NewBookingRequest, the booking is made with these details when the offer is claimed.
+type JoinWaitlistRequest struct {
+	RoomID     string `json:"room_id" binding:"required"`
+	StartTime  string `json:"start_time" binding:"required"`
+	NumPeriods int    `json:"duration"`         // in periods of models.BookingPeriodSize
+	Minutes    int    `json:"duration_minutes"` // used instead of duration if set, for areas with a finer resolution
+	Title      string `json:"title" binding:"required"`
+}
+
+func HandleJoinWaitlist(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	var req JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error().Err(err).Msg("Error binding waitlist request to struct")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	parsedStartTime, err := models.ParseDateTime(&req.StartTime)
+	if err != nil {
+		log.Warn().Err(err).Str("start_time", req.StartTime).Msg("Error parsing time into time object. Layout should be YYYY-MM-DD HH:mm")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Incorrect datetime format provided",
+		})
+		return
+	}
+
+	duration, ok := requestDuration(req.NumPeriods, req.Minutes)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "duration or duration_minutes is required",
+		})
+		return
+	}
+
+	parsedRoomID, err := strconv.ParseUint(req.RoomID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid room_id provided",
+		})
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		UserID:    userID,
+		RoomID:    uint(parsedRoomID),
+		StartTime: parsedStartTime,
+		EndTime:   parsedStartTime.Add(duration),
+		Title:     req.Title,
+	}
+
+	if bookingErr := booking.JoinWaitlist(c, &entry); bookingErr != nil {
+		c.JSON(bookingErr.HTTPStatusCode, gin.H{
+			"error": bookingErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     fmt.Sprintf("You have joined the waitlist for %s from %s to %s. You will be notified if the slot is freed.", models.GetRoomNameFromID(int(entry.RoomID)), entry.StartTime.Format(models.DateTimeFormat), entry.EndTime.Format(models.DateTimeFormat)),
+		"waitlist_id": entry.WaitlistID,
+	})
+}
+
+// HandleGetWaitlist returns the open waitlist entries and offers of the user. Offers are returned with their expiry,
+// so that the web client can prompt the user to claim them.
+func HandleGetWaitlist(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	entries, err := gorm.G[models.WaitlistEntry](db.GormDB).
+		Where("user_id = ? AND status IN ? AND end_time > ?", userID, []string{models.WaitlistWaiting, models.WaitlistOffered}, time.Now()).
+		Order("start_time ASC").
+		Find(c)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching waitlist")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func HandleLeaveWaitlist(c *gin.Context) {
+	waitlistID, ok := parseWaitlistID(c)
+	if !ok {
+		return
+	}
+
+	if bookingErr := booking.LeaveWaitlist(c, waitlistID, api.GetUIDFromContext(c)); bookingErr != nil {
+		c.JSON(bookingErr.HTTPStatusCode, gin.H{
+			"error": bookingErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "You have left the waitlist.",
+	})
+}
+
+func HandleClaimWaitlistOffer(c *gin.Context) {
+	waitlistID, ok := parseWaitlistID(c)
+	if !ok {
+		return
+	}
+
+	newBooking, bookingErr := booking.ClaimWaitlistOffer(c, waitlistID, api.GetUIDFromContext(c))
+	if bookingErr != nil {
+		c.JSON(bookingErr.HTTPStatusCode, gin.H{
+			"error": bookingErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    fmt.Sprintf("Booking success, %v has been booked from %v to %v.", models.GetRoomNameFromID(int(newBooking.RoomID)), newBooking.StartTime.Format(models.DateTimeFormat), newBooking.EndTime.Format(models.DateTimeFormat)),
+		"booking_id": newBooking.BookingID,
+	})
+}
+
+func parseWaitlistID(c *gin.Context) (uint, bool) {
+	waitlistID, err := strconv.ParseUint(c.Param("waitlist-id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid waitlist ID",
+		})
+		return 0, false
+	}
+	return uint(waitlistID), true
+}
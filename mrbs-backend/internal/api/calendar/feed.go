@@ -0,0 +1,79 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/ical"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// HandleUserFeed serves the bookings of the token's owner as an iCalendar feed.
+func HandleUserFeed(c *gin.Context) {
+	userID, ok := authenticateFeed(c)
+	if !ok {
+		return
+	}
+
+	serveFeed(c, "REP MRBS - My bookings", booking.FeedFilter{UserID: userID})
+}
+
+// HandleRoomFeed serves every booking of a room as an iCalendar feed. The room is given as "<room-id>.ics".
+func HandleRoomFeed(c *gin.Context) {
+	if _, ok := authenticateFeed(c); !ok {
+		return
+	}
+
+	roomID, err := strconv.ParseUint(strings.TrimSuffix(c.Param("room"), ".ics"), 10, 32)
+	room, exists := models.GetRoom(uint(roomID))
+	if err != nil || !exists {
+		c.String(http.StatusNotFound, "Room not found")
+		return
+	}
+
+	serveFeed(c, "REP MRBS - "+room.DisplayName, booking.FeedFilter{RoomID: room.RoomID})
+}
+
+// authenticateFeed returns the owner of the token in the URL. Feeds are read by calendar apps rather than the
+// website, so errors are returned as plain text.
+func authenticateFeed(c *gin.Context) (uint, bool) {
+	token, err := gorm.G[models.CalendarToken](db.GormDB).
+		Where("token_hash = ?", models.HashToken(c.Param("token"))).
+		Take(context.Background())
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.Error().Err(err).Msg("Error fetching calendar token")
+		}
+		log.Warn().Msg("Calendar feed requested with an invalid token")
+		c.String(http.StatusNotFound, "Calendar not found")
+		return 0, false
+	}
+
+	_, err = gorm.G[models.CalendarToken](db.GormDB).Where("user_id = ?", token.UserID).Update(context.Background(), "last_used", time.Now())
+	if err != nil {
+		log.Warn().Err(err).Uint("user_id", token.UserID).Msg("Error updating calendar token last used time")
+	}
+
+	return token.UserID, true
+}
+
+func serveFeed(c *gin.Context, name string, filter booking.FeedFilter) {
+	events, err := booking.FeedEvents(context.Background(), filter)
+	if err != nil {
+		log.Error().Err(err).Interface("filter", filter).Msg("Error fetching calendar feed events")
+		c.String(http.StatusInternalServerError, "Error fetching bookings, please try again later.")
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", ical.Calendar("", name, events))
+}
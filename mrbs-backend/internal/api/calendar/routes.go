@@ -0,0 +1,18 @@
+// Package calendar contains the handlers for iCalendar subscription feeds.
+package calendar
+
+import (
+	"rep-mrbs/internal/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCalendarRoutes(router *gin.RouterGroup) {
+	router.GET("/token", api.AuthGuard(1), HandleGetCalendarToken)
+	router.POST("/token", api.AuthGuard(1), HandleNewCalendarToken)
+	router.DELETE("/token", api.AuthGuard(1), HandleDeleteCalendarToken)
+
+	// Calendar apps cannot log in, the token in the URL authenticates the request instead.
+	router.GET("/feed/:token/bookings.ics", HandleUserFeed)
+	router.GET("/feed/:token/rooms/:room", HandleRoomFeed)
+}
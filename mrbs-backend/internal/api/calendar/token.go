@@ -0,0 +1,121 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoomFeed struct {
+	RoomID   uint   `json:"room_id"`
+	RoomName string `json:"room_name"`
+	URL      string `json:"url"`
+}
+
+// HandleGetCalendarToken tells the user whether they have a feed token. The token itself is only shown when it is created.
+func HandleGetCalendarToken(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	token, err := gorm.G[models.CalendarToken](db.GormDB).Where("user_id = ?", userID).Take(context.Background())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{
+			"exists": false,
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching calendar token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exists":       true,
+		"time_created": token.TimeCreated,
+		"last_used":    token.LastUsed,
+	})
+}
+
+// HandleNewCalendarToken creates a feed token for the user and returns the feed URLs. Any existing token is
+// replaced, so feeds subscribed to with the old token stop working.
+func HandleNewCalendarToken(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	token, err := models.GenerateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Error generating calendar token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	row := models.CalendarToken{UserID: userID, TokenHash: models.HashToken(token), TimeCreated: time.Now()}
+	err = gorm.G[models.CalendarToken](db.GormDB, clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_hash", "time_created", "last_used"}),
+	}).Create(context.Background(), &row)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error saving calendar token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	base := fmt.Sprintf("%s/api/calendar/feed/%s", constants.MRBSWebsiteURL, token)
+	rooms := []RoomFeed{}
+	for _, room := range models.GetRooms() {
+		rooms = append(rooms, RoomFeed{
+			RoomID:   room.RoomID,
+			RoomName: room.DisplayName,
+			URL:      fmt.Sprintf("%s/rooms/%d.ics", base, room.RoomID),
+		})
+	}
+
+	log.Info().Uint("user_id", userID).Msg("Calendar token created")
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Calendar feeds created. Keep these links private, anyone with a link can see the bookings in it.",
+		"bookings_url": base + "/bookings.ics",
+		"rooms":        rooms,
+	})
+}
+
+// HandleDeleteCalendarToken revokes the user's feed token.
+func HandleDeleteCalendarToken(c *gin.Context) {
+	userID := api.GetUIDFromContext(c)
+
+	rows, err := gorm.G[models.CalendarToken](db.GormDB).Where("user_id = ?", userID).Delete(context.Background())
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error deleting calendar token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No calendar feed to delete",
+		})
+		return
+	}
+
+	log.Info().Uint("user_id", userID).Msg("Calendar token revoked")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Calendar feeds deleted",
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"rep-mrbs/internal/db"
@@ -24,6 +25,13 @@ import (
 func AuthGuard(requiredLevel int) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Trace().Msg("AuthGuard triggered")
+
+		// Scripts authenticate with a personal access token instead of the session cookie
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			tokenGuard(c, token, requiredLevel)
+			return
+		}
+
 		// Retrieve session key
 		session, err := c.Cookie("session")
 		if err != nil {
@@ -100,9 +108,12 @@ func AuthGuard(requiredLevel int) gin.HandlerFunc {
 			return
 		}
 
+		TouchSession(c, &sessionObj)
+
 		// Pass the user level and the user to the next function.
 		c.Set("userID", sessionObj.UserID)
 		c.Set("userLevel", userLevel)
+		c.Set("sessionID", sessionObj.SessionID)
 
 		log.Debug().Msg("AuthGuard passed")
 		c.Next()
@@ -123,6 +134,30 @@ func GetUIDFromContext(c *gin.Context) uint {
 	return userIDVal.(uint)
 }
 
+// GetSessionIDFromContext returns the id of the session making the request, 0 if the request was made with an API token.
+func GetSessionIDFromContext(c *gin.Context) uint {
+	sessionID, exists := c.Get("sessionID")
+	if !exists {
+		return 0
+	}
+	return sessionID.(uint)
+}
+
+// TouchSession records that the session was just used, from the client's current IP address.
+func TouchSession(c *gin.Context, session *models.Session) {
+	if time.Since(session.LastSeen) < models.SessionLastSeenInterval && session.IPAddress == c.ClientIP() {
+		return
+	}
+
+	_, err := gorm.G[models.Session](db.GormDB).
+		Where("session_key = ?", session.SessionKey).
+		Select("last_seen", "ip_address").
+		Updates(context.Background(), models.Session{LastSeen: time.Now(), IPAddress: c.ClientIP()})
+	if err != nil {
+		log.Warn().Err(err).Uint("session_id", session.SessionID).Msg("Error updating session last seen time")
+	}
+}
+
 func GetUserLevelFromContext(c *gin.Context) int {
 	// Retrieve uid from context
 	userLevel, exists := c.Get("userLevel")
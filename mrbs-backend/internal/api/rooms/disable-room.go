@@ -0,0 +1,55 @@
+package rooms
+
+import (
+	"fmt"
+	"net/http"
+
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// HandleDisableRoom stops new bookings from being made in a room. Existing bookings are kept.
+func HandleDisableRoom(c *gin.Context) {
+	setRoomDisabled(c, true)
+}
+
+// HandleEnableRoom allows a disabled room to be booked again.
+func HandleEnableRoom(c *gin.Context) {
+	setRoomDisabled(c, false)
+}
+
+func setRoomDisabled(c *gin.Context, disabled bool) {
+	roomID, ok := parseRoomID(c)
+	if !ok {
+		return
+	}
+
+	before, _ := models.GetRoom(roomID)
+	_, err := gorm.G[models.Room](db.GormDB).Where("room_id = ?", roomID).Update(c, "disabled", disabled)
+	if err != nil {
+		log.Error().Err(err).Uint("room_id", roomID).Bool("disabled", disabled).Msg("Error updating room status")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	refreshRooms()
+
+	status, action := "enabled", audit.ActionRoomEnable
+	if disabled {
+		status, action = "disabled", audit.ActionRoomDisable
+	}
+	after, _ := models.GetRoom(roomID)
+	audit.Record(c, action, audit.TargetRoom, roomID, before, after)
+	log.Info().Uint("room_id", roomID).Msgf("Room %s", status)
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%s %s", models.GetRoomNameFromID(int(roomID)), status),
+	})
+}
@@ -0,0 +1,90 @@
+package rooms
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// parseRoomID reads the room-id path parameter. Responds with 404 and returns false if the room does not exist.
+func parseRoomID(c *gin.Context) (uint, bool) {
+	roomID, err := strconv.ParseUint(c.Param("room-id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid room_id provided",
+		})
+		return 0, false
+	}
+
+	if _, ok := models.GetRoom(uint(roomID)); !ok {
+		log.Warn().Uint64("room_id", roomID).Msg("Room not found")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Room not found",
+		})
+		return 0, false
+	}
+
+	return uint(roomID), true
+}
+
+// HandleEditRoom replaces the details of a room. Use the disable/enable routes to change whether it can be booked.
+func HandleEditRoom(c *gin.Context) {
+	roomID, ok := parseRoomID(c)
+	if !ok {
+		return
+	}
+
+	var req RoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error().Err(err).Msg("Error binding request to room")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		log.Warn().Interface("room request", req).Msg(msg)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	updateData := map[string]any{
+		"area_id":      req.AreaID,
+		"display_name": req.DisplayName,
+		"sort_key":     req.SortKey,
+		"description":  req.Description,
+		"capacity":     req.Capacity,
+		"admin_email":  req.AdminEmail,
+	}
+
+	before, _ := models.GetRoom(roomID)
+	rows, err := gorm.G[map[string]any](db.GormDB).Table("mrbs.rooms").Where("room_id = ?", roomID).Updates(c, updateData)
+	if err != nil {
+		log.Error().Err(err).Int("rows affected", rows).Msg("Error editing room")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	refreshRooms()
+	after, _ := models.GetRoom(roomID)
+	audit.Record(c, audit.ActionRoomEdit, audit.TargetRoom, roomID, before, after)
+
+	log.Info().Uint("room_id", roomID).Interface("room", req).Msg("Room details edited successfully")
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Room %s updated successfully", req.DisplayName),
+	})
+}
@@ -0,0 +1,14 @@
+package rooms
+
+import (
+	"net/http"
+
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetAllRooms returns every room, including disabled rooms.
+func HandleGetAllRooms(c *gin.Context) {
+	c.JSON(http.StatusOK, models.GetRooms())
+}
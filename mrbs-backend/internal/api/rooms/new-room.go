@@ -0,0 +1,95 @@
+package rooms
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// RoomRequest is used to create a room, and to replace the details of an existing room.
+type RoomRequest struct {
+	AreaID      uint   `json:"area_id" binding:"required"`
+	DisplayName string `json:"display_name" binding:"required"`
+	SortKey     string `json:"sort_key"` // Defaults to the display name
+	Description string `json:"description"`
+	Capacity    uint   `json:"capacity"`
+	AdminEmail  string `json:"admin_email"`
+}
+
+// validate trims the request and checks that the area exists. Returns the error message for the client.
+func (r *RoomRequest) validate() string {
+	r.DisplayName = strings.TrimSpace(r.DisplayName)
+	r.SortKey = strings.TrimSpace(r.SortKey)
+	if r.DisplayName == "" {
+		return "Room name cannot be empty"
+	}
+	if r.SortKey == "" {
+		r.SortKey = r.DisplayName
+	}
+	if _, ok := models.GetArea(r.AreaID); !ok {
+		return "Invalid area_id provided"
+	}
+	return ""
+}
+
+func HandleNewRoom(c *gin.Context) {
+	var req RoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error().Err(err).Msg("Error binding request to room")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		log.Warn().Interface("room request", req).Msg(msg)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	room := models.Room{
+		AreaID:      req.AreaID,
+		DisplayName: req.DisplayName,
+		SortKey:     req.SortKey,
+		Description: req.Description,
+		Capacity:    req.Capacity,
+		AdminEmail:  req.AdminEmail,
+	}
+
+	if err := gorm.G[models.Room](db.GormDB).Create(c, &room); err != nil {
+		log.Error().Err(err).Interface("room request", req).Msg("Error creating room")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	refreshRooms()
+	audit.Record(c, audit.ActionRoomCreate, audit.TargetRoom, room.RoomID, nil, room)
+
+	log.Info().Uint("room_id", room.RoomID).Str("display_name", room.DisplayName).Msg("Room created")
+	c.JSON(http.StatusCreated, gin.H{
+		"message": fmt.Sprintf("%s created successfully", room.DisplayName),
+		"room_id": room.RoomID,
+	})
+}
+
+// refreshRooms reloads the room cache after a change. The change itself has already been committed,
+// so a failed reload is only logged, the cache is retried on the next change.
+func refreshRooms() {
+	if err := models.InitRooms(); err != nil {
+		log.Error().Err(err).Msg("Room changed but room cache could not be refreshed")
+	}
+}
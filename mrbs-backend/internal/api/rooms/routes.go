@@ -0,0 +1,16 @@
+// Package rooms defines functions for admins to manage rooms.
+package rooms
+
+import (
+	"rep-mrbs/internal/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoomRoutes(router *gin.RouterGroup) {
+	router.GET("/", api.AuthGuard(2), HandleGetAllRooms)
+	router.POST("/new", api.AuthGuard(2), HandleNewRoom)
+	router.POST("/:room-id", api.AuthGuard(2), HandleEditRoom)
+	router.POST("/:room-id/disable", api.AuthGuard(2), HandleDisableRoom)
+	router.POST("/:room-id/enable", api.AuthGuard(2), HandleEnableRoom)
+}
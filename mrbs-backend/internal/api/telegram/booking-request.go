@@ -0,0 +1,173 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// handleBookingRequest starts the booking wizard from a free-text request such as "Da Vinci tomorrow 3pm for 2h
+// team sync". The wizard opens at its summary if every part of the booking was understood, otherwise at the step of
+// the first part that was not. Any wizard in progress is replaced. The caller must hold the wizard lock of the chat.
+// Returns false if the text does not look like a booking request.
+func handleBookingRequest(ctx context.Context, b *bot.Bot, chatID int64, text string) bool {
+	req := parseBookingRequest(text, time.Now(), m.GetRooms())
+	if !req.recognised() {
+		return false
+	}
+	log.Debug().Interface("request", req).Msg("Booking request parsed")
+
+	if old, exists := loadWizard(ctx, chatID); exists {
+		_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: old.MessageID,
+		})
+	}
+
+	s := &m.BookingState{ChatID: chatID}
+	note := fillWizardFromRequest(ctx, s, &req)
+	if note != "" {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "⚠️ " + note,
+		})
+	}
+
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      "🌟 Initializing booking wizard...",
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg(constants.SendTelegramMsgError)
+		return true
+	}
+
+	s.MessageID = msg.ID
+	saveWizard(ctx, s)
+	routeToStep(ctx, b, s)
+	return true
+}
+
+// fillWizardFromRequest fills in the wizard s with the parts of the request that can be booked, in the order of the
+// wizard steps, and sets its step to the first part that is missing or cannot be booked. Returns why a part given in
+// the request was left out, if it was.
+func fillWizardFromRequest(ctx context.Context, s *m.BookingState, req *bookingRequest) string {
+	// The title is kept even if the wizard has to ask for other parts first, see the duration step
+	if req.Title != "" {
+		s.Title = cleanTitle(req.Title)
+		s.Description = "Booked via Telegram."
+	}
+
+	now := time.Now().In(requestLocation)
+	y, mo, d := now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, requestLocation)
+
+	// Step 1: date
+	date := req.day(now)
+	if date.IsZero() {
+		s.Step = 0
+		return ""
+	}
+	if date.Before(today) {
+		s.Step = 0
+		return fmt.Sprintf("%s has already passed, please choose another date.", date.Format("02 Jan 2006"))
+	}
+	s.StartTime = date
+
+	// Step 2: room
+	if req.RoomID == 0 || !m.IsBookableRoom(req.RoomID) {
+		s.Step = 1
+		return ""
+	}
+	s.RoomID = int(req.RoomID)
+	room := m.GetRoomNameFromID(s.RoomID)
+	area, ok := m.GetAreaForRoom(req.RoomID)
+	if !ok {
+		s.Step = 1
+		return ""
+	}
+
+	// Step 3: start time
+	if !req.HasStart {
+		s.Step = 2
+		return ""
+	}
+	start := date.Add(req.Start)
+	slot := time.Duration(max(area.Resolution, 1)) * time.Minute
+	switch {
+	case !start.After(now):
+		s.Step = 2
+		return fmt.Sprintf("%s has already passed, please choose another time.", start.Format("02 Jan 15:04"))
+	case !area.IsAligned(start) || !area.IsOpen(start, start.Add(slot)):
+		s.Step = 2
+		return fmt.Sprintf("%s cannot be booked from %s, please choose one of the times below.", room, start.Format("15:04"))
+	case isRoomBooked(ctx, req.RoomID, start, start.Add(slot)):
+		s.Step = 2
+		return fmt.Sprintf("%s is already booked at %s, please choose another time.", room, start.Format("15:04"))
+	}
+	s.StartTime = start
+
+	// Step 4: duration
+	if req.Minutes <= 0 {
+		s.Step = 3
+		return ""
+	}
+	end := start.Add(time.Duration(req.Minutes) * time.Minute)
+	maxDuration, hasMax := area.MaxBookingDuration()
+	switch {
+	case req.Minutes%m.BookingPeriodSize != 0 || !area.IsAligned(end) || !area.IsOpen(start, end) || (hasMax && end.Sub(start) > maxDuration):
+		s.Step = 3
+		return fmt.Sprintf("%s cannot be booked for %s from %s, please choose one of the durations below.", room, formatDuration(req.Minutes), start.Format("15:04"))
+	case isRoomBooked(ctx, req.RoomID, start, end):
+		s.Step = 3
+		return fmt.Sprintf("%s is not free for the whole %s, please choose a shorter booking.", room, formatDuration(req.Minutes))
+	}
+	s.NumPeriods = req.Minutes / m.BookingPeriodSize
+
+	// Step 5: title
+	if s.Title == "" {
+		s.Step = 4
+		return ""
+	}
+	s.Step = 5
+	return ""
+}
+
+// isRoomBooked reports whether the room has a booking overlapping [start, end). Errors are logged and reported as
+// not booked, the clash is then caught when the booking is made.
+func isRoomBooked(ctx context.Context, roomID uint, start time.Time, end time.Time) bool {
+	count, err := gorm.G[m.Booking](db.GormDB).
+		Where("room_id = ? AND start_time < ? AND end_time > ?", roomID, end, start).
+		Count(ctx, "booking_id")
+	if err != nil {
+		log.Error().Err(err).Uint("room_id", roomID).Msg("Error checking if room is booked")
+		return false
+	}
+	return count > 0
+}
+
+// cleanTitle trims a booking title, and truncates it to the maximum title length.
+func cleanTitle(title string) string {
+	// Trim whitespace to avoid empty-looking titles
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "Untitled Booking"
+	}
+
+	// Safe truncation using runes
+	if runes := []rune(title); len(runes) > m.MaxTitleLength {
+		return string(runes[:m.MaxTitleLength])
+	}
+	return title
+}
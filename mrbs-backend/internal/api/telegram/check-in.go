@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// HandleCheckIn lists the bookings of the linked user that can be checked in to right now.
+func HandleCheckIn(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	userID, err := getLinkedUserID(ctx, chatID)
+	if err == gorm.ErrRecordNotFound {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("Your Telegram account is not linked. Please use the code on %s/link-telegram to link your account.", constants.MRBSWebsiteURL),
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	// Bookings whose check-in window is currently open
+	now := time.Now()
+	pending, err := gorm.G[m.Booking](db.GormDB).
+		Where("user_id = ? AND checked_in_at IS NULL AND released_at IS NULL", userID).
+		Where("start_time BETWEEN ? AND ?", now.Add(-booking.CheckInGracePeriod), now.Add(booking.CheckInGracePeriod)).
+		Order("start_time ASC").
+		Find(ctx)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching bookings to check in")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	if len(pending) == 0 {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("You have no bookings to check in to right now. Check-in opens %d minutes before your booking starts.", int(booking.CheckInGracePeriod.Minutes())),
+		})
+		return
+	}
+
+	var rows [][]models.InlineKeyboardButton
+	for _, p := range pending {
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("✅ %s (%s - %s)", m.GetRoomNameFromID(int(p.RoomID)), p.StartTime.Format("15:04"), p.EndTime.Format("15:04")),
+			CallbackData: fmt.Sprintf("checkin:%d", p.BookingID),
+		}})
+	}
+
+	if _, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        "📍 <b>Check in</b>\nWhich booking are you checking in to?",
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: rows},
+	}); err != nil {
+		log.Error().Err(err).Msg(constants.SendTelegramMsgError)
+	}
+}
+
+// OnCheckInCallback handles the check-in buttons, callback data format: "checkin:<booking id>"
+func OnCheckInCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error answering callback query")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgID := update.CallbackQuery.Message.Message.ID
+
+	bookingID, err := strconv.ParseUint(strings.TrimPrefix(update.CallbackQuery.Data, "checkin:"), 10, 32)
+	if err != nil {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+
+	userID, err := getLinkedUserID(ctx, chatID)
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	user, err := gorm.G[m.User](db.GormDB).Where("user_id = ?", userID).Take(ctx)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching user from database")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	var text string
+	checkedIn, bookingErr := booking.CheckIn(ctx, uint(bookingID), userID, user.Level)
+	if bookingErr != nil {
+		text = "⚠️ " + bookingErr.Message
+	} else {
+		text = fmt.Sprintf("✅ <b>Checked in</b>\n%s, %s - %s",
+			m.GetRoomNameFromID(int(checkedIn.RoomID)),
+			checkedIn.StartTime.Format("15:04"),
+			checkedIn.EndTime.Format("15:04"))
+	}
+
+	if _, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: msgID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error editing check-in message")
+	}
+}
@@ -3,6 +3,8 @@ package telegram
 import (
 	"context"
 
+	"rep-mrbs/internal/wizard"
+
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog/log"
@@ -16,14 +18,23 @@ func DefaultBotHandler(c context.Context, b *bot.Bot, update *models.Update) {
 	log.Info().Interface("chat id", update.Message.Chat.ID).Msg("Text message received")
 
 	// Check if user is in the new booking wizard
-	s, exists := UserBookingStates[update.Message.Chat.ID]
+	unlock := wizard.Lock(update.Message.Chat.ID)
+	defer unlock()
+
+	s, exists := loadWizard(c, update.Message.Chat.ID)
 	if exists && s.Step == 4 {
 		// handle capture title
-		HandleSetTitle(c, b, update.Message.Chat.ID, update.Message.Text)
+		HandleSetTitle(c, b, s, update.Message.Text)
+		return
+	}
+
+	// Booking requests typed in the chat, e.g. "Da Vinci tomorrow 3pm for 2h team sync"
+	if handleBookingRequest(c, b, update.Message.Chat.ID, update.Message.Text) {
 		return
 	}
+
 	_, _ = b.SendMessage(c, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "Welcome to REP Meeting Room booking bot! To create a new booking, type /new. To view the list of bookings today, type /list.",
+		Text:   "Welcome to REP Meeting Room booking bot! To book a room, type your request (e.g. 'Da Vinci tomorrow 3pm for 2h team sync') or type /new. To view the list of bookings today, type /list. To find a room that is free now, type /free. To view, edit or cancel your upcoming bookings, type /mine. To check in to your booking, type /checkin. To choose when you are reminded of your bookings, type /reminders.",
 	})
 }
@@ -0,0 +1,287 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
+	"rep-mrbs/internal/wizard"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// reHeadcount - number of people in a /free request, e.g. "6 people" or "6pax".
+var reHeadcount = regexp.MustCompile(`(?i)\b(\d+)\s*(people|persons|person|pax|ppl)\b`)
+
+// freeRoomTitle - title of a booking made from /free, it can be changed on the summary before confirming.
+const freeRoomTitle = "Walk-in booking"
+
+const freeUsage = "Usage: /free [time] [duration] [people], e.g. /free, /free 3pm, /free tomorrow 10am 2h or /free 3pm 1h 6 pax."
+
+// freeRoom is a room that is free for the window requested with /free.
+type freeRoom struct {
+	Room  m.Room
+	Start time.Time
+	End   time.Time
+}
+
+// HandleFreeRooms lists the rooms that are free right now, or for the time and duration given, with a button to
+// book each of them. Usage: "/free [time] [duration] [people]". The window starts at the beginning of the current
+// slot of each room if no time is given, and lasts the default duration of the room's area if no duration is given.
+func HandleFreeRooms(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/free"))
+	now := time.Now().In(requestLocation)
+	req := parseBookingRequest(args, now, nil)
+
+	headcount := 0
+	if match := reHeadcount.FindStringSubmatch(args); match != nil {
+		headcount, _ = strconv.Atoi(match[1])
+	}
+
+	// Without a time, the rooms are checked from now
+	from := now
+	if req.HasStart || !req.Date.IsZero() {
+		if !req.HasStart {
+			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatID,
+				Text:   "Please give a time as well as a date. " + freeUsage,
+			})
+			return
+		}
+		from = req.day(now).Add(req.Start)
+		if !from.After(now) {
+			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatID,
+				Text:   fmt.Sprintf("%s has already passed. %s", from.Format("02 Jan 15:04"), freeUsage),
+			})
+			return
+		}
+	}
+
+	rooms, err := findFreeRooms(ctx, from, req.Minutes, headcount)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching free rooms")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	when := "right now"
+	if req.HasStart {
+		when = "at " + from.Format("15:04, 02 Jan")
+	}
+	if req.Minutes > 0 {
+		when += " for " + formatDuration(req.Minutes)
+	}
+	if headcount > 0 {
+		when += fmt.Sprintf(" for %d people", headcount)
+	}
+
+	if len(rooms) == 0 {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("😕 No rooms are free %s. Try another time, e.g. /free 3pm 1h.", when),
+		})
+		return
+	}
+
+	var rows [][]models.InlineKeyboardButton
+	for _, free := range rooms {
+		text := fmt.Sprintf("%s (%s — %s)", free.Room.DisplayName, free.Start.Format("15:04"), free.End.Format("15:04"))
+		if free.Room.Capacity > 0 {
+			text += fmt.Sprintf(", %d pax", free.Room.Capacity)
+		}
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         "🏢 " + text,
+			CallbackData: fmt.Sprintf("free:%d:%d:%d", free.Room.RoomID, free.Start.Unix(), int(free.End.Sub(free.Start).Minutes())),
+		}})
+	}
+
+	if _, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        fmt.Sprintf("🟢 <b>Rooms free %s</b>\nTap a room to book it.", when),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: rows},
+	}); err != nil {
+		log.Error().Err(err).Msg(constants.SendTelegramMsgError)
+	}
+}
+
+// findFreeRooms returns the bookable rooms with at least headcount seats (0 for any room) that have no booking in the
+// window starting at from and lasting minutes (0 for the default duration of the area). The window of each room is
+// aligned to the resolution of its area, and must fall within its opening hours and maximum booking duration.
+func findFreeRooms(ctx context.Context, from time.Time, minutes int, headcount int) ([]freeRoom, error) {
+	var candidates []freeRoom
+	earliest, latest := time.Time{}, time.Time{}
+	for _, room := range m.GetRooms() {
+		if room.Disabled || room.Capacity < uint(headcount) {
+			continue
+		}
+		area, ok := m.GetAreaForRoom(room.RoomID)
+		if !ok {
+			continue
+		}
+
+		resolution := time.Duration(max(area.Resolution, 1)) * time.Minute
+		opening, _ := area.BusinessDay(from)
+		start := opening.Add(from.Sub(opening).Truncate(resolution))
+		length := slotStep(area)
+		if minutes > 0 {
+			length = time.Duration(minutes) * time.Minute
+		}
+		end := start.Add(length)
+		// A window starting before now must still reach past it, the booking would otherwise be over already
+		if !end.After(from) || !area.IsOpen(start, end) || !area.IsAligned(end) {
+			continue
+		}
+		if maxDuration, ok := area.MaxBookingDuration(); ok && length > maxDuration {
+			continue
+		}
+
+		candidates = append(candidates, freeRoom{Room: room, Start: start, End: end})
+		if earliest.IsZero() || start.Before(earliest) {
+			earliest = start
+		}
+		if end.After(latest) {
+			latest = end
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	booked, err := gorm.G[m.Booking](db.GormDB).
+		Where("start_time < ? AND end_time > ?", latest, earliest).
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rooms []freeRoom
+	for _, free := range candidates {
+		clash := false
+		for _, bk := range booked {
+			if bk.RoomID == free.Room.RoomID && bk.StartTime.Before(free.End) && bk.EndTime.After(free.Start) {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			rooms = append(rooms, free)
+		}
+	}
+	return rooms, nil
+}
+
+// OnFreeRoomCallback starts the booking wizard for a room listed by /free, callback data format:
+// "free:<room id>:<start time as unix seconds>:<minutes>". The window was free when it was listed, so the wizard
+// opens at the summary, unless the room has been booked since or the window cannot be expressed in booking periods,
+// in which case the duration is asked for. Buttons sent before the duration was added open at the duration step.
+// Any wizard in progress is discarded.
+func OnFreeRoomCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error answering callback query")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+
+	parts := strings.Split(update.CallbackQuery.Data, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+	roomID, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+	unix, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+	start := time.Unix(unix, 0).In(requestLocation)
+	minutes := 0
+	if len(parts) == 4 {
+		if minutes, err = strconv.Atoi(parts[3]); err != nil || minutes <= 0 {
+			log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+			return
+		}
+	}
+
+	area, ok := m.GetAreaForRoom(uint(roomID))
+	if !ok || !m.IsBookableRoom(uint(roomID)) {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "⚠️ This room is no longer available for booking. Type /free to see the rooms that are free.",
+		})
+		return
+	}
+	if !start.Add(time.Duration(max(area.Resolution, 1)) * time.Minute).After(time.Now()) {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "⌛ This slot has passed. Type /free to see the rooms that are free now.",
+		})
+		return
+	}
+
+	unlock := wizard.Lock(chatID)
+	defer unlock()
+
+	if old, exists := loadWizard(ctx, chatID); exists {
+		_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: old.MessageID,
+		})
+	}
+
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      "🌟 Initializing booking wizard...",
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg(constants.SendTelegramMsgError)
+		return
+	}
+
+	s := &m.BookingState{
+		ChatID:    chatID,
+		MessageID: msg.ID,
+		Step:      3,
+		RoomID:    int(roomID),
+		StartTime: start,
+	}
+	end := start.Add(time.Duration(minutes) * time.Minute)
+	if minutes == 0 || minutes%m.BookingPeriodSize != 0 || isRoomBooked(ctx, uint(roomID), start, end) {
+		saveWizard(ctx, s)
+		showDurationSelection(ctx, b, s)
+		return
+	}
+
+	s.Step = 5
+	s.NumPeriods = minutes / m.BookingPeriodSize
+	s.Title = freeRoomTitle
+	saveWizard(ctx, s)
+	showBookingSummary(ctx, b, s)
+}
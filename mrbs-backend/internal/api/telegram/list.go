@@ -7,11 +7,10 @@ import (
 	"time"
 
 	"rep-mrbs/internal/api/bookings"
-	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
-	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
 )
 
@@ -20,37 +19,18 @@ func HandleListBookings(ctx context.Context, b *bot.Bot, update *models.Update)
 		return
 	}
 
-	// 1. Calculate time bounds: 8am today to 2am tomorrow (SGT/UTC+8)
+	// 1. Fetch today's bookings, opening hours are taken from the area of each room.
 	now := time.Now()
-	dateStr := now.Format("2006-01-02")
+	dateStr := now.Format(m.DateFormat)
 
-	startRange := fmt.Sprintf("%s 08:00+08", dateStr)
-	endRange := fmt.Sprintf("%s 02:00+08", now.AddDate(0, 0, 1).Format("2006-01-02"))
+	log.Trace().Msgf("Telegram: Fetching bookings for %s", dateStr)
 
-	log.Trace().Msgf("Telegram: Fetching bookings from %s to %s", startRange, endRange)
-
-	query := `
-    SELECT b.booking_id, u.display_name booked_by, u.name booked_by_username, b.start_time, b.end_time, r.display_name room_name, b.title, b.description, b.room_id, b.colour
-    FROM mrbs.BOOKINGS b 
-    INNER JOIN mrbs.USERS u ON b.user_id = u.user_id 
-    INNER JOIN mrbs.ROOMS r ON b.room_id = r.room_id 
-    WHERE b.start_time >= $1::timestamptz AND b.start_time < $2::timestamptz
-    ORDER BY room_name ASC, b.start_time ASC;`
-
-	rows, err := db.Pool.Query(ctx, query, startRange, endRange)
+	bookings, err := bookings.GetBookingsForDate(ctx, dateStr)
 	if err != nil {
 		log.Error().Err(err).Msg("Database query error in HandleListBookings")
 		sendError(ctx, b, update.Message.Chat.ID)
 		return
 	}
-	defer rows.Close()
-
-	bookings, err := pgx.CollectRows(rows, pgx.RowToStructByName[bookings.GetBookingResponse])
-	if err != nil {
-		log.Error().Err(err).Msg("Error collecting rows in HandleListBookings")
-		sendError(ctx, b, update.Message.Chat.ID)
-		return
-	}
 
 	// 3. Format the message
 	if len(bookings) == 0 {
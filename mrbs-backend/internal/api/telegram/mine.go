@@ -0,0 +1,290 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
+	"rep-mrbs/internal/wizard"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// maxMyBookings - number of upcoming bookings listed by /mine, so that the buttons fit on screen.
+const maxMyBookings = 10
+
+// HandleMyBookings lists the upcoming bookings of the linked user, with buttons to edit or cancel each of them.
+func HandleMyBookings(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	userID, err := getLinkedUserID(ctx, chatID)
+	if err == gorm.ErrRecordNotFound {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("Your Telegram account is not linked. Please use the code on %s/link-telegram to link your account.", constants.MRBSWebsiteURL),
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	text, kb, err := myBookingsMessage(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching upcoming bookings")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	if _, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: kb,
+	}); err != nil {
+		log.Error().Err(err).Msg(constants.SendTelegramMsgError)
+	}
+}
+
+// myBookingsMessage renders the upcoming bookings of a user. The keyboard is nil if there are none.
+func myBookingsMessage(ctx context.Context, userID uint) (string, *models.InlineKeyboardMarkup, error) {
+	upcoming, err := gorm.G[m.Booking](db.GormDB).
+		Where("user_id = ? AND end_time > ? AND released_at IS NULL", userID, time.Now()).
+		Order("start_time ASC").
+		Limit(maxMyBookings).
+		Find(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(upcoming) == 0 {
+		return "📅 You have no upcoming bookings. Type /new to make one.", nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📅 <b>Your Upcoming Bookings</b>\n")
+
+	var rows [][]models.InlineKeyboardButton
+	for i, bk := range upcoming {
+		fmt.Fprintf(&sb, "\n<b>%d.</b> %s\n", i+1, html.EscapeString(bk.Title))
+		fmt.Fprintf(&sb, "🏢 %s\n", m.GetRoomNameFromID(int(bk.RoomID)))
+		fmt.Fprintf(&sb, "🕒 %s, %s - %s\n", bk.StartTime.Format("02 Jan 2006"), bk.StartTime.Format("15:04"), bk.EndTime.Format("15:04"))
+
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: fmt.Sprintf("✏️ Edit %d", i+1), CallbackData: fmt.Sprintf("mine:edit:%d", bk.BookingID)},
+			{Text: fmt.Sprintf("❌ Cancel %d", i+1), CallbackData: fmt.Sprintf("mine:cancel:%d", bk.BookingID)},
+		})
+	}
+	if len(upcoming) == maxMyBookings {
+		fmt.Fprintf(&sb, "\nOnly your next %d bookings are shown. See all of them on %s.", maxMyBookings, constants.MRBSWebsiteURL)
+	}
+
+	return sb.String(), &models.InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+// OnMyBookingsCallback handles the buttons of /mine, callback data format: "mine:list", "mine:edit:<booking id>",
+// "mine:cancel:<booking id>" (asks for confirmation) or "mine:confirm:<booking id>" (cancels the booking).
+func OnMyBookingsCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error answering callback query")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgID := update.CallbackQuery.Message.Message.ID
+
+	parts := strings.Split(strings.TrimPrefix(update.CallbackQuery.Data, "mine:"), ":")
+	var bookingID uint64
+	if parts[0] != "list" {
+		var err error
+		if len(parts) != 2 {
+			log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+			return
+		}
+		if bookingID, err = strconv.ParseUint(parts[1], 10, 32); err != nil {
+			log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+			return
+		}
+	}
+
+	userID, err := getLinkedUserID(ctx, chatID)
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	user, err := gorm.G[m.User](db.GormDB).Where("user_id = ?", userID).Take(ctx)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching user from database")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	switch parts[0] {
+	case "list":
+		showMyBookings(ctx, b, chatID, msgID, userID)
+	case "cancel":
+		confirmCancelBooking(ctx, b, chatID, msgID, uint(bookingID), user)
+	case "confirm":
+		cancelBooking(ctx, b, chatID, msgID, uint(bookingID), user)
+	case "edit":
+		editBooking(ctx, b, chatID, msgID, uint(bookingID), user)
+	default:
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Unknown callback data received")
+	}
+}
+
+// showMyBookings shows the upcoming bookings again in an existing message.
+func showMyBookings(ctx context.Context, b *bot.Bot, chatID int64, msgID int, userID uint) {
+	text, kb, err := myBookingsMessage(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching upcoming bookings")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   msgID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+}
+
+// findOwnBooking fetches a booking the user is allowed to change, following the rule of the booking API: admins
+// can change any booking, other users only their own. The booking is reported missing otherwise.
+func findOwnBooking(ctx context.Context, b *bot.Bot, chatID int64, msgID int, bookingID uint, user m.User) (*m.Booking, bool) {
+	target, err := gorm.G[m.Booking](db.GormDB).Where("booking_id = ?", bookingID).Take(ctx)
+	if err == gorm.ErrRecordNotFound || (err == nil && user.Level < 2 && target.UserID != user.UserID) {
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: msgID,
+			Text:      "⚠️ " + booking.ErrBookingNotFound.Message,
+			ReplyMarkup: &models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{
+					{{Text: "⬅️ Back to my bookings", CallbackData: "mine:list"}},
+				},
+			},
+		})
+		return nil, false
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", bookingID).Msg("Error fetching booking from database")
+		sendError(ctx, b, chatID)
+		return nil, false
+	}
+	return &target, true
+}
+
+func confirmCancelBooking(ctx context.Context, b *bot.Bot, chatID int64, msgID int, bookingID uint, user m.User) {
+	target, ok := findOwnBooking(ctx, b, chatID, msgID, bookingID, user)
+	if !ok {
+		return
+	}
+
+	text := fmt.Sprintf(
+		"❓ <b>Cancel this booking?</b>\n\n"+
+			"📝 %s\n"+
+			"🏢 %s\n"+
+			"🕒 %s, %s - %s",
+		html.EscapeString(target.Title),
+		m.GetRoomNameFromID(int(target.RoomID)),
+		target.StartTime.Format("02 Jan 2006"),
+		target.StartTime.Format("15:04"),
+		target.EndTime.Format("15:04"),
+	)
+	if target.SeriesID != nil {
+		text += "\n\nThis booking is recurring, only this occurrence will be cancelled."
+	}
+
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: msgID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{{Text: "🗑 Yes, cancel it", CallbackData: fmt.Sprintf("mine:confirm:%d", bookingID)}},
+				{{Text: "⬅️ No, keep it", CallbackData: "mine:list"}},
+			},
+		},
+	})
+}
+
+func cancelBooking(ctx context.Context, b *bot.Bot, chatID int64, msgID int, bookingID uint, user m.User) {
+	text := "✅ Your booking has been cancelled."
+	if _, bookingErr := booking.DeleteBooking(ctx, bookingID, user.UserID, user.Level, m.ScopeOccurrence, "Cancelled via Telegram"); bookingErr != nil {
+		text = "⚠️ " + bookingErr.Message
+	} else {
+		log.Info().Uint("booking_id", bookingID).Uint("user_id", user.UserID).Msg("Booking cancelled via Telegram")
+	}
+
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: msgID,
+		Text:      text,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{{Text: "⬅️ Back to my bookings", CallbackData: "mine:list"}},
+			},
+		},
+	})
+}
+
+// editBooking opens the booking wizard at its summary, filled in with the current values of the booking. Any
+// wizard in progress in the chat is discarded.
+func editBooking(ctx context.Context, b *bot.Bot, chatID int64, msgID int, bookingID uint, user m.User) {
+	target, ok := findOwnBooking(ctx, b, chatID, msgID, bookingID, user)
+	if !ok {
+		return
+	}
+
+	unlock := wizard.Lock(chatID)
+	defer unlock()
+
+	if s, exists := loadWizard(ctx, chatID); exists && s.MessageID != msgID {
+		_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    chatID,
+			MessageID: s.MessageID,
+		})
+	}
+
+	// Bookings made on the website may not be a whole number of periods, they are rounded up
+	minutes := int(target.EndTime.Sub(target.StartTime).Minutes())
+	s := &m.BookingState{
+		ChatID:        chatID,
+		MessageID:     msgID,
+		Step:          5,
+		RoomID:        int(target.RoomID),
+		StartTime:     target.StartTime,
+		NumPeriods:    (minutes + m.BookingPeriodSize - 1) / m.BookingPeriodSize,
+		Title:         target.Title,
+		Description:   target.Description,
+		EditBookingID: &target.BookingID,
+	}
+	saveWizard(ctx, s)
+	showBookingSummary(ctx, b, s)
+}
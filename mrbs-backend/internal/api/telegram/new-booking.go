@@ -11,6 +11,7 @@ import (
 	"rep-mrbs/internal/constants"
 	"rep-mrbs/internal/db"
 	m "rep-mrbs/internal/models"
+	"rep-mrbs/internal/wizard"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -30,16 +31,16 @@ import (
 * The wizard will check for clashes at the very end and advice the user if the booking cannot be created.
 * */
 
-// UserBookingStates Map chatId to the current booking state
-var UserBookingStates = make(map[int64]*m.BookingState)
-
 func HandleNewBooking(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if update.Message == nil {
 		return
 	}
 
+	unlock := wizard.Lock(update.Message.Chat.ID)
+	defer unlock()
+
 	// Check if user is in the process of creating a new booking
-	if s, exists := UserBookingStates[update.Message.Chat.ID]; exists {
+	if s, exists := loadWizard(ctx, update.Message.Chat.ID); exists {
 		// User has a new booking in progress and ran "/new" command again. Let user choose whether to restart over or continue from where user left off.
 		log.Trace().Msg("/new is triggered by user who is in the midst of creating a new booking")
 
@@ -57,17 +58,24 @@ func HandleNewBooking(ctx context.Context, b *bot.Bot, update *models.Update) {
 			},
 		}
 
+		text := "You were previously in the middle of creating a new booking, which has not been completed. Would you like to continue from where you left off or start over?"
+		if s.EditBookingID != nil {
+			text = "You were previously in the middle of editing a booking, which has not been saved. Would you like to continue from where you left off or make a new booking instead?"
+		}
+
 		msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:      update.Message.Chat.ID,
-			Text:        "You were previously in the middle of creating a new booking, which has not been completed. Would you like to continue from where you left off or start over?",
+			Text:        text,
 			ParseMode:   models.ParseModeHTML,
 			ReplyMarkup: kb,
 		})
 		if err != nil {
 			log.Logger.Err(err).Msg(constants.SendTelegramMsgError)
+			return
 		}
 
 		s.MessageID = msg.ID
+		saveWizard(ctx, s)
 		return
 	}
 
@@ -90,7 +98,24 @@ func OnWizardCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
 
 	data := update.CallbackQuery.Data
 	chatID := update.CallbackQuery.Message.Message.Chat.ID
-	msgID := UserBookingStates[chatID].MessageID
+
+	unlock := wizard.Lock(chatID)
+	defer unlock()
+
+	s, exists := loadWizard(ctx, chatID)
+	if !exists {
+		if data == "wiz_action:discard_booking" {
+			startBookingWizard(ctx, b, chatID)
+			return
+		}
+		// Buttons of a wizard that has been completed, discarded or has expired
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: update.CallbackQuery.Message.Message.ID,
+			Text:      "⌛ This booking is no longer in progress. Type /new to start again.",
+		})
+		return
+	}
 
 	// 2. Parse the data (e.g., "wiz_room:3")
 	// Use SplitN to ensure we only get two parts: the action and the value
@@ -104,15 +129,22 @@ func OnWizardCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
 	value := parts[1]  // e.g., "today"
 
 	// 3. Send to the Dispatcher
-	BookingWizardDispatcher(ctx, b, chatID, msgID, action, value)
+	BookingWizardDispatcher(ctx, b, s, action, value)
 }
 
-func BookingWizardDispatcher(ctx context.Context, b *bot.Bot, chatID int64, msgID int, action string, value string) {
-	s, exists := UserBookingStates[chatID]
+// BookingWizardDispatcher applies a button press to the wizard s, which is saved afterwards unless the booking
+// was discarded or made.
+func BookingWizardDispatcher(ctx context.Context, b *bot.Bot, s *m.BookingState, action string, value string) {
 	log.Debug().Interface("bookings state", s).Msg("booking state")
-	if !exists {
-		startBookingWizard(ctx, b, chatID)
-	}
+	chatID, msgID := s.ChatID, s.MessageID
+
+	finished := false
+	defer func() {
+		if !finished {
+			saveWizard(ctx, s)
+		}
+	}()
+
 	_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:    chatID,
 		MessageID: msgID,
@@ -144,19 +176,33 @@ func BookingWizardDispatcher(ctx context.Context, b *bot.Bot, chatID int64, msgI
 				ChatID:    chatID,
 				MessageID: s.MessageID,
 			})
+			finished = true
 			startBookingWizard(ctx, b, chatID)
 		case "continue_booking":
-			routeToStep(ctx, b, chatID, msgID, s.Step)
+			routeToStep(ctx, b, s)
 			return
 		case "back":
 			if s.Step > 0 {
 				s.Step--
 			}
-			routeToStep(ctx, b, chatID, msgID, s.Step)
+			routeToStep(ctx, b, s)
 			return
 		case "confirm":
 			s.Step = 6
-			handleCreateBooking(ctx, b, chatID)
+			if s.EditBookingID != nil {
+				finished = handleUpdateBooking(ctx, b, s)
+				return
+			}
+			finished = handleCreateBooking(ctx, b, s)
+			return
+		case "cancel_edit":
+			finished = true
+			deleteWizard(ctx, chatID)
+			_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+				ChatID:    chatID,
+				MessageID: msgID,
+				Text:      "Your booking has not been changed. Type /mine to see your upcoming bookings.",
+			})
 			return
 		}
 
@@ -229,12 +275,12 @@ func BookingWizardDispatcher(ctx context.Context, b *bot.Bot, chatID int64, msgI
 			})
 			return
 		}
-		if s.RoomID <= 0 || s.RoomID > len(m.CachedRooms) {
-			log.Error().Msg("How on earth do you even do this?? Anyway this is not a valid room id.")
+		if s.RoomID <= 0 || !m.IsBookableRoom(uint(s.RoomID)) {
+			log.Error().Int("room_id", s.RoomID).Msg("Room selected in wizard does not exist or has been disabled.")
 			_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
 				ChatID:    chatID,
 				MessageID: msgID,
-				Text:      "⚠️ <b>An error has occured</b>\nThis is not a valid room and you should never see this message! Maybe a huge renovation has occured and REP has added more rooms.",
+				Text:      "⚠️ <b>An error has occured</b>\nThis room does not exist or is no longer available for booking. Please restart and pick another room.",
 				ParseMode: models.ParseModeHTML,
 				ReplyMarkup: &models.InlineKeyboardMarkup{
 					InlineKeyboard: [][]models.InlineKeyboardButton{
@@ -245,7 +291,7 @@ func BookingWizardDispatcher(ctx context.Context, b *bot.Bot, chatID int64, msgI
 			return
 		}
 		s.Step = 2
-		showTimeSelection(ctx, b, chatID, msgID)
+		showTimeSelection(ctx, b, s)
 	case "wiz_time":
 		if s.Step != 2 {
 			log.Warn().Int("step", s.Step).Msg("User tried to skip steps")
@@ -283,7 +329,7 @@ func BookingWizardDispatcher(ctx context.Context, b *bot.Bot, chatID int64, msgI
 
 		s.StartTime = bookingTime
 		s.Step = 3
-		showDurationSelection(ctx, b, chatID, msgID)
+		showDurationSelection(ctx, b, s)
 	case "wiz_duration":
 		if s.Step != 3 {
 			log.Warn().Int("step", s.Step).Msg("User tried to skip steps")
@@ -316,19 +362,23 @@ func BookingWizardDispatcher(ctx context.Context, b *bot.Bot, chatID int64, msgI
 			})
 			return
 		}
-		s.NumPeriods = numMinutes / 30
+		s.NumPeriods = numMinutes / m.BookingPeriodSize
+		// The title may be known already, from a typed request or the booking being edited
+		if s.Title != "" {
+			s.Step = 5
+			showBookingSummary(ctx, b, s)
+			return
+		}
 		s.Step = 4
 		showTitlePrompt(ctx, b, chatID, msgID)
 	}
 }
 
-func HandleSetTitle(ctx context.Context, b *bot.Bot, chatID int64, title string) {
-	s := UserBookingStates[chatID]
-
+func HandleSetTitle(ctx context.Context, b *bot.Bot, s *m.BookingState, title string) {
 	if s.Step != 4 {
 		log.Warn().Int("step", s.Step).Msg("User attept to skip steps")
 		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    chatID,
+			ChatID:    s.ChatID,
 			MessageID: s.MessageID,
 			Text:      "⚠️ <b>Oops, an error has occured</b>\nIt appears that you have tried to skip steps. Please restart and try again.",
 			ParseMode: models.ParseModeHTML,
@@ -342,31 +392,20 @@ func HandleSetTitle(ctx context.Context, b *bot.Bot, chatID int64, title string)
 	}
 
 	// Set title and default description
-	// Trim whitespace to avoid empty-looking titles
-	cleanTitle := strings.TrimSpace(title)
-	if cleanTitle == "" {
-		cleanTitle = "Untitled Booking"
+	s.Title = cleanTitle(title)
+	if s.EditBookingID == nil {
+		s.Description = "Booked via Telegram." // default description, edited bookings keep their own
 	}
-
-	// Safe truncation using runes
-	runes := []rune(cleanTitle)
-	if len(runes) > m.MaxTitleLength {
-		s.Title = string(runes[:m.MaxTitleLength])
-	} else {
-		s.Title = cleanTitle
-	}
-	s.Description = "Booked via Telegram." // default description
 	s.Step = 5
+	saveWizard(ctx, s)
 
-	showBookingSummary(ctx, b, chatID)
+	showBookingSummary(ctx, b, s)
 }
 
-func handleCreateBooking(ctx context.Context, b *bot.Bot, chatID int64) {
-	s, exists := UserBookingStates[chatID]
-	if !exists {
-		log.Warn().Int64("chatID", chatID).Msg("chatID does not exist in user booking states.")
-		return
-	}
+// handleCreateBooking makes the booking of a completed wizard. Returns true if the booking was made, and the wizard
+// has been deleted.
+func handleCreateBooking(ctx context.Context, b *bot.Bot, s *m.BookingState) bool {
+	chatID := s.ChatID
 
 	// Check that all previous steps have been completed
 	if s.Step != 6 {
@@ -382,13 +421,11 @@ func handleCreateBooking(ctx context.Context, b *bot.Bot, chatID int64) {
 				},
 			},
 		})
-		return
+		return false
 	}
 
-	tx := db.GormDB.Begin()
-
 	// Fetch user details
-	telegramUser, err := gorm.G[m.TelegramAuth](tx).Where("telegram_chat_id = ?", chatID).First(ctx)
+	telegramUser, err := gorm.G[m.TelegramAuth](db.GormDB).Where("telegram_chat_id = ?", chatID).First(ctx)
 
 	if err == gorm.ErrRecordNotFound {
 		log.Error().Err(err).Msg("Telegram user not found in mrbs.telegram_auth table. Was the user removed or did the user unlink his account?")
@@ -403,7 +440,7 @@ func handleCreateBooking(ctx context.Context, b *bot.Bot, chatID int64) {
 				},
 			},
 		})
-		return
+		return false
 	}
 	if err != nil {
 		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching user details from database")
@@ -418,14 +455,14 @@ func handleCreateBooking(ctx context.Context, b *bot.Bot, chatID int64) {
 				},
 			},
 		})
-		return
+		return false
 	}
 
 	// Convert request into new booking struct
 	newBooking := m.Booking{
 		UserID:      telegramUser.UserID,
 		StartTime:   s.StartTime,
-		EndTime:     s.StartTime.Add(time.Duration(s.NumPeriods) * 30 * time.Minute),
+		EndTime:     s.StartTime.Add(time.Duration(s.NumPeriods*m.BookingPeriodSize) * time.Minute),
 		TimeCreated: time.Now(),
 		RoomID:      uint(s.RoomID),
 		Title:       s.Title,
@@ -446,7 +483,7 @@ func handleCreateBooking(ctx context.Context, b *bot.Bot, chatID int64) {
 				},
 			},
 		})
-		return
+		return false
 	}
 
 	log.Info().Msg("booking successfully creation")
@@ -456,11 +493,13 @@ func handleCreateBooking(ctx context.Context, b *bot.Bot, chatID int64) {
 			"Your booking has been confirmed.\n\n"+
 			"🏢 <b>Room:</b> %s\n"+
 			"📅 <b>Date:</b> %s\n"+
-			"🕒 <b>Time:</b> %s — %s",
+			"🕒 <b>Time:</b> %s — %s\n\n"+
+			"Remember to /checkin within %d minutes of the start time, otherwise the booking will be released.",
 		m.GetRoomNameFromID(int(newBooking.RoomID)),
 		newBooking.StartTime.Format("02 Jan 2006"),
 		newBooking.StartTime.Format("15:04"),
 		newBooking.EndTime.Format("15:04"),
+		int(booking.CheckInGracePeriod.Minutes()),
 	)
 
 	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
@@ -471,11 +510,119 @@ func handleCreateBooking(ctx context.Context, b *bot.Bot, chatID int64) {
 	})
 
 	// Clear the state
-	delete(UserBookingStates, chatID)
+	deleteWizard(ctx, chatID)
+	return true
+}
+
+// handleUpdateBooking saves the changes of a wizard opened from /mine to its booking, with the same ownership rule
+// as the edit booking API. Returns true if the wizard is done with, and has been deleted.
+func handleUpdateBooking(ctx context.Context, b *bot.Bot, s *m.BookingState) bool {
+	chatID, msgID := s.ChatID, s.MessageID
+
+	// Lets the user go back to the summary to change something, or give up on the edit
+	retryKb := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: "⬅️ Back", CallbackData: "wiz_action:back"}},
+			{{Text: "❌ Cancel", CallbackData: "wiz_action:cancel_edit"}},
+		},
+	}
+
+	userID, err := getLinkedUserID(ctx, chatID)
+	if err == gorm.ErrRecordNotFound {
+		deleteWizard(ctx, chatID)
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: msgID,
+			Text:      fmt.Sprintf("Your Telegram account is not linked. Please use the code on %s/link-telegram to link your account.", constants.MRBSWebsiteURL),
+		})
+		return true
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msgID,
+			Text:        constants.DefaultErrorMsg,
+			ReplyMarkup: retryKb,
+		})
+		return false
+	}
+
+	user, err := gorm.G[m.User](db.GormDB).Where("user_id = ?", userID).Take(ctx)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching user from database")
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msgID,
+			Text:        constants.DefaultErrorMsg,
+			ReplyMarkup: retryKb,
+		})
+		return false
+	}
+
+	original, err := gorm.G[m.Booking](db.GormDB).Where("booking_id = ?", *s.EditBookingID).Take(ctx)
+	if err == gorm.ErrRecordNotFound || (err == nil && user.Level < 2 && original.UserID != userID) {
+		deleteWizard(ctx, chatID)
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: msgID,
+			Text:      "⚠️ This booking no longer exists. Type /mine to see your upcoming bookings.",
+		})
+		return true
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", *s.EditBookingID).Msg("Error fetching booking to edit")
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msgID,
+			Text:        constants.DefaultErrorMsg,
+			ReplyMarkup: retryKb,
+		})
+		return false
+	}
+
+	edited := original
+	edited.StartTime = s.StartTime
+	edited.EndTime = s.StartTime.Add(time.Duration(s.NumPeriods*m.BookingPeriodSize) * time.Minute)
+	edited.RoomID = uint(s.RoomID)
+	edited.Title = s.Title
+	edited.Description = s.Description
+
+	if bookingErr := booking.UpdateBooking(ctx, &edited, userID, user.Level); bookingErr != nil {
+		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msgID,
+			Text:        bookingErr.Message,
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: retryKb,
+		})
+		return false
+	}
+
+	log.Info().Uint("booking_id", edited.BookingID).Msg("Booking updated via Telegram")
+
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: msgID,
+		Text: fmt.Sprintf(
+			"✏️ <b>Booking updated!</b>\n\n"+
+				"🏢 <b>Room:</b> %s\n"+
+				"📅 <b>Date:</b> %s\n"+
+				"🕒 <b>Time:</b> %s — %s",
+			m.GetRoomNameFromID(int(edited.RoomID)),
+			edited.StartTime.Format("02 Jan 2006"),
+			edited.StartTime.Format("15:04"),
+			edited.EndTime.Format("15:04"),
+		),
+		ParseMode: models.ParseModeHTML,
+	})
+
+	deleteWizard(ctx, chatID)
+	return true
 }
 
 func startBookingWizard(ctx context.Context, b *bot.Bot, chatID int64) {
-	UserBookingStates[chatID] = &m.BookingState{Step: 0}
+	s := &m.BookingState{ChatID: chatID, Step: 0}
 
 	// Initial message creation
 	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
@@ -488,6 +635,7 @@ func startBookingWizard(ctx context.Context, b *bot.Bot, chatID int64) {
 		return
 	}
 
-	UserBookingStates[chatID].MessageID = msg.ID
+	s.MessageID = msg.ID
+	saveWizard(ctx, s)
 	showDateSelection(ctx, b, chatID, msg.ID)
 }
@@ -0,0 +1,84 @@
+package telegram
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+type ReminderSettingsResponse struct {
+	Linked         bool `json:"linked"`          // Reminders are only sent once Telegram is linked
+	Minutes        int  `json:"minutes"`         // Lead time in minutes, 0 if reminders are off
+	IsDefault      bool `json:"is_default"`      // The user has not chosen a lead time
+	DefaultMinutes int  `json:"default_minutes"` // Lead time used until the user chooses one
+}
+
+type SetReminderSettingsRequest struct {
+	Minutes *int `json:"minutes"` // null: default lead time, 0: reminders off
+}
+
+// HandleGetReminderSettings returns the Telegram reminder settings of the user.
+func HandleGetReminderSettings(c *gin.Context) {
+	auth, err := gorm.G[models.TelegramAuth](db.GormDB).Where("user_id = ?", api.GetUIDFromContext(c)).Take(c)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error().Err(err).Msg("Error fetching reminder settings")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReminderSettingsResponse{
+		Linked:         auth.TelegramChatID != nil,
+		Minutes:        reminderMinutes(&auth),
+		IsDefault:      auth.ReminderMinutes == nil,
+		DefaultMinutes: int(booking.ReminderLeadTime.Minutes()),
+	})
+}
+
+// HandleSetReminderSettings changes the reminder lead time of the user, or turns reminders off.
+func HandleSetReminderSettings(c *gin.Context) {
+	var req SetReminderSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn().Err(err).Msg("Error binding reminder settings request")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Error binding request. Please try again later.",
+		})
+		return
+	}
+	if req.Minutes != nil && (*req.Minutes < 0 || *req.Minutes > models.MaxReminderMinutes) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "minutes must be between 0 and " + strconv.Itoa(models.MaxReminderMinutes),
+		})
+		return
+	}
+
+	err := setReminderMinutes(c, api.GetUIDFromContext(c), req.Minutes)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Link your Telegram account before setting up reminders",
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error saving reminder settings")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reminder settings saved",
+	})
+}
@@ -0,0 +1,315 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// reminderChoices - lead times offered by the /reminders buttons, in minutes.
+var reminderChoices = []int{5, 10, 15, 30, 60}
+
+func (n telegramNotifier) sendBookingReminder(ctx context.Context, chatID int64, bk *m.Booking) error {
+	text := fmt.Sprintf(
+		"⏰ <b>Your booking starts soon</b>\n\n"+
+			"📝 %s\n"+
+			"🏢 <b>Room:</b> %s\n"+
+			"📅 <b>Date:</b> %s\n"+
+			"🕒 <b>Time:</b> %s — %s\n\n"+
+			"Remember to check in within %d minutes of the start time, otherwise the booking will be released.",
+		html.EscapeString(bk.Title),
+		m.GetRoomNameFromID(int(bk.RoomID)),
+		bk.StartTime.Format("02 Jan 2006"),
+		bk.StartTime.Format("15:04"),
+		bk.EndTime.Format("15:04"),
+		int(booking.CheckInGracePeriod.Minutes()),
+	)
+
+	row := []models.InlineKeyboardButton{{Text: "📍 Check in", CallbackData: fmt.Sprintf("checkin:%d", bk.BookingID)}}
+	if newEnd, ok := extendedEndTime(ctx, bk); ok {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         "⏩ Extend to " + newEnd.Format("15:04"),
+			CallbackData: fmt.Sprintf("reminder:extend:%d", bk.BookingID),
+		})
+	}
+	row = append(row, models.InlineKeyboardButton{Text: "❌ Cancel", CallbackData: fmt.Sprintf("mine:cancel:%d", bk.BookingID)})
+
+	_, err := n.b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}},
+	})
+	return err
+}
+
+// extendedEndTime returns the end time of the booking extended by one slot of its area. ok is false if the
+// extension would fall outside the opening hours or the maximum duration, or if the room is booked in the meantime.
+// Quotas are left to booking.UpdateBooking.
+func extendedEndTime(ctx context.Context, bk *m.Booking) (time.Time, bool) {
+	area, ok := m.GetAreaForRoom(bk.RoomID)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	newEnd := bk.EndTime.Add(slotStep(area))
+	if !area.IsOpen(bk.StartTime, newEnd) {
+		return time.Time{}, false
+	}
+	if maxDuration, ok := area.MaxBookingDuration(); ok && newEnd.Sub(bk.StartTime) > maxDuration {
+		return time.Time{}, false
+	}
+
+	clashes, err := gorm.G[m.Booking](db.GormDB).
+		Where("room_id = ? AND start_time < ? AND end_time > ? AND booking_id != ?", bk.RoomID, newEnd, bk.EndTime, bk.BookingID).
+		Count(ctx, "booking_id")
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", bk.BookingID).Msg("Error checking if the next slot is free")
+		return time.Time{}, false
+	}
+	return newEnd, clashes == 0
+}
+
+// OnReminderCallback handles the extend button of booking reminders, callback data format: "reminder:extend:<booking id>".
+// The check in and cancel buttons are handled by OnCheckInCallback and OnMyBookingsCallback.
+func OnReminderCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error answering callback query")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgID := update.CallbackQuery.Message.Message.ID
+
+	parts := strings.Split(update.CallbackQuery.Data, ":")
+	if len(parts) != 3 || parts[1] != "extend" {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+	bookingID, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+
+	userID, err := getLinkedUserID(ctx, chatID)
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	user, err := gorm.G[m.User](db.GormDB).Where("user_id = ?", userID).Take(ctx)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Error fetching user from database")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	target, ok := findOwnBooking(ctx, b, chatID, msgID, uint(bookingID), user)
+	if !ok {
+		return
+	}
+
+	var text string
+	newEnd, free := extendedEndTime(ctx, target)
+	switch {
+	case !target.EndTime.After(time.Now()):
+		text = "⚠️ This booking has already ended."
+	case !free:
+		text = "⚠️ The booking cannot be extended, the next slot is no longer free."
+	default:
+		extended := *target
+		extended.EndTime = newEnd
+		if bookingErr := booking.UpdateBooking(ctx, &extended, user.UserID, user.Level); bookingErr != nil {
+			text = "⚠️ " + bookingErr.Message
+			break
+		}
+		log.Info().Uint("booking_id", extended.BookingID).Time("end_time", newEnd).Msg("Booking extended via Telegram")
+		text = fmt.Sprintf("⏩ <b>Booking extended!</b>\n%s is now booked from %s to %s on %s.",
+			m.GetRoomNameFromID(int(extended.RoomID)),
+			extended.StartTime.Format("15:04"),
+			extended.EndTime.Format("15:04"),
+			extended.StartTime.Format("02 Jan 2006"))
+	}
+
+	if _, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: msgID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error editing reminder message")
+	}
+}
+
+// HandleReminders shows or changes the reminder lead time of the linked user.
+// Usage: "/reminders", "/reminders <minutes>" or "/reminders off".
+func HandleReminders(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	auth, err := gorm.G[m.TelegramAuth](db.GormDB).Where("telegram_chat_id = ?", chatID).Take(ctx)
+	if err == gorm.ErrRecordNotFound {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("Your Telegram account is not linked. Please use the code on %s/link-telegram to link your account.", constants.MRBSWebsiteURL),
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	arg := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/reminders")))
+	if arg != "" {
+		minutes, ok := parseReminderMinutes(arg)
+		if !ok {
+			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatID,
+				Text:   fmt.Sprintf("Usage: /reminders <minutes> to be reminded that many minutes before your bookings start (1 to %d), or /reminders off.", m.MaxReminderMinutes),
+			})
+			return
+		}
+		if err = setReminderMinutes(ctx, auth.UserID, &minutes); err != nil {
+			log.Error().Err(err).Uint("user_id", auth.UserID).Msg("Error saving reminder settings")
+			sendError(ctx, b, chatID)
+			return
+		}
+		auth.ReminderMinutes = &minutes
+	}
+
+	text, kb := remindersMessage(&auth)
+	if _, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: kb,
+	}); err != nil {
+		log.Error().Err(err).Msg(constants.SendTelegramMsgError)
+	}
+}
+
+// OnRemindersCallback handles the /reminders buttons, callback data format: "reminders:<minutes|off>"
+func OnRemindersCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error answering callback query")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgID := update.CallbackQuery.Message.Message.ID
+
+	minutes, ok := parseReminderMinutes(strings.TrimPrefix(update.CallbackQuery.Data, "reminders:"))
+	if !ok {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+
+	auth, err := gorm.G[m.TelegramAuth](db.GormDB).Where("telegram_chat_id = ?", chatID).Take(ctx)
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	if err = setReminderMinutes(ctx, auth.UserID, &minutes); err != nil {
+		log.Error().Err(err).Uint("user_id", auth.UserID).Msg("Error saving reminder settings")
+		sendError(ctx, b, chatID)
+		return
+	}
+	auth.ReminderMinutes = &minutes
+
+	text, kb := remindersMessage(&auth)
+	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   msgID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: kb,
+	})
+}
+
+// remindersMessage describes the reminder settings of a user, with buttons to change them.
+func remindersMessage(auth *m.TelegramAuth) (string, *models.InlineKeyboardMarkup) {
+	text := "🔕 <b>Booking reminders are off.</b>\nChoose when you would like to be reminded before your bookings start:"
+	if minutes := reminderMinutes(auth); minutes > 0 {
+		text = fmt.Sprintf("⏰ <b>You are reminded %d minutes before your bookings start.</b>\nChoose another time or turn reminders off:", minutes)
+	}
+
+	var row []models.InlineKeyboardButton
+	for _, choice := range reminderChoices {
+		row = append(row, models.InlineKeyboardButton{Text: fmt.Sprintf("%d min", choice), CallbackData: fmt.Sprintf("reminders:%d", choice)})
+	}
+	return text, &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			row,
+			{{Text: "🔕 Off", CallbackData: "reminders:off"}},
+		},
+	}
+}
+
+// parseReminderMinutes parses a lead time in minutes, or "off" for 0.
+func parseReminderMinutes(value string) (int, bool) {
+	if value == "off" {
+		return 0, true
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 1 || minutes > m.MaxReminderMinutes {
+		return 0, false
+	}
+	return minutes, true
+}
+
+// reminderMinutes returns the lead time of the user's reminders in minutes, 0 if reminders are off.
+func reminderMinutes(auth *m.TelegramAuth) int {
+	if auth.ReminderMinutes == nil {
+		return int(booking.ReminderLeadTime.Minutes())
+	}
+	return *auth.ReminderMinutes
+}
+
+// setReminderMinutes saves the reminder lead time of a user, nil for the default lead time and 0 to turn reminders off.
+// Returns gorm.ErrRecordNotFound if the user has never started linking Telegram.
+func setReminderMinutes(ctx context.Context, userID uint, minutes *int) error {
+	rows, err := gorm.G[m.TelegramAuth](db.GormDB).
+		Where("user_id = ?", userID).
+		Select("reminder_minutes").
+		Updates(ctx, m.TelegramAuth{ReminderMinutes: minutes})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
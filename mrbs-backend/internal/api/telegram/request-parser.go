@@ -0,0 +1,592 @@
+package telegram
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	m "rep-mrbs/internal/models"
+)
+
+/**
+* NOTES:
+* Free-text booking requests, e.g. "Da Vinci tomorrow 3pm for 2h team sync", are parsed word by word. Each part of the
+* booking (room, date, time, duration) is looked for in the words not taken by the previous parts, and the words left
+* over make up the title. Parts that are not found are left empty and asked for by the booking wizard.
+* */
+
+// requestLocation - time zone of the dates and times in booking requests.
+var requestLocation = time.FixedZone("GMT", 8*3600)
+
+// bookingRequest holds the parts of a booking recognised in a free-text request. Zero values were not recognised.
+type bookingRequest struct {
+	RoomID   uint
+	Date     time.Time     // Midnight of the day of the booking
+	Start    time.Duration // Start time since midnight
+	HasStart bool
+	Minutes  int // Duration
+	Title    string
+}
+
+// recognised reports whether the text looked like a booking request at all.
+func (r *bookingRequest) recognised() bool {
+	return r.RoomID != 0 || !r.Date.IsZero() || r.HasStart
+}
+
+// day returns midnight of the day of the booking. A time without a date is taken as the next time it comes round:
+// today, or tomorrow if the time has passed. Returns the zero time if neither was given.
+func (r *bookingRequest) day(now time.Time) time.Time {
+	if !r.Date.IsZero() || !r.HasStart {
+		return r.Date
+	}
+
+	now = now.In(requestLocation)
+	y, mo, d := now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, requestLocation)
+	if today.Add(r.Start).After(now) {
+		return today
+	}
+	return today.AddDate(0, 0, 1)
+}
+
+var (
+	reClock      = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?(am|pm)?$`)
+	reHours      = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|hr|hrs|hour|hours)$`)
+	reMinutes    = regexp.MustCompile(`^(\d+)(m|min|mins|minute|minutes)$`)
+	reHoursMins  = regexp.MustCompile(`^(\d+)h(\d{1,2})(m|min|mins)?$`)
+	reNumber     = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+	reNumDate    = regexp.MustCompile(`^(\d{1,2})[/-](\d{1,2})(?:[/-](\d{2}|\d{4}))?$`)
+	reISODate    = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	reDayOfMonth = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+)
+
+var hourUnits = map[string]bool{"h": true, "hr": true, "hrs": true, "hour": true, "hours": true}
+var minuteUnits = map[string]bool{"m": true, "min": true, "mins": true, "minute": true, "minutes": true}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// fillerWords are dropped from the start and end of the title, e.g. "book ... for", "at".
+var fillerWords = map[string]bool{
+	"a": true, "an": true, "the": true, "book": true, "booking": true, "reserve": true, "please": true, "pls": true,
+	"for": true, "at": true, "on": true, "in": true, "from": true, "to": true, "with": true, "room": true, "i": true,
+	"want": true, "need": true, "would": true, "like": true, "can": true, "you": true, "me": true, "and": true,
+}
+
+// requestParser keeps track of the words of a request that have been recognised.
+type requestParser struct {
+	words []string // Original words, for the title
+	lower []string // Lower case words without surrounding punctuation
+	used  []bool
+	today time.Time // Midnight of the day the request was made
+}
+
+// parseBookingRequest recognises the room, date, start time, duration and title of a free-text booking request.
+// Room names are matched against rooms, allowing for typos. Relative dates are resolved from now.
+func parseBookingRequest(text string, now time.Time, rooms []m.Room) bookingRequest {
+	now = now.In(requestLocation)
+	y, mo, d := now.Date()
+	p := &requestParser{today: time.Date(y, mo, d, 0, 0, 0, 0, requestLocation)}
+
+	// "3 p.m." is read as "3 pm", and "3–5pm" as "3-5pm"
+	replacer := strings.NewReplacer("a.m.", "am", "p.m.", "pm", "–", "-", "—", "-")
+	for _, word := range strings.Fields(replacer.Replace(text)) {
+		p.words = append(p.words, word)
+		p.lower = append(p.lower, strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+			return unicode.IsPunct(r) && r != '-' && r != '.'
+		})))
+	}
+	p.used = make([]bool, len(p.words))
+	for i, word := range p.lower {
+		p.lower[i] = strings.TrimRight(word, ".")
+	}
+
+	var req bookingRequest
+	req.RoomID = p.findRoom(rooms)
+	req.Date = p.findDate()
+	req.Start, req.HasStart, req.Minutes = p.findTime()
+	// An end time takes precedence over a duration, which is still looked for so that it is left out of the title
+	if minutes := p.findDuration(); req.Minutes == 0 {
+		req.Minutes = minutes
+	}
+	req.Title = p.title()
+	return req
+}
+
+func (p *requestParser) free(i int) bool {
+	return i >= 0 && i < len(p.lower) && !p.used[i]
+}
+
+func (p *requestParser) take(from int, to int) {
+	for i := from; i < to; i++ {
+		p.used[i] = true
+	}
+}
+
+// takePreposition marks the word before i as recognised if it is one of words, e.g. "at" in "at 3pm".
+func (p *requestParser) takePreposition(i int, words ...string) {
+	if !p.free(i - 1) {
+		return
+	}
+	for _, word := range words {
+		if p.lower[i-1] == word {
+			p.used[i-1] = true
+			return
+		}
+	}
+}
+
+// findRoom returns the room named in the request, or 0. A run of words matches a room if it is close to the full
+// name of the room, e.g. "davinci" or "da vinchi", or if every word is in the name of that room only, e.g. "vinci"
+// or "seminar 1". Longer runs are preferred, a run matching several rooms equally well is ignored.
+func (p *requestParser) findRoom(rooms []m.Room) uint {
+	for size := min(4, len(p.lower)); size >= 1; size-- {
+		for start := 0; start+size <= len(p.lower); start++ {
+			run := p.lower[start : start+size]
+			if !hasLongWord(run) {
+				continue
+			}
+
+			var match uint
+			best, ambiguous := -1, false
+			for _, room := range rooms {
+				if room.Disabled {
+					continue
+				}
+				score, ok := roomMatch(run, roomWords(room.DisplayName))
+				if !ok {
+					continue
+				}
+				if best == -1 || score < best {
+					match, best, ambiguous = room.RoomID, score, false
+				} else if score == best {
+					ambiguous = true
+				}
+			}
+			if match != 0 && !ambiguous {
+				p.take(start, start+size)
+				return match
+			}
+		}
+	}
+	return 0
+}
+
+// roomMatch returns how far apart a run of words and the words of a room name are (lower is closer).
+func roomMatch(run []string, name []string) (int, bool) {
+	// Numbers must match exactly, "seminar room 3" is not "seminar room 1"
+	if digits(strings.Join(run, "")) != digits(strings.Join(name, "")) && digits(strings.Join(run, "")) != "" {
+		return 0, false
+	}
+
+	// Close to the full name, with or without spaces
+	for _, candidate := range []string{strings.Join(run, " "), strings.Join(run, "")} {
+		full := strings.Join(name, " ")
+		if candidate == strings.Join(name, "") {
+			return 0, true
+		}
+		if dist := levenshtein(candidate, full); dist <= typoAllowance(full) {
+			return dist, true
+		}
+	}
+
+	// Every word is one of the words of the name
+	total := 0
+	for _, word := range run {
+		closest := -1
+		for _, nameWord := range name {
+			if dist := levenshtein(word, nameWord); dist <= typoAllowance(nameWord) && (closest == -1 || dist < closest) {
+				closest = dist
+			}
+		}
+		if closest == -1 {
+			return 0, false
+		}
+		total += closest
+	}
+	// Partial matches rank after full names
+	return total + 1, true
+}
+
+// roomWords splits a room name into lower case words.
+func roomWords(name string) []string {
+	return strings.Fields(strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, name)))
+}
+
+// hasLongWord reports whether the run has a word of 3 letters or more, so that numbers or words such as "a" and "da"
+// are not taken as room names on their own.
+func hasLongWord(run []string) bool {
+	for _, word := range run {
+		letters := 0
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+		if letters >= 3 && !fillerWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// typoAllowance - number of typos tolerated in a word or name, none for short words.
+func typoAllowance(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// findDate returns midnight of the date in the request, or the zero time. Dates are read day first (25/12).
+func (p *requestParser) findDate() time.Time {
+	for i, word := range p.lower {
+		if !p.free(i) {
+			continue
+		}
+
+		switch word {
+		case "today", "tdy", "tonight":
+			p.take(i, i+1)
+			return p.today
+		case "tomorrow", "tmr", "tmrw", "tomorow", "tommorow":
+			// "day after tomorrow"
+			if p.free(i-2) && p.lower[i-2] == "day" && p.free(i-1) && p.lower[i-1] == "after" {
+				p.take(i-2, i+1)
+				return p.today.AddDate(0, 0, 2)
+			}
+			p.take(i, i+1)
+			return p.today.AddDate(0, 0, 1)
+		}
+
+		if weekday, ok := weekdays[word]; ok {
+			days := (int(weekday) - int(p.today.Weekday()) + 7) % 7
+			if p.free(i-1) && p.lower[i-1] == "next" && days == 0 {
+				days = 7
+			}
+			p.take(i, i+1)
+			p.takePreposition(i, "next", "this")
+			p.takePreposition(i-1, "on")
+			return p.today.AddDate(0, 0, days)
+		}
+
+		if match := reISODate.FindStringSubmatch(word); match != nil {
+			if date, ok := p.makeDate(match[3], match[2], match[1]); ok {
+				p.take(i, i+1)
+				p.takePreposition(i, "on")
+				return date
+			}
+		}
+		if match := reNumDate.FindStringSubmatch(word); match != nil {
+			if date, ok := p.makeDate(match[1], match[2], match[3]); ok {
+				p.take(i, i+1)
+				p.takePreposition(i, "on")
+				return date
+			}
+		}
+
+		// "25 dec", "25th december" or "dec 25"
+		if month, ok := months[word]; ok {
+			for _, j := range []int{i - 1, i + 1} {
+				if !p.free(j) {
+					continue
+				}
+				if match := reDayOfMonth.FindStringSubmatch(p.lower[j]); match != nil {
+					if date, ok := p.makeDate(match[1], strconv.Itoa(int(month)), ""); ok {
+						p.take(min(i, j), max(i, j)+1)
+						p.takePreposition(min(i, j), "on")
+						return date
+					}
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// makeDate builds a date from its parts. Without a year, the next occurrence of the date is used.
+func (p *requestParser) makeDate(day string, month string, year string) (time.Time, bool) {
+	d, _ := strconv.Atoi(day)
+	mo, _ := strconv.Atoi(month)
+	if mo < 1 || mo > 12 || d < 1 || d > 31 {
+		return time.Time{}, false
+	}
+
+	y := p.today.Year()
+	if year != "" {
+		y, _ = strconv.Atoi(year)
+		if y < 100 {
+			y += 2000
+		}
+	}
+
+	date := time.Date(y, time.Month(mo), d, 0, 0, 0, 0, requestLocation)
+	if date.Day() != d {
+		return time.Time{}, false // e.g. 31/02
+	}
+	if year == "" && date.Before(p.today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, true
+}
+
+// findTime returns the start time in the request, and the duration in minutes if an end time is given as well,
+// e.g. "3pm", "at 15:30", "3-5pm" or "from 3pm to 4:30pm".
+func (p *requestParser) findTime() (time.Duration, bool, int) {
+	for i, word := range p.lower {
+		if !p.free(i) {
+			continue
+		}
+
+		// "3-5pm", "3pm-5pm"
+		if parts := strings.Split(word, "-"); len(parts) == 2 {
+			startClock, startOK := parseClock(parts[0], "", true)
+			endClock, endOK := parseClock(parts[1], p.next(i), true)
+			written := startClock.meridiem != "" || endClock.meridiem != "" || (strings.ContainsAny(parts[0], ":.") && strings.ContainsAny(parts[1], ":."))
+			if startOK && endOK && written {
+				start, end := resolveRange(startClock, endClock)
+				p.take(i, i+1)
+				if endClock.meridiemWord {
+					p.take(i+1, i+2)
+				}
+				p.takePreposition(i, "at", "from")
+				return start, true, int((end - start).Minutes())
+			}
+		}
+
+		preceded := p.free(i-1) && (p.lower[i-1] == "at" || p.lower[i-1] == "from")
+		startClock, ok := parseClock(word, p.next(i), preceded)
+		if !ok {
+			continue
+		}
+		length := 1
+		if startClock.meridiemWord {
+			length = 2
+		}
+		p.take(i, i+length)
+		p.takePreposition(i, "at", "from")
+
+		// End time: "3pm to 5pm", "3pm - 5pm", "3pm until 5pm"
+		j := i + length
+		if p.free(j) && p.free(j+1) && isRangeWord(p.lower[j]) {
+			if endClock, ok := parseClock(p.lower[j+1], p.next(j+1), true); ok {
+				start, end := resolveRange(startClock, endClock)
+				p.take(j, j+2)
+				if endClock.meridiemWord {
+					p.take(j+2, j+3)
+				}
+				return start, true, int((end - start).Minutes())
+			}
+		}
+		return startClock.resolve(), true, 0
+	}
+	return 0, false, 0
+}
+
+func isRangeWord(word string) bool {
+	return word == "to" || word == "-" || word == "until" || word == "till" || word == "til"
+}
+
+// next returns the word after i if it has not been recognised yet.
+func (p *requestParser) next(i int) string {
+	if p.free(i + 1) {
+		return p.lower[i+1]
+	}
+	return ""
+}
+
+// clock is a time of day as written in the request.
+type clock struct {
+	hour, minute int
+	meridiem     string // "am", "pm", or empty for 24 hour or ambiguous times
+	meridiemWord bool   // The meridiem is the next word, as in "3 pm"
+}
+
+// parseClock reads a time of day from word, using next for "3 pm". Hours without minutes or meridiem, such as "3",
+// are only taken as times if bare is true, as they could be durations or room numbers.
+func parseClock(word string, next string, bare bool) (clock, bool) {
+	switch word {
+	case "noon", "midday":
+		return clock{hour: 12, meridiem: "pm"}, true
+	}
+
+	match := reClock.FindStringSubmatch(word)
+	if match == nil {
+		return clock{}, false
+	}
+	c := clock{meridiem: match[3]}
+	c.hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		c.minute, _ = strconv.Atoi(match[2])
+	}
+	if c.meridiem == "" && (next == "am" || next == "pm") {
+		c.meridiem, c.meridiemWord = next, true
+	}
+
+	if c.minute > 59 || c.hour > 23 || (c.meridiem != "" && (c.hour < 1 || c.hour > 12)) {
+		return clock{}, false
+	}
+	if c.meridiem == "" && match[2] == "" && !bare {
+		return clock{}, false
+	}
+	return c, true
+}
+
+// resolve converts the clock into the time since midnight. Times without a meridiem before 8 are taken as
+// afternoon times, since rooms are rarely booked before 8am.
+func (c clock) resolve() time.Duration {
+	hour := c.hour
+	switch {
+	case c.meridiem == "am" && hour == 12:
+		hour = 0
+	case c.meridiem == "pm" && hour < 12:
+		hour += 12
+	case c.meridiem == "" && hour >= 1 && hour < 8:
+		hour += 12
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(c.minute)*time.Minute
+}
+
+// resolveRange converts a start and end time, where the meridiem may only be given once, as in "3-5pm" or "11-1pm".
+func resolveRange(start clock, end clock) (time.Duration, time.Duration) {
+	if start.meridiem == "" && end.meridiem != "" {
+		start.meridiem = end.meridiem
+		if end.meridiem == "pm" && start.hour != 12 && start.hour > end.hour && end.hour != 12 {
+			start.meridiem = "am" // "11-1pm"
+		}
+	}
+	if end.meridiem == "" && start.meridiem != "" {
+		end.meridiem = start.meridiem
+		if start.meridiem == "am" && end.hour < start.hour {
+			end.meridiem = "pm" // "11am-1"
+		}
+	}
+	return start.resolve(), end.resolve()
+}
+
+// findDuration returns the duration in the request in minutes, e.g. "2h", "90 mins", "1h30", "1.5 hours" or
+// "an hour", or 0.
+func (p *requestParser) findDuration() int {
+	for i, word := range p.lower {
+		if !p.free(i) {
+			continue
+		}
+
+		minutes, length := 0, 1
+		if match := reHoursMins.FindStringSubmatch(word); match != nil {
+			h, _ := strconv.Atoi(match[1])
+			mins, _ := strconv.Atoi(match[2])
+			minutes = h*60 + mins
+		} else if match := reHours.FindStringSubmatch(word); match != nil {
+			h, _ := strconv.ParseFloat(match[1], 64)
+			minutes = int(h * 60)
+		} else if match := reMinutes.FindStringSubmatch(word); match != nil {
+			minutes, _ = strconv.Atoi(match[1])
+		} else if reNumber.MatchString(word) && p.free(i+1) && hourUnits[p.lower[i+1]] {
+			h, _ := strconv.ParseFloat(word, 64)
+			minutes, length = int(h*60), 2
+		} else if reNumber.MatchString(word) && p.free(i+1) && minuteUnits[p.lower[i+1]] {
+			minutes, _ = strconv.Atoi(word)
+			length = 2
+		} else if (word == "an" || word == "a" || word == "one") && p.free(i+1) && p.lower[i+1] == "hour" {
+			minutes, length = 60, 2
+			if p.free(i-1) && p.lower[i-1] == "half" {
+				minutes, i, length = 30, i-1, 3 // "half an hour"
+			}
+		} else if word == "half" && p.free(i+1) && p.lower[i+1] == "hour" {
+			minutes, length = 30, 2
+		}
+
+		if minutes > 0 {
+			p.take(i, i+length)
+			p.takePreposition(i, "for")
+			return minutes
+		}
+	}
+	return 0
+}
+
+// title returns the words that were not recognised, without filler words at either end.
+func (p *requestParser) title() string {
+	var words []string
+	var lower []string
+	for i := range p.words {
+		if !p.used[i] {
+			words = append(words, p.words[i])
+			lower = append(lower, p.lower[i])
+		}
+	}
+
+	start, end := 0, len(words)
+	for start < end && (fillerWords[lower[start]] || lower[start] == "") {
+		start++
+	}
+	for end > start && (fillerWords[lower[end-1]] || lower[end-1] == "") {
+		end--
+	}
+	return strings.Trim(strings.Join(words[start:end], " "), " ,;:-")
+}
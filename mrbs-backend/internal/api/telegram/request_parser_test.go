@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	m "rep-mrbs/internal/models"
+)
+
+var testRooms = []m.Room{
+	{RoomID: 1, DisplayName: "Seminar Room 1"},
+	{RoomID: 2, DisplayName: "Seminar Room 2"},
+	{RoomID: 3, DisplayName: "Alan Turing"},
+	{RoomID: 4, DisplayName: "Da Vinci"},
+	{RoomID: 5, DisplayName: "Ada Lovelace", Disabled: true},
+}
+
+func TestParseBookingRequest(t *testing.T) {
+	// Monday 12 January 2026, 10am
+	now := time.Date(2026, 1, 12, 10, 0, 0, 0, requestLocation)
+	date := func(y int, mo time.Month, d int) time.Time {
+		return time.Date(y, mo, d, 0, 0, 0, 0, requestLocation)
+	}
+
+	tests := []struct {
+		text string
+		want bookingRequest
+	}{
+		{
+			text: "Da Vinci tomorrow 3pm for 2h team sync",
+			want: bookingRequest{RoomID: 4, Date: date(2026, 1, 13), Start: 15 * time.Hour, HasStart: true, Minutes: 120, Title: "team sync"},
+		},
+		// Typos in room names
+		{
+			text: "davinchi at 10am",
+			want: bookingRequest{RoomID: 4, Start: 10 * time.Hour, HasStart: true},
+		},
+		{
+			text: "alan turnig friday 2pm interviews",
+			want: bookingRequest{RoomID: 3, Date: date(2026, 1, 16), Start: 14 * time.Hour, HasStart: true, Title: "interviews"},
+		},
+		{
+			text: "book semnar 2 today 4pm",
+			want: bookingRequest{RoomID: 2, Date: date(2026, 1, 12), Start: 16 * time.Hour, HasStart: true},
+		},
+		// Ranges
+		{
+			text: "seminar 1 3-5pm",
+			want: bookingRequest{RoomID: 1, Start: 15 * time.Hour, HasStart: true, Minutes: 120},
+		},
+		{
+			text: "Turing 11-1pm lunch talk",
+			want: bookingRequest{RoomID: 3, Start: 11 * time.Hour, HasStart: true, Minutes: 120, Title: "lunch talk"},
+		},
+		{
+			text: "Da Vinci from 3pm to 4:30pm",
+			want: bookingRequest{RoomID: 4, Start: 15 * time.Hour, HasStart: true, Minutes: 90},
+		},
+		{
+			text: "Da Vinci 9:30-11:00 standup",
+			want: bookingRequest{RoomID: 4, Start: 9*time.Hour + 30*time.Minute, HasStart: true, Minutes: 90, Title: "standup"},
+		},
+		// Explicit dates
+		{
+			text: "Da Vinci 25/12 10am",
+			want: bookingRequest{RoomID: 4, Date: date(2026, 12, 25), Start: 10 * time.Hour, HasStart: true},
+		},
+		{
+			text: "Da Vinci on 2026-03-02 at 2pm for 90 mins",
+			want: bookingRequest{RoomID: 4, Date: date(2026, 3, 2), Start: 14 * time.Hour, HasStart: true, Minutes: 90},
+		},
+		{
+			text: "Turing 3rd feb 11am",
+			want: bookingRequest{RoomID: 3, Date: date(2026, 2, 3), Start: 11 * time.Hour, HasStart: true},
+		},
+		{
+			// A date without a year that has passed is next year's
+			text: "Turing 5/1 11am",
+			want: bookingRequest{RoomID: 3, Date: date(2027, 1, 5), Start: 11 * time.Hour, HasStart: true},
+		},
+		// Disabled rooms are not matched
+		{
+			text: "Ada Lovelace tomorrow",
+			want: bookingRequest{Date: date(2026, 1, 13), Title: "Ada Lovelace"},
+		},
+		// Unrecognised input
+		{
+			text: "hello there",
+			want: bookingRequest{Title: "hello there"},
+		},
+		{
+			text: "",
+			want: bookingRequest{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := parseBookingRequest(tt.text, now, testRooms)
+			if got.RoomID != tt.want.RoomID || !got.Date.Equal(tt.want.Date) || got.Start != tt.want.Start ||
+				got.HasStart != tt.want.HasStart || got.Minutes != tt.want.Minutes || got.Title != tt.want.Title {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+			if got.recognised() != tt.want.recognised() {
+				t.Fatalf("expected recognised %v, got %v", tt.want.recognised(), got.recognised())
+			}
+		})
+	}
+}
+
+func TestBookingRequestDay(t *testing.T) {
+	now := time.Date(2026, 1, 12, 10, 0, 0, 0, requestLocation)
+	today := time.Date(2026, 1, 12, 0, 0, 0, 0, requestLocation)
+
+	tests := []struct {
+		name string
+		req  bookingRequest
+		want time.Time
+	}{
+		{"time later today", bookingRequest{Start: 15 * time.Hour, HasStart: true}, today},
+		{"time already passed", bookingRequest{Start: 9 * time.Hour, HasStart: true}, today.AddDate(0, 0, 1)},
+		{"explicit date", bookingRequest{Date: today.AddDate(0, 0, 3), Start: 9 * time.Hour, HasStart: true}, today.AddDate(0, 0, 3)},
+		{"neither", bookingRequest{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.day(now); !got.Equal(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/notify"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-telegram/bot"
@@ -41,7 +42,21 @@ func SetupBot(ctx context.Context) (http.Handler, error) {
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypePrefix, HandleStartChat)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/list", bot.MatchTypePrefix, HandleListBookings)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/new", bot.MatchTypePrefix, HandleNewBooking)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/checkin", bot.MatchTypePrefix, HandleCheckIn)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/mine", bot.MatchTypePrefix, HandleMyBookings)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/reminders", bot.MatchTypePrefix, HandleReminders)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/free", bot.MatchTypePrefix, HandleFreeRooms)
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "wiz_", bot.MatchTypePrefix, OnWizardCallback) // callback handler for new booking wizard
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "checkin:", bot.MatchTypePrefix, OnCheckInCallback)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "waitlist:", bot.MatchTypePrefix, OnWaitlistCallback)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "mine:", bot.MatchTypePrefix, OnMyBookingsCallback)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "reminder:", bot.MatchTypePrefix, OnReminderCallback)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "reminders:", bot.MatchTypePrefix, OnRemindersCallback)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "free:", bot.MatchTypePrefix, OnFreeRoomCallback)
+
+	// Send notifications (e.g. waitlist offers) to linked accounts
+	notify.Register(telegramNotifier{b: b})
+	startWizardExpiryJob(ctx, b)
 	go b.StartWebhook(ctx)
 
 	return b.WebhookHandler(), nil
@@ -58,4 +73,6 @@ func RegisterTelegramRoutes(router *gin.RouterGroup) {
 		log.Error().Err(err).Msg("Telegram webhook is not running")
 	}
 	router.GET("/get-code", api.AuthGuard(1), HandleCreateNewCode)
+	router.GET("/reminders", api.AuthGuard(1), HandleGetReminderSettings)
+	router.PUT("/reminders", api.AuthGuard(1), HandleSetReminderSettings)
 }
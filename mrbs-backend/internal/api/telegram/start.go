@@ -48,7 +48,7 @@ func HandleStartChat(ctx context.Context, b *bot.Bot, update *models.Update) {
 		// User has already linked their account
 		_, err = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
-			Text:   "Welcome to REP Meeting Room booking bot! You can type your request in the chat, or use the commands /new to create a new booking and /list to show all bookings.",
+			Text:   "Welcome to REP Meeting Room booking bot! You can type your request in the chat, or use the commands /new to create a new booking, /list to show all bookings, /free to find a free room and /mine to manage your own bookings.",
 		})
 		if err != nil {
 			log.Error().Err(err).Msg("Error sending telegram message")
@@ -0,0 +1,152 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// telegramNotifier sends notifications to users who have linked their Telegram account.
+type telegramNotifier struct {
+	b *bot.Bot
+}
+
+func (telegramNotifier) Name() string {
+	return "telegram"
+}
+
+func (n telegramNotifier) Notify(ctx context.Context, event notify.Event) error {
+	chatID, ok, err := getLinkedChatID(ctx, event.UserID)
+	if err != nil || !ok {
+		return err
+	}
+
+	switch event.Kind {
+	case notify.KindWaitlistOffer:
+		return n.sendWaitlistOffer(ctx, chatID, event.Waitlist)
+	case notify.KindBookingReminder:
+		return n.sendBookingReminder(ctx, chatID, &event.Bookings[0])
+	}
+	return nil
+}
+
+func (n telegramNotifier) sendWaitlistOffer(ctx context.Context, chatID int64, entry *m.WaitlistEntry) error {
+	text := fmt.Sprintf(
+		"🔔 <b>A slot you waitlisted is free!</b>\n\n"+
+			"🏢 <b>Room:</b> %s\n"+
+			"📅 <b>Date:</b> %s\n"+
+			"🕒 <b>Time:</b> %s — %s\n\n"+
+			"Claim it before %s, otherwise it will be offered to the next person.",
+		m.GetRoomNameFromID(int(entry.RoomID)),
+		entry.StartTime.Format("02 Jan 2006"),
+		entry.StartTime.Format("15:04"),
+		entry.EndTime.Format("15:04"),
+		entry.OfferExpiresAt.Format("15:04"),
+	)
+
+	_, err := n.b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{Text: "✅ Claim", CallbackData: fmt.Sprintf("waitlist:claim:%d", entry.WaitlistID)},
+					{Text: "❌ Decline", CallbackData: fmt.Sprintf("waitlist:decline:%d", entry.WaitlistID)},
+				},
+			},
+		},
+	})
+	return err
+}
+
+// getLinkedChatID returns the Telegram chat linked to an MRBS user. ok is false if the user has not linked Telegram.
+func getLinkedChatID(ctx context.Context, userID uint) (int64, bool, error) {
+	telegramUser, err := gorm.G[m.TelegramAuth](db.GormDB).Where("user_id = ? AND telegram_chat_id IS NOT NULL", userID).Take(ctx)
+	if err == gorm.ErrRecordNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return *telegramUser.TelegramChatID, true, nil
+}
+
+// OnWaitlistCallback handles the offer buttons, callback data format: "waitlist:<claim|decline>:<waitlist id>"
+func OnWaitlistCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error answering callback query")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgID := update.CallbackQuery.Message.Message.ID
+
+	parts := strings.Split(update.CallbackQuery.Data, ":")
+	if len(parts) != 3 {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+	waitlistID, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Malformed callback data received")
+		return
+	}
+
+	userID, err := getLinkedUserID(ctx, chatID)
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error fetching linked user")
+		sendError(ctx, b, chatID)
+		return
+	}
+
+	var text string
+	switch parts[1] {
+	case "claim":
+		newBooking, bookingErr := booking.ClaimWaitlistOffer(ctx, uint(waitlistID), userID)
+		if bookingErr != nil {
+			text = "⚠️ " + bookingErr.Message
+			break
+		}
+		text = fmt.Sprintf("🎉 <b>Booking success!</b>\n%s has been booked from %s to %s on %s.",
+			m.GetRoomNameFromID(int(newBooking.RoomID)),
+			newBooking.StartTime.Format("15:04"),
+			newBooking.EndTime.Format("15:04"),
+			newBooking.StartTime.Format("02 Jan 2006"))
+	case "decline":
+		if bookingErr := booking.LeaveWaitlist(ctx, uint(waitlistID), userID); bookingErr != nil {
+			text = "⚠️ " + bookingErr.Message
+			break
+		}
+		text = "You have declined the offer and left the waitlist."
+	default:
+		log.Warn().Str("data", update.CallbackQuery.Data).Msg("Unknown waitlist action")
+		return
+	}
+
+	if _, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: msgID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		log.Error().Err(err).Msg("Error editing waitlist message")
+	}
+}
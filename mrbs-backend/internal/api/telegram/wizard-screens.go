@@ -3,8 +3,11 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"html"
+	"strings"
 	"time"
 
+	"rep-mrbs/internal/booking"
 	"rep-mrbs/internal/constants"
 	"rep-mrbs/internal/db"
 	m "rep-mrbs/internal/models"
@@ -48,17 +51,26 @@ func showDateSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int)
 
 // Step 2: Select the rooms
 func showRoomSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int) {
-	if len(m.CachedRooms) == 0 {
+	if len(m.GetRooms()) == 0 {
 		log.Warn().Msg("Room cache is empty, attempting emergency fetch")
 		if err := m.InitRooms(); err != nil {
 			sendError(ctx, b, chatID)
 			return
 		}
 	}
+
+	// Disabled rooms are hidden from the wizard
+	var rooms []m.Room
+	for _, room := range m.GetRooms() {
+		if !room.Disabled {
+			rooms = append(rooms, room)
+		}
+	}
+
 	var rows [][]models.InlineKeyboardButton
 	var currentRow []models.InlineKeyboardButton
 
-	for i, room := range m.CachedRooms {
+	for i, room := range rooms {
 		btn := models.InlineKeyboardButton{
 			Text:         room.DisplayName,
 			CallbackData: fmt.Sprintf("wiz_room:%d", room.RoomID),
@@ -66,7 +78,7 @@ func showRoomSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int)
 		currentRow = append(currentRow, btn)
 
 		// Create a new row every 2 buttons (2-column layout)
-		if (i+1)%2 == 0 || i == len(m.CachedRooms)-1 {
+		if (i+1)%2 == 0 || i == len(rooms)-1 {
 			rows = append(rows, currentRow)
 			currentRow = []models.InlineKeyboardButton{}
 		}
@@ -86,35 +98,35 @@ func showRoomSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int)
 }
 
 // Step 3: Choose available timeslot
-func showTimeSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int) {
-	state, ok := UserBookingStates[chatID]
+func showTimeSelection(ctx context.Context, b *bot.Bot, state *m.BookingState) {
+	chatID, msgID := state.ChatID, state.MessageID
+
+	area, ok := m.GetAreaForRoom(uint(state.RoomID))
 	if !ok {
+		log.Error().Int("room_id", state.RoomID).Msg("Area not found for room")
 		sendError(ctx, b, chatID)
 		return
 	}
 
-	// 1. Define bounds for the selected date
+	// 1. Define bounds for the selected date using the opening hours of the room's area
 	loc, _ := time.LoadLocation("Asia/Singapore")
 	now := time.Now().In(loc)
 
-	var startRange time.Time
 	// Check if today using native date components
 	y1, m1, d1 := state.StartTime.Date()
 	y2, m2, d2 := now.Date()
 	isToday := y1 == y2 && m1 == m2 && d1 == d2
 
-	if isToday {
+	openingTime, endRange := area.OpeningHours(time.Date(y1, m1, d1, 0, 0, 0, 0, loc))
+	startRange := openingTime
+	if isToday && now.After(openingTime) {
 		startRange = now
-	} else {
-		startRange = time.Date(y1, m1, d1, 8, 0, 0, 0, loc)
 	}
 
-	endRange := time.Date(y1, m1, d1+1, 2, 0, 0, 0, loc)
-
-	// 2. Fetch existing bookings for this room and day
+	// 2. Fetch existing bookings for this room and day, apart from the booking being edited
 	var existingBookings []m.Booking
-	err := db.GormDB.Where("room_id = ? AND start_time >= ? AND start_time < ?",
-		state.RoomID, startRange, endRange).Find(&existingBookings).Error
+	err := db.GormDB.Where("room_id = ? AND start_time >= ? AND start_time < ? AND booking_id != ?",
+		state.RoomID, startRange, endRange, editedBookingID(state)).Find(&existingBookings).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to fetch bookings for time selection")
 		sendError(ctx, b, chatID)
@@ -125,22 +137,20 @@ func showTimeSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int)
 	busySlots := make(map[string]bool)
 	for _, bk := range existingBookings {
 		log.Debug().Interface("bk", bk).Msg("")
-		for curr := bk.StartTime; curr.Before(bk.EndTime); curr = curr.Add(m.BookingPeriodSize * time.Minute) {
+		for curr := bk.StartTime; curr.Before(bk.EndTime); curr = curr.Add(time.Duration(area.Resolution) * time.Minute) {
 			busySlots[curr.Format("15:04")] = true
 		}
 	}
 
 	log.Debug().Interface("busySlots", busySlots).Msg("")
 
-	// 4. Generate the keyboard (8 AM to 2 AM) slice
+	// 4. Generate the keyboard from opening to closing time, one button every default duration
 	var rows [][]models.InlineKeyboardButton
 	var currentRow []models.InlineKeyboardButton
 
-	slotTimeIter := time.Date(y1, m1, d1, 8, 0, 0, 0, loc)
-
-	for range 18 {
+	step := slotStep(area)
+	for slotTimeIter := openingTime; slotTimeIter.Before(endRange); slotTimeIter = slotTimeIter.Add(step) {
 		if isToday && slotTimeIter.Before(now) {
-			slotTimeIter = slotTimeIter.Add(time.Hour)
 			continue
 		}
 
@@ -161,7 +171,6 @@ func showTimeSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int)
 		}
 
 		log.Trace().Time("slotTimeIter", slotTimeIter).Msg("")
-		slotTimeIter = slotTimeIter.Add(time.Hour)
 	}
 
 	if len(currentRow) > 0 {
@@ -187,18 +196,38 @@ func showTimeSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int)
 }
 
 // Step 4: Choose duration
-func showDurationSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID int) {
-	state, ok := UserBookingStates[chatID]
+func showDurationSelection(ctx context.Context, b *bot.Bot, state *m.BookingState) {
+	chatID, msgID := state.ChatID, state.MessageID
+
+	area, ok := m.GetAreaForRoom(uint(state.RoomID))
 	if !ok {
+		log.Error().Int("room_id", state.RoomID).Msg("Area not found for room")
 		sendError(ctx, b, chatID)
 		return
 	}
 
+	// Longest possible booking: until closing time, capped by the area's maximum duration
+	_, closingTime := area.BusinessDay(state.StartTime)
+	maxDurationMinutes := int(closingTime.Sub(state.StartTime).Minutes())
+	if maxDuration, ok := area.MaxBookingDuration(); ok {
+		maxDurationMinutes = min(maxDurationMinutes, int(maxDuration.Minutes()))
+	}
+
+	// Cap the duration at the user's remaining daily and weekly quota
+	if userID, err := getLinkedUserID(ctx, chatID); err == nil {
+		quota, err := booking.RemainingQuota(ctx, userID, uint(state.RoomID), state.StartTime, editedBookingID(state))
+		if err != nil {
+			log.Error().Err(err).Msg("Error calculating remaining booking quota")
+		} else if quota.Limited {
+			maxDurationMinutes = min(maxDurationMinutes, int(quota.Remaining.Minutes()))
+		}
+	}
+
 	// Prepare query
-	endRange := state.StartTime.Add(3 * time.Hour)
+	endRange := state.StartTime.Add(time.Duration(maxDurationMinutes) * time.Minute)
 
-	// Fetch existing bookings with start times in the next 3 hours
-	existingBookings, err := gorm.G[m.Booking](db.GormDB).Where("room_id = ? AND start_time > ? AND start_time < ?", state.RoomID, state.StartTime, endRange).Order("start_time ASC").Find(ctx)
+	// Fetch existing bookings with start times before the longest possible booking ends
+	existingBookings, err := gorm.G[m.Booking](db.GormDB).Where("room_id = ? AND start_time > ? AND start_time < ? AND booking_id != ?", state.RoomID, state.StartTime, endRange, editedBookingID(state)).Order("start_time ASC").Find(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Error fetching bookings from db")
 		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
@@ -215,36 +244,19 @@ func showDurationSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID
 		return
 	}
 
-	loc, _ := time.LoadLocation("Asia/Singapore")
-	startYear, startMonth, startDay := state.StartTime.Date()
-	businessDayStart := time.Date(startYear, startMonth, startDay, 8, 0, 0, 0, loc)
-
-	if state.StartTime.Hour() < 8 {
-		// Booking is between 12am and 2am of the next day: adjust the business day
-		businessDayStart = businessDayStart.AddDate(0, 0, -1)
-	}
-	closingTime := time.Date(businessDayStart.Year(), businessDayStart.Month(), businessDayStart.Day()+1, 2, 0, 0, 0, loc)
-
-	untilClosing := int(closingTime.Sub(state.StartTime).Minutes())
-	maxDurationMinutes := min(m.MaxBookingDuration, untilClosing)
-
-	// If there's a booking in the next 3 hours, the gap is our new limit
+	// If there's a booking before then, the gap is our new limit
 	if len(existingBookings) > 0 {
 		nextBookingStart := existingBookings[0].StartTime
 		gapMinutes := int(nextBookingStart.Sub(state.StartTime).Minutes())
 		maxDurationMinutes = min(maxDurationMinutes, gapMinutes)
 	}
 
-	// 4. Generate duration buttons (60, 120, 180 mins)
+	// 4. Generate duration buttons, in multiples of the area's default duration
 	var rows [][]models.InlineKeyboardButton
-	for mins := 60; mins <= min(maxDurationMinutes, m.MaxBookingDuration); mins += 60 {
-		hours := mins / 60
-		label := fmt.Sprintf("%d Hour", hours)
-		if hours > 1 {
-			label += "s"
-		}
+	step := int(slotStep(area).Minutes())
+	for mins := step; mins <= maxDurationMinutes; mins += step {
 		rows = append(rows, []models.InlineKeyboardButton{{
-			Text:         label,
+			Text:         formatDuration(mins),
 			CallbackData: fmt.Sprintf("wiz_duration:%d", mins),
 		}})
 	}
@@ -261,7 +273,7 @@ func showDurationSelection(ctx context.Context, b *bot.Bot, chatID int64, msgID
 		_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:      chatID,
 			MessageID:   msgID,
-			Text:        "⚠️ <b>No slots available</b>\nThere isn't enough time before the next booking or closing time for even a 1-hour session. Please try another starting time or room.",
+			Text:        fmt.Sprintf("⚠️ <b>No slots available</b>\nThere isn't enough time before the next booking or closing time, or left in your daily and weekly quota, for even a %s session. Please try another starting time or room.", strings.ToLower(formatDuration(step))),
 			ParseMode:   models.ParseModeHTML,
 			ReplyMarkup: errorKb, // Add the buttons here
 		})
@@ -302,34 +314,41 @@ func showTitlePrompt(ctx context.Context, b *bot.Bot, chatID int64, msgID int) {
 }
 
 // Step 7: Booking summary
-func showBookingSummary(ctx context.Context, b *bot.Bot, chatID int64) {
-	s := UserBookingStates[chatID]
+func showBookingSummary(ctx context.Context, b *bot.Bot, s *m.BookingState) {
+	heading, question := "✅ <b>Review Your Booking</b>", "Would you like to confirm this booking?"
+	confirmText, cancelData := "✅ Confirm Booking", "wiz_action:discard_booking"
+	if s.EditBookingID != nil {
+		heading, question = "✏️ <b>Review Your Changes</b>", "Would you like to save these changes?"
+		confirmText, cancelData = "✅ Save Changes", "wiz_action:cancel_edit"
+	}
 
 	summary := fmt.Sprintf(
-		"✅ <b>Review Your Booking</b>\n\n"+
+		"%s\n\n"+
 			"🏢 <b>Room:</b> %s\n"+
 			"📅 <b>Date:</b> %s\n"+
 			"🕒 <b>Time:</b> %s\n"+
-			"⏳ <b>Duration:</b> %d hour(s)\n"+
+			"⏳ <b>Duration:</b> %s\n"+
 			"📝 <b>Title:</b> %s\n\n"+
-			"Would you like to confirm this booking?",
+			"%s",
+		heading,
 		m.GetRoomNameFromID(int(s.RoomID)),
 		s.StartTime.Format("02 Jan 2006"),
 		s.StartTime.Format("15:04"),
-		s.NumPeriods/2,
-		s.Title,
+		formatDuration(s.NumPeriods*m.BookingPeriodSize),
+		html.EscapeString(s.Title),
+		question,
 	)
 
 	kb := &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
-			{{Text: "✅ Confirm Booking", CallbackData: "wiz_action:confirm"}},
+			{{Text: confirmText, CallbackData: "wiz_action:confirm"}},
 			{{Text: "⬅️ Edit Title", CallbackData: "wiz_action:back"}},
-			{{Text: "❌ Cancel", CallbackData: "wiz_action:discard_booking"}},
+			{{Text: "❌ Cancel", CallbackData: cancelData}},
 		},
 	}
 
 	_, _ = b.EditMessageText(ctx, &bot.EditMessageTextParams{
-		ChatID:      chatID,
+		ChatID:      s.ChatID,
 		MessageID:   s.MessageID,
 		Text:        summary,
 		ParseMode:   models.ParseModeHTML,
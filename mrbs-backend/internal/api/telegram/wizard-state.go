@@ -0,0 +1,105 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	m "rep-mrbs/internal/models"
+	"rep-mrbs/internal/wizard"
+
+	"github.com/go-telegram/bot"
+	"github.com/rs/zerolog/log"
+)
+
+// wizards holds the new booking wizard of every chat. Handlers lock the chat with wizard.Lock before loading it.
+var wizards wizard.Store = wizard.NewPostgresStore(wizard.TTL)
+
+const wizardExpiryJobInterval = time.Minute
+
+// loadWizard returns the wizard in progress in the chat. Errors are logged and treated as no wizard.
+func loadWizard(ctx context.Context, chatID int64) (*m.BookingState, bool) {
+	s, err := wizards.Get(ctx, chatID)
+	if err != nil {
+		if !errors.Is(err, wizard.ErrNotFound) {
+			log.Error().Err(err).Int64("chatID", chatID).Msg("Error loading booking wizard")
+		}
+		return nil, false
+	}
+	return s, true
+}
+
+func saveWizard(ctx context.Context, s *m.BookingState) {
+	if err := wizards.Save(ctx, s); err != nil {
+		log.Error().Err(err).Int64("chatID", s.ChatID).Msg("Error saving booking wizard")
+	}
+}
+
+func deleteWizard(ctx context.Context, chatID int64) {
+	if err := wizards.Delete(ctx, chatID); err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error deleting booking wizard")
+	}
+}
+
+// startWizardExpiryJob discards abandoned wizards in the background until ctx is cancelled. The stale wizard
+// message is removed so that its buttons cannot be used anymore.
+func startWizardExpiryJob(ctx context.Context, b *bot.Bot) {
+	go func() {
+		ticker := time.NewTicker(wizardExpiryJobInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expired, err := wizards.ListExpired(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg("Error fetching expired booking wizards")
+					continue
+				}
+				discarded := 0
+				for _, s := range expired {
+					if discardExpiredWizard(ctx, b, s.ChatID) {
+						discarded++
+					}
+				}
+				if discarded > 0 {
+					log.Info().Int("count", discarded).Msg("Expired booking wizards discarded")
+				}
+			}
+		}
+	}()
+	log.Info().Dur("ttl", wizard.TTL).Msg("Booking wizard expiry job started")
+}
+
+// discardExpiredWizard removes the wizard of a chat if it has still expired once the chat is locked. An update that
+// loaded the wizard just before it expired saves it again before releasing the lock, and the wizard is then kept.
+func discardExpiredWizard(ctx context.Context, b *bot.Bot, chatID int64) bool {
+	unlock := wizard.Lock(chatID)
+	defer unlock()
+
+	s, err := wizards.DeleteExpired(ctx, chatID)
+	if errors.Is(err, wizard.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("chatID", chatID).Msg("Error deleting expired booking wizard")
+		return false
+	}
+
+	_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+		ChatID:    s.ChatID,
+		MessageID: s.MessageID,
+	})
+	text := fmt.Sprintf("⌛ Your new booking was not completed within %d minutes and has been discarded. Type /new to start again.", int(wizard.TTL.Minutes()))
+	if s.EditBookingID != nil {
+		text = fmt.Sprintf("⌛ Your changes were not saved within %d minutes and have been discarded. Type /mine to edit your booking again.", int(wizard.TTL.Minutes()))
+	}
+	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: s.ChatID,
+		Text:   text,
+	})
+	return true
+}
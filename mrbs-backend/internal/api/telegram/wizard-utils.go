@@ -2,9 +2,15 @@ package telegram
 
 import (
 	"context"
+	"fmt"
+	"time"
+
+	"rep-mrbs/internal/db"
+	m "rep-mrbs/internal/models"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"gorm.io/gorm"
 )
 
 // Utiity function to add back button
@@ -15,19 +21,65 @@ func addBackButtonToRows(rows [][]models.InlineKeyboardButton) [][]models.Inline
 	return append(rows, backButtonRow)
 }
 
-func routeToStep(ctx context.Context, b *bot.Bot, chatID int64, msgID int, step int) {
-	switch step {
+func routeToStep(ctx context.Context, b *bot.Bot, s *m.BookingState) {
+	switch s.Step {
 	case 0:
-		showDateSelection(ctx, b, chatID, msgID)
+		showDateSelection(ctx, b, s.ChatID, s.MessageID)
 	case 1:
-		showRoomSelection(ctx, b, chatID, msgID)
+		showRoomSelection(ctx, b, s.ChatID, s.MessageID)
 	case 2:
-		showTimeSelection(ctx, b, chatID, msgID)
+		showTimeSelection(ctx, b, s)
 	case 3:
-		showDurationSelection(ctx, b, chatID, msgID)
+		showDurationSelection(ctx, b, s)
 	case 4:
-		showTitlePrompt(ctx, b, chatID, msgID)
+		showTitlePrompt(ctx, b, s.ChatID, s.MessageID)
 	case 5:
-		showBookingSummary(ctx, b, chatID)
+		showBookingSummary(ctx, b, s)
+	}
+}
+
+// editedBookingID returns the id of the booking edited by the wizard, to leave it out of availability checks, or -1
+// if the wizard makes a new booking.
+func editedBookingID(s *m.BookingState) int {
+	if s.EditBookingID == nil {
+		return -1
+	}
+	return int(*s.EditBookingID)
+}
+
+// slotStep returns the interval between the start time and duration buttons of the wizard.
+func slotStep(area *m.Area) time.Duration {
+	if area.DefaultDuration <= 0 {
+		return time.Hour
+	}
+	return time.Duration(area.DefaultDuration) * time.Minute
+}
+
+// formatDuration formats minutes for display, e.g. "1 Hour", "2 Hours" or "1 Hour 30 Mins".
+func formatDuration(mins int) string {
+	hours, rest := mins/60, mins%60
+	var label string
+	switch hours {
+	case 0:
+	case 1:
+		label = "1 Hour"
+	default:
+		label = fmt.Sprintf("%d Hours", hours)
+	}
+	if rest > 0 {
+		if label != "" {
+			label += " "
+		}
+		label += fmt.Sprintf("%d Mins", rest)
+	}
+	return label
+}
+
+// getLinkedUserID returns the MRBS user linked to a Telegram chat.
+func getLinkedUserID(ctx context.Context, chatID int64) (uint, error) {
+	telegramUser, err := gorm.G[m.TelegramAuth](db.GormDB).Where("telegram_chat_id = ?", chatID).Take(ctx)
+	if err != nil {
+		return 0, err
 	}
+	return telegramUser.UserID, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"rep-mrbs/internal/audit"
 	"rep-mrbs/internal/db"
 	"rep-mrbs/internal/models"
 
@@ -25,23 +26,34 @@ func HandleDeleteUser(c *gin.Context) {
 		return
 	}
 
-	res, err := gorm.G[models.User](db.GormDB).Where("name = ?", name).Delete(context.Background())
+	// Fetched first for the audit log
+	user, err := gorm.G[models.User](db.GormDB).Where("name = ?", name).Take(context.Background())
+	if err == gorm.ErrRecordNotFound {
+		log.Warn().Msg("User not found, no rows deleted")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Username not found",
+		})
+		return
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("Error deleting user")
+		log.Error().Err(err).Msg("Error fetching user to delete")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error deleting user, please try again later.",
 		})
 		return
 	}
 
-	if res == 0 {
-		log.Warn().Msg("User not found, no rows deleted")
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Username not found",
+	_, err = gorm.G[models.User](db.GormDB).Where("user_id = ?", user.UserID).Delete(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Error deleting user")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting user, please try again later.",
 		})
 		return
 	}
 
+	audit.Record(c, audit.ActionUserDelete, audit.TargetUser, user.UserID, user.PublicUser, nil)
+
 	log.Info().Str("username", name).Msg("User deleted successfully")
 	c.JSON(http.StatusOK, gin.H{
 		"message": fmt.Sprintf("%s deleted successfully", name),
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"rep-mrbs/internal/audit"
 	"rep-mrbs/internal/constants"
 	"rep-mrbs/internal/db"
 	"rep-mrbs/internal/models"
@@ -76,6 +77,13 @@ func HandleEditUser(c *gin.Context) {
 		return
 	}
 
+	edited := user.PublicUser
+	edited.DisplayName = editUserRequest.DisplayName
+	edited.Name = editUserRequest.Name
+	edited.Email = editUserRequest.Email
+	edited.Level = editUserRequest.Level
+	audit.Record(c, audit.ActionUserEdit, audit.TargetUser, user.UserID, user.PublicUser, edited)
+
 	log.Info().Interface("user", editUserRequest).Msg("User details edited successfully")
 
 	if editUserRequest.Level == 2 && user.Level == 1 {
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"rep-mrbs/internal/audit"
 	"rep-mrbs/internal/db"
 	"rep-mrbs/internal/models"
 
@@ -114,6 +115,13 @@ func HandleInsertUsers(c *gin.Context) {
 			return
 		}
 		log.Info().Int("users inserted", int(result.RowsAffected)).Msg("Users inserted into database")
+
+		// Users that already existed are skipped by the insert, so the request is recorded as a whole
+		requested := make([]gin.H, 0, len(parsedUsers))
+		for _, user := range parsedUsers {
+			requested = append(requested, gin.H{"name": user.Name, "display_name": user.DisplayName, "email": user.Email, "level": user.Level})
+		}
+		audit.Record(c, audit.ActionUserCreate, audit.TargetUser, "", nil, gin.H{"inserted": result.RowsAffected, "users": requested})
 	}
 
 	// Return the parsed users to verify
@@ -0,0 +1,80 @@
+package users
+
+import (
+	"net/http"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// HandleGetLockouts lists the usernames and IPs that currently cannot log in because of failed logins.
+func HandleGetLockouts(c *gin.Context) {
+	lockouts, err := gorm.G[models.LoginThrottle](db.GormDB).
+		Where("blocked_until > ?", time.Now()).
+		Order("last_failure DESC").
+		Find(c)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching login lockouts")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// HandleClearLockout forgets the failed logins of a username or IP, given as ?key=user:<username> or ?key=ip:<address>.
+func HandleClearLockout(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "key is required, e.g. user:JDSON or ip:203.0.113.7",
+		})
+		return
+	}
+
+	throttle, err := gorm.G[models.LoginThrottle](db.GormDB).Where("throttle_key = ?", key).Take(c)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No failed logins recorded for " + key,
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Error fetching login lockout")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	rows, err := gorm.G[models.LoginThrottle](db.GormDB).Where("throttle_key = ?", key).Delete(c)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Error clearing login lockout")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No failed logins recorded for " + key,
+		})
+		return
+	}
+
+	audit.Record(c, audit.ActionLockoutClear, audit.TargetLockout, key, throttle, nil)
+	log.Info().Str("event", "login_unlocked").Str("key", key).Uint("admin_id", api.GetUIDFromContext(c)).Msg("Login lockout cleared by admin")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Failed logins cleared for " + key,
+	})
+}
@@ -9,7 +9,19 @@ import (
 
 func RegisterUserRoutes(router *gin.RouterGroup) {
 	router.GET("/", api.AuthGuard(2), HandleGetAllUsers)
+
+	// Usernames and IPs locked out after failed logins
+	router.GET("/lockouts", api.AuthGuard(2), HandleGetLockouts)
+	router.DELETE("/lockouts", api.AuthGuard(2), HandleClearLockout)
 	router.POST("/new", api.AuthGuard(2), HandleInsertUsers)
 	router.DELETE("/:user", api.AuthGuard(2), HandleDeleteUser)
 	router.POST("/:user", api.AuthGuard(2), HandleEditUser)
+
+	// Strikes, :user is the username
+	router.GET("/:user/strikes", api.AuthGuard(2), HandleGetStrikes)
+	router.POST("/:user/strikes", api.AuthGuard(2), HandleAddStrike)
+	router.DELETE("/:user/strikes", api.AuthGuard(2), HandleClearStrikes)
+
+	// Log the user out everywhere
+	router.DELETE("/:user/sessions", api.AuthGuard(2), HandleForceLogout)
 }
@@ -0,0 +1,39 @@
+package users
+
+import (
+	"net/http"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// HandleForceLogout deletes every session of the user. API tokens are not affected.
+func HandleForceLogout(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	rows, err := gorm.G[models.Session](db.GormDB).Where("user_id = ?", user.UserID).Delete(c)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error deleting sessions of user")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	audit.Record(c, audit.ActionUserLogout, audit.TargetUser, user.UserID, nil, gin.H{"revoked": rows})
+	log.Info().Uint("user_id", user.UserID).Uint("admin_id", api.GetUIDFromContext(c)).Int("sessions", rows).Msg("User logged out by admin")
+	c.JSON(http.StatusOK, gin.H{
+		"message": user.Name + " has been logged out of all sessions",
+		"revoked": rows,
+	})
+}
@@ -0,0 +1,156 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/audit"
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+type GetStrikesResponse struct {
+	Strikes        []models.Strike `json:"strikes"` // Includes cleared strikes
+	SuspendedUntil *time.Time      `json:"suspended_until"`
+}
+
+type AddStrikeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// findUser fetches the user in the :user path parameter (username). Responds with an error and returns false if not found.
+func findUser(c *gin.Context) (*models.User, bool) {
+	name := c.Param("user")
+	user, err := gorm.G[models.User](db.GormDB).Where("name = ?", name).Take(context.Background())
+	if err == gorm.ErrRecordNotFound {
+		log.Warn().Str("username", name).Msg("User not found")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Username not found",
+		})
+		return nil, false
+	}
+	if err != nil {
+		log.Error().Err(err).Str("username", name).Msg("Error retrieving user from database")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return nil, false
+	}
+	return &user, true
+}
+
+func HandleGetStrikes(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	strikes, err := gorm.G[models.Strike](db.GormDB).Where("user_id = ?", user.UserID).Order("time_created DESC").Find(c)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error fetching strikes from database")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	res := GetStrikesResponse{Strikes: strikes}
+	if user.Level == 1 {
+		res.SuspendedUntil, err = booking.SuspendedUntil(c, db.GormDB, user.UserID)
+		if err != nil {
+			log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error checking user suspension")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": constants.InternalServerErrorMsg,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func HandleAddStrike(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	var req AddStrikeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A reason is required",
+		})
+		return
+	}
+
+	adminID := api.GetUIDFromContext(c)
+	strike := models.Strike{
+		UserID:    user.UserID,
+		Reason:    strings.TrimSpace(req.Reason),
+		CreatedBy: &adminID,
+	}
+	if err := gorm.G[models.Strike](db.GormDB).Create(c, &strike); err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error adding strike")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	audit.Record(c, audit.ActionUserStrikeAdd, audit.TargetUser, user.UserID, nil, strike)
+	log.Info().Str("username", user.Name).Uint("admin", adminID).Str("reason", strike.Reason).Msg("Strike added manually")
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   fmt.Sprintf("Strike added to %s", user.Name),
+		"strike_id": strike.StrikeID,
+	})
+}
+
+// HandleClearStrikes clears a single strike if the id query parameter is provided, otherwise every active strike of the user.
+func HandleClearStrikes(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	query := gorm.G[models.Strike](db.GormDB).Where("user_id = ? AND cleared_at IS NULL", user.UserID)
+	if idStr, exists := c.GetQuery("id"); exists {
+		strikeID, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid strike ID",
+			})
+			return
+		}
+		query = query.Where("strike_id = ?", strikeID)
+	}
+
+	adminID := api.GetUIDFromContext(c)
+	now := time.Now()
+	rows, err := query.Select("cleared_at", "cleared_by").Updates(c, models.Strike{ClearedAt: &now, ClearedBy: &adminID})
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error clearing strikes")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": constants.InternalServerErrorMsg,
+		})
+		return
+	}
+
+	if rows > 0 {
+		audit.Record(c, audit.ActionUserStrikesClear, audit.TargetUser, user.UserID, nil, gin.H{"strike_id": c.Query("id"), "cleared": rows})
+	}
+	log.Info().Str("username", user.Name).Uint("admin", adminID).Int("cleared", rows).Msg("Strikes cleared")
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%d strike(s) cleared for %s", rows, user.Name),
+	})
+}
@@ -0,0 +1,153 @@
+// Package audit keeps a persistent record of privileged changes made by admins, in addition to the log.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Actions
+const (
+	ActionBookingEdit    = "booking.edit"
+	ActionBookingDelete  = "booking.delete"
+	ActionBookingImport  = "booking.import"
+	ActionBookingRestore = "booking.restore"
+
+	ActionUserCreate       = "user.create"
+	ActionUserEdit         = "user.edit"
+	ActionUserDelete       = "user.delete"
+	ActionUserLogout       = "user.force_logout"
+	ActionUserStrikeAdd    = "user.strike_add"
+	ActionUserStrikesClear = "user.strikes_clear"
+	ActionLockoutClear     = "lockout.clear"
+
+	ActionRoomCreate  = "room.create"
+	ActionRoomEdit    = "room.edit"
+	ActionRoomDisable = "room.disable"
+	ActionRoomEnable  = "room.enable"
+
+	ActionAreaCreate = "area.create"
+	ActionAreaEdit   = "area.edit"
+)
+
+// Target types
+const (
+	TargetBooking = "booking"
+	TargetUser    = "user"
+	TargetLockout = "lockout"
+	TargetRoom    = "room"
+	TargetArea    = "area"
+)
+
+// Filter narrows down the audit log. Zero values match everything.
+type Filter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+}
+
+// Record stores a privileged change made by the user of the request. before and after are stored as JSON, nil if
+// there is no state (e.g. before a creation). Errors are logged, the change has already been made at this point.
+func Record(c *gin.Context, action string, targetType string, targetID any, before any, after any) {
+	actorID := api.GetUIDFromContext(c)
+	entry := models.AuditEntry{
+		ActorID:    &actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		Before:     toJSON(before),
+		After:      toJSON(after),
+		IPAddress:  c.ClientIP(),
+	}
+
+	// The name is copied so that the entry stays readable if the admin is deleted
+	err := db.GormDB.WithContext(c).Exec(`
+		INSERT INTO mrbs.audit_log (actor_id, actor_name, action, target_type, target_id, before, after, ip_address)
+		VALUES (?, COALESCE((SELECT name FROM mrbs.users WHERE user_id = ?), ''), ?, ?, ?, ?, ?, ?)`,
+		entry.ActorID, actorID, entry.Action, entry.TargetType, entry.TargetID, entry.Before, entry.After, entry.IPAddress).
+		Error
+	if err != nil {
+		log.Error().Err(err).Str("action", action).Str("target_type", targetType).Str("target_id", entry.TargetID).Uint("actor_id", actorID).
+			Msg("Error writing audit log")
+	}
+}
+
+// Find returns a page of the audit log, newest first, and the number of entries matching the filter.
+func Find(ctx context.Context, filter Filter, page int, pageSize int) ([]models.AuditEntry, int64, error) {
+	where, args := filter.where()
+	total, err := gorm.G[models.AuditEntry](db.GormDB).Where(where, args...).Count(ctx, "audit_id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, err := gorm.G[models.AuditEntry](db.GormDB).Where(where, args...).
+		Order("time_created DESC, audit_id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(ctx)
+	return entries, total, err
+}
+
+// Each calls fc with the entries matching the filter, oldest first, in batches.
+func Each(ctx context.Context, filter Filter, fc func(entries []models.AuditEntry) error) error {
+	where, args := filter.where()
+	return gorm.G[models.AuditEntry](db.GormDB).Where(where, args...).FindInBatches(ctx, 500, func(entries []models.AuditEntry, _ int) error {
+		return fc(entries)
+	})
+}
+
+// where returns the conditions of the filter, for use with Where.
+func (f Filter) where() (string, []any) {
+	conds := []string{"TRUE"}
+	var args []any
+	add := func(cond string, arg any) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+
+	if f.ActorID != 0 {
+		add("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		add("action = ?", f.Action)
+	}
+	if f.TargetType != "" {
+		add("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		add("target_id = ?", f.TargetID)
+	}
+	if !f.From.IsZero() {
+		add("time_created >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		add("time_created < ?", f.To)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func toJSON(v any) models.JSONB {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Error().Err(err).Msg("Error encoding audit log state")
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,92 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/ical"
+	"rep-mrbs/internal/models"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+// TombstoneRetention - how far back calendar feeds go. Bookings that ended and bookings that were deleted
+// longer ago are left out of the feeds. Set with CALENDAR_HISTORY_DAYS in config/.env (default 30).
+var TombstoneRetention = 30 * 24 * time.Hour
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	TombstoneRetention = time.Duration(lookupPositiveInt("CALENDAR_HISTORY_DAYS", 30)) * 24 * time.Hour
+}
+
+// FeedFilter selects the bookings published in a calendar feed. Exactly one field should be set.
+type FeedFilter struct {
+	UserID uint // Bookings made by the user
+	RoomID uint // Bookings of the room
+}
+
+type feedBooking struct {
+	models.Booking `gorm:"embedded"`
+	BookedBy       string `gorm:"column:booked_by"`
+}
+
+// FeedEvents returns the events of a calendar feed: every booking selected by filter that has not ended more than
+// TombstoneRetention ago, followed by the bookings deleted within that time as cancelled events.
+func FeedEvents(ctx context.Context, filter FeedFilter) ([]ical.Event, error) {
+	since := time.Now().Add(-TombstoneRetention)
+
+	column, value := "user_id", filter.UserID
+	if filter.RoomID != 0 {
+		column, value = "room_id", filter.RoomID
+	}
+
+	var bookings []feedBooking
+	err := db.GormDB.WithContext(ctx).Raw(`
+		SELECT b.*, u.display_name booked_by
+		FROM mrbs.bookings b
+		INNER JOIN mrbs.users u ON b.user_id = u.user_id
+		WHERE b.`+column+` = ? AND b.end_time > ? AND b.deleted_at IS NULL
+		ORDER BY b.start_time ASC`, value, since).
+		Scan(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tombstones, err := gorm.G[models.BookingTombstone](db.GormDB).
+		Where(column+" = ? AND time_deleted > ? AND end_time > ?", value, since, since).
+		Order("start_time ASC").
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]ical.Event, 0, len(bookings)+len(tombstones))
+	for _, b := range bookings {
+		event := ical.FromBooking(&b.Booking, ical.StatusConfirmed)
+		// Everyone can see who booked a room, so room feeds say who the booking belongs to
+		if filter.RoomID != 0 {
+			event.Description = "Booked by " + b.BookedBy
+			if b.Description != "" {
+				event.Description += "\n\n" + b.Description
+			}
+		}
+		events = append(events, event)
+	}
+	for _, t := range tombstones {
+		events = append(events, ical.Event{
+			UID:          t.IcalUID,
+			Sequence:     t.IcalSeq,
+			Start:        t.StartTime,
+			End:          t.EndTime,
+			Summary:      t.Title,
+			Location:     models.GetRoomNameFromID(int(t.RoomID)),
+			Status:       ical.StatusCancelled,
+			LastModified: t.TimeDeleted,
+		})
+	}
+
+	return events, nil
+}
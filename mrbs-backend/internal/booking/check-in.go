@@ -0,0 +1,211 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// CheckInGracePeriod - bookings must be checked in within this period after they start, otherwise the rest of the
+// booking is released. Check-in opens the same period before the booking starts. Set with CHECK_IN_GRACE_PERIOD
+// (minutes) in config/.env
+var CheckInGracePeriod = 15 * time.Minute
+
+// noShowJobInterval - how often the release job looks for bookings that were not checked in.
+const noShowJobInterval = time.Minute
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	CheckInGracePeriod = time.Duration(lookupPositiveInt("CHECK_IN_GRACE_PERIOD", 15)) * time.Minute
+}
+
+// CheckInWindow returns the period during which a booking can be checked in.
+func CheckInWindow(booking *models.Booking) (time.Time, time.Time) {
+	return booking.StartTime.Add(-CheckInGracePeriod), booking.StartTime.Add(CheckInGracePeriod)
+}
+
+// CheckIn marks the user as present for a booking. Non-admins can only check in to their own bookings.
+// Checking in to a booking that has already been checked in is not an error.
+func CheckIn(ctx context.Context, bookingID uint, userID uint, userLevel int) (*models.Booking, *BookingError) {
+	target, err := gorm.G[models.Booking](db.GormDB).Where("booking_id = ?", bookingID).Take(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBookingNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", bookingID).Msg("Error fetching booking from database")
+		return nil, NewBookingError(err.Error())
+	}
+
+	if userLevel < 2 && target.UserID != userID {
+		log.Warn().Uint("booking_id", bookingID).Uint("user_id", userID).Msg("User attempted to check in to another user's booking")
+		return nil, ErrBookingNotFound
+	}
+
+	if target.CheckedInAt != nil {
+		return &target, nil
+	}
+	if target.ReleasedAt != nil {
+		return nil, ErrBookingReleased
+	}
+
+	now := time.Now()
+	opens, closes := CheckInWindow(&target)
+	if now.Before(opens) || now.After(closes) {
+		return nil, NewCheckInWindowError(opens, closes)
+	}
+
+	// The release job may run between the fetch and the update, only check in if the booking has not been released.
+	rows, err := gorm.G[models.Booking](db.GormDB).
+		Where("booking_id = ? AND checked_in_at IS NULL AND released_at IS NULL", bookingID).
+		Update(ctx, "checked_in_at", now)
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", bookingID).Msg("Error checking in booking")
+		return nil, NewBookingError(err.Error())
+	}
+	if rows == 0 {
+		return nil, ErrBookingReleased
+	}
+
+	target.CheckedInAt = &now
+	log.Info().Uint("booking_id", bookingID).Uint("user_id", userID).Msg("Booking checked in")
+	return &target, nil
+}
+
+// StartNoShowJob runs ReleaseNoShows in the background until ctx is cancelled.
+func StartNoShowJob(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(noShowJobInterval)
+		defer ticker.Stop()
+
+		lastRun := time.Now().Add(-noShowJobInterval)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if _, err := ReleaseNoShows(ctx, lastRun); err != nil {
+					log.Error().Err(err).Msg("Error releasing no-show bookings")
+					continue
+				}
+				lastRun = now
+			}
+		}
+	}()
+	log.Info().Dur("grace period", CheckInGracePeriod).Msg("No-show release job started")
+}
+
+// ReleaseNoShows shortens ongoing bookings that were not checked in within the grace period, and records a no-show
+// and a strike against the user. Bookings are cut to the end of the grace period, rounded up to the resolution of the area.
+// Since quotas are calculated from booking durations, the released time is refunded to the user's quota.
+// Bookings that ended since the last run without a check-in, e.g. bookings no longer than the grace period, are
+// recorded as no-shows too. No strike is given when no time was freed. Bookings made by admins are exempt.
+// Returns the number of bookings released.
+func ReleaseNoShows(ctx context.Context, lastRun time.Time) (int, error) {
+	now := time.Now()
+	candidates, err := gorm.G[models.Booking](db.GormDB).
+		Where("checked_in_at IS NULL AND released_at IS NULL").
+		Where("start_time <= ? AND end_time > ?", now.Add(-CheckInGracePeriod), lastRun).
+		Where("user_id IN (SELECT user_id FROM mrbs.users WHERE level < 2)").
+		Find(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, b := range candidates {
+		newEnd := releasedEnd(&b)
+		// Time that has already passed cannot be given back
+		if !b.EndTime.After(now) {
+			newEnd = b.EndTime
+		}
+		ok, err := releaseBooking(ctx, &b, newEnd, now)
+		if err != nil {
+			log.Error().Err(err).Uint("booking_id", b.BookingID).Msg("Error releasing no-show booking")
+			continue
+		}
+		if !ok {
+			log.Debug().Uint("booking_id", b.BookingID).Msg("Booking checked in before it could be released")
+			continue
+		}
+		released++
+
+		// The shortened booking still blocks its own range, only the released part can be offered
+		if newEnd.Before(b.EndTime) {
+			OfferFreedSlot(ctx, b.RoomID, newEnd, b.EndTime)
+		}
+	}
+
+	if released > 0 {
+		log.Info().Int("count", released).Msg("No-show bookings released")
+	}
+	return released, nil
+}
+
+// releasedEnd returns the end of a no-show booking once released: the end of the grace period, rounded up to the
+// resolution of the area, and never later than the original end.
+func releasedEnd(b *models.Booking) time.Time {
+	newEnd := b.StartTime.Add(CheckInGracePeriod)
+	if area, ok := models.GetAreaForRoom(b.RoomID); ok && area.Resolution > 0 {
+		resolution := time.Duration(area.Resolution) * time.Minute
+		newEnd = b.StartTime.Add((CheckInGracePeriod + resolution - 1).Truncate(resolution))
+	}
+	if newEnd.After(b.EndTime) {
+		newEnd = b.EndTime
+	}
+	return newEnd
+}
+
+// releaseBooking cuts the booking to newEnd and records the no-show, with a strike if any time was freed. Returns false
+// if the booking was checked in or released after it was fetched, in which case nothing is changed.
+func releaseBooking(ctx context.Context, b *models.Booking, newEnd time.Time, now time.Time) (bool, error) {
+	released := false
+	err := db.GormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Skip the booking if the user checked in after it was fetched
+		rows, err := gorm.G[models.Booking](tx).
+			Where("booking_id = ? AND checked_in_at IS NULL AND released_at IS NULL", b.BookingID).
+			Select("end_time", "released_at").
+			Updates(ctx, models.Booking{EndTime: newEnd, ReleasedAt: &now})
+		if err != nil || rows == 0 {
+			return err
+		}
+		released = true
+
+		freed := newEnd.Before(b.EndTime)
+		if freed {
+			// The booking is shorter now, calendars need a higher sequence to apply the change
+			_, err = gorm.G[models.Booking](tx).Where("booking_id = ?", b.BookingID).Update(ctx, "ical_seq", gorm.Expr("ical_seq + 1"))
+			if err != nil {
+				return err
+			}
+		}
+
+		noShow := models.NoShow{
+			UserID:          b.UserID,
+			BookingID:       &b.BookingID,
+			RoomID:          b.RoomID,
+			StartTime:       b.StartTime,
+			ReleasedMinutes: int(b.EndTime.Sub(newEnd).Minutes()),
+		}
+		if err = gorm.G[models.NoShow](tx).Create(ctx, &noShow); err != nil {
+			return err
+		}
+		if !freed {
+			return nil
+		}
+
+		return gorm.G[models.Strike](tx).Create(ctx, &models.Strike{
+			UserID:   b.UserID,
+			NoShowID: &noShow.NoShowID,
+			Reason:   models.StrikeReasonNoShow,
+		})
+	})
+	return released && err == nil, err
+}
@@ -0,0 +1,124 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// DeleteBooking deletes a booking, or several occurrences of a recurring booking depending on scope.
+// Non-admins can only delete their own bookings. Returns the bookings deleted.
+// Bookings are soft-deleted with the user and reason, so that an admin can restore them until they are purged.
+func DeleteBooking(ctx context.Context, bookingID uint, userID uint, userLevel int, scope models.SeriesScope, reason string) ([]models.Booking, *BookingError) {
+	target, err := gorm.G[models.Booking](db.GormDB).Where("booking_id = ?", bookingID).Take(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBookingNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", bookingID).Msg("Error fetching booking from database")
+		return nil, NewBookingError(err.Error())
+	}
+
+	if userLevel < 2 && target.UserID != userID {
+		log.Warn().Uint("booking_id", bookingID).Uint("user_id", userID).Msg("User attempted to delete another user's booking")
+		return nil, ErrBookingNotFound
+	}
+
+	if !scope.IsValid() {
+		return nil, ErrInvalidScope
+	}
+	if scope != models.ScopeOccurrence && scope != "" && target.SeriesID == nil {
+		return nil, ErrNotInSeries
+	}
+
+	query := gorm.G[models.Booking](db.GormDB).Where("booking_id = ?", bookingID)
+	switch scope {
+	case models.ScopeFollowing:
+		query = gorm.G[models.Booking](db.GormDB).Where("series_id = ? AND start_time >= ?", *target.SeriesID, target.StartTime)
+	case models.ScopeSeries:
+		// Occurrences that have already ended are kept as a record of the room's use
+		query = gorm.G[models.Booking](db.GormDB).Where("series_id = ? AND end_time > now()", *target.SeriesID)
+	}
+	if userLevel < 2 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	// Fetched before deleting so that the freed slots can be offered to the waitlist
+	deleted, err := query.Find(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching bookings to delete")
+		return nil, NewBookingError(err.Error())
+	}
+
+	rows, err := query.Updates(ctx, models.Booking{
+		DeletedAt:    gorm.DeletedAt{Time: time.Now(), Valid: true},
+		DeletedBy:    &userID,
+		DeleteReason: reason,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error deleting record")
+		return nil, NewBookingError(err.Error())
+	}
+
+	recordTombstones(ctx, deleted)
+	for _, b := range deleted {
+		OfferFreedSlot(ctx, b.RoomID, b.StartTime, b.EndTime)
+	}
+
+	// Users are only told about deletions they did not make themselves
+	if target.UserID != userID && len(deleted) > 0 {
+		notify.Dispatch(notify.Event{Kind: notify.KindBookingDeleted, UserID: target.UserID, ActorID: userID, Bookings: deleted})
+	}
+
+	log.Info().Int("rows deleted", rows).Uint("booking_id", bookingID).Str("scope", string(scope)).Str("reason", reason).Msg("Booking deleted")
+	return deleted, nil
+}
+
+// recordTombstones keeps the deleted bookings around for TombstoneRetention, so that calendar feeds can publish
+// them as cancelled events. Older tombstones are purged at the same time.
+func recordTombstones(ctx context.Context, deleted []models.Booking) {
+	if len(deleted) == 0 {
+		return
+	}
+
+	tombstones := make([]models.BookingTombstone, 0, len(deleted))
+	for _, b := range deleted {
+		tombstones = append(tombstones, models.BookingTombstone{
+			BookingID: b.BookingID,
+			UserID:    b.UserID,
+			RoomID:    b.RoomID,
+			StartTime: b.StartTime,
+			EndTime:   b.EndTime,
+			Title:     b.Title,
+			IcalUID:   b.IcalUID,
+			IcalSeq:   b.IcalSeq + 1,
+		})
+	}
+
+	if err := gorm.G[models.BookingTombstone](db.GormDB).CreateInBatches(ctx, &tombstones, 100); err != nil {
+		log.Warn().Err(err).Msg("Error recording tombstones for deleted bookings")
+	}
+
+	_, err := gorm.G[models.BookingTombstone](db.GormDB).Where("time_deleted < ?", time.Now().Add(-TombstoneRetention)).Delete(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Error purging old booking tombstones")
+	}
+}
+
+// deleteEmptySeries removes a series once its last occurrence has been purged. Deleted occurrences keep their
+// series so that they can be restored into it.
+func deleteEmptySeries(ctx context.Context, seriesID uint) {
+	_, err := gorm.G[models.BookingSeries](db.GormDB).
+		Where("series_id = ? AND NOT EXISTS (SELECT 1 FROM mrbs.bookings b WHERE b.series_id = ?)", seriesID, seriesID).
+		Delete(ctx)
+	if err != nil {
+		log.Warn().Err(err).Uint("series_id", seriesID).Msg("Error cleaning up empty booking series")
+	}
+}
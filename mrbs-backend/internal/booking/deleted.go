@@ -0,0 +1,171 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeletedRetention - how long deleted bookings can be restored before they are purged for good.
+// Set with DELETED_BOOKING_RETENTION_DAYS in config/.env (default 30).
+var DeletedRetention = 30 * 24 * time.Hour
+
+const purgeJobInterval = time.Hour
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	DeletedRetention = time.Duration(lookupPositiveInt("DELETED_BOOKING_RETENTION_DAYS", 30)) * 24 * time.Hour
+}
+
+// DeletedFilter narrows down the deleted bookings. Zero values match everything.
+type DeletedFilter struct {
+	UserID uint // Owner of the booking
+	RoomID uint
+}
+
+// DeletedBooking is a deleted booking with the names of its owner and of the user who deleted it.
+type DeletedBooking struct {
+	models.Booking `gorm:"embedded"`
+	BookedBy       string `gorm:"column:booked_by"`
+	DeletedByName  string `gorm:"column:deleted_by_name"` // Empty if the user who deleted it has been removed
+}
+
+// FindDeleted returns a page of the deleted bookings, most recently deleted first, and the number of deleted
+// bookings matching the filter.
+func FindDeleted(ctx context.Context, filter DeletedFilter, page int, pageSize int) ([]DeletedBooking, int64, error) {
+	where, args := "b.deleted_at IS NOT NULL", []any{}
+	if filter.UserID != 0 {
+		where += " AND b.user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.RoomID != 0 {
+		where += " AND b.room_id = ?"
+		args = append(args, filter.RoomID)
+	}
+
+	var total int64
+	if err := db.GormDB.WithContext(ctx).Raw("SELECT COUNT(*) FROM mrbs.bookings b WHERE "+where, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deleted []DeletedBooking
+	err := db.GormDB.WithContext(ctx).Raw(`
+		SELECT b.*, u.display_name booked_by, COALESCE(d.display_name, '') deleted_by_name
+		FROM mrbs.bookings b
+		INNER JOIN mrbs.users u ON b.user_id = u.user_id
+		LEFT JOIN mrbs.users d ON b.deleted_by = d.user_id
+		WHERE `+where+`
+		ORDER BY b.deleted_at DESC, b.booking_id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...).
+		Scan(&deleted).Error
+	return deleted, total, err
+}
+
+// RestoreBooking brings back a deleted booking after running the clash checks again, as the slot may have been
+// booked since. Only admins restore bookings, so userLevel is expected to be 2 or more.
+func RestoreBooking(ctx context.Context, bookingID uint, userID uint, userLevel int) (*models.Booking, *BookingError) {
+	tx := db.GormDB.WithContext(ctx).Begin()
+
+	target, err := gorm.G[models.Booking](tx.Unscoped(), clause.Locking{Strength: "UPDATE"}).
+		Where("booking_id = ? AND deleted_at IS NOT NULL", bookingID).
+		Take(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		return nil, ErrBookingNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", bookingID).Msg("Error fetching deleted booking")
+		tx.Rollback()
+		return nil, NewBookingError(err.Error())
+	}
+
+	if bookingErr := validateBooking(ctx, tx, &target, int(target.BookingID), userLevel); bookingErr != nil {
+		tx.Rollback()
+		return nil, bookingErr
+	}
+
+	// Calendar feeds published the deletion with IcalSeq + 1, see recordTombstones
+	target.IcalSeq += 2
+	target.DeletedAt = gorm.DeletedAt{}
+	target.DeletedBy = nil
+	target.DeleteReason = ""
+
+	_, err = gorm.G[models.Booking](tx.Unscoped()).
+		Where("booking_id = ?", bookingID).
+		Select("deleted_at", "deleted_by", "delete_reason", "ical_seq").
+		Updates(ctx, target)
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", bookingID).Msg("Error restoring booking")
+		tx.Rollback()
+		return nil, fromDBError(err)
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Error().Err(err).Msg("Error committing restored booking in database")
+		return nil, fromDBError(err)
+	}
+
+	if _, err := gorm.G[models.BookingTombstone](db.GormDB).Where("booking_id = ?", bookingID).Delete(ctx); err != nil {
+		log.Warn().Err(err).Uint("booking_id", bookingID).Msg("Error removing tombstone of restored booking")
+	}
+
+	// Sent as an update so that calendar apps put the cancelled event back
+	notify.Dispatch(notify.Event{Kind: notify.KindBookingEdited, UserID: target.UserID, ActorID: userID, Bookings: []models.Booking{target}})
+
+	log.Info().Uint("booking_id", bookingID).Uint("admin_id", userID).Msg("Booking restored")
+	return &target, nil
+}
+
+// PurgeDeletedBookings permanently removes bookings deleted more than DeletedRetention ago, along with series
+// that have no occurrences left. Returns the number of bookings purged.
+func PurgeDeletedBookings(ctx context.Context) (int, error) {
+	var purged []models.Booking
+	err := db.GormDB.WithContext(ctx).Raw("DELETE FROM mrbs.bookings WHERE deleted_at < ? RETURNING *", time.Now().Add(-DeletedRetention)).
+		Scan(&purged).Error
+	if err != nil {
+		return 0, err
+	}
+
+	seen := map[uint]bool{}
+	for _, b := range purged {
+		if b.SeriesID != nil && !seen[*b.SeriesID] {
+			seen[*b.SeriesID] = true
+			deleteEmptySeries(ctx, *b.SeriesID)
+		}
+	}
+
+	if len(purged) > 0 {
+		log.Info().Int("count", len(purged)).Msg("Deleted bookings purged")
+	}
+	return len(purged), nil
+}
+
+// StartPurgeJob runs PurgeDeletedBookings in the background until ctx is cancelled.
+func StartPurgeJob(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(purgeJobInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := PurgeDeletedBookings(ctx); err != nil {
+					log.Error().Err(err).Msg("Error purging deleted bookings")
+				}
+			}
+		}
+	}()
+	log.Info().Dur("retention", DeletedRetention).Msg("Deleted booking purge job started")
+}
@@ -0,0 +1,87 @@
+package booking
+
+import (
+	"context"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateBooking replaces the editable fields of an existing booking after running the same clash checks
+// as CreateBooking. The caller is responsible for checking that the user is allowed to edit the booking.
+// actorID is the user making the edit, the notification tells the owner when it is someone else.
+func UpdateBooking(ctx context.Context, edited *models.Booking, actorID uint, userLevel int) *BookingError {
+	if bookingErr := updateBooking(ctx, edited, userLevel); bookingErr != nil {
+		return bookingErr
+	}
+
+	notify.Dispatch(notify.Event{Kind: notify.KindBookingEdited, UserID: edited.UserID, ActorID: actorID, Bookings: []models.Booking{*edited}})
+	return nil
+}
+
+// updateBooking is UpdateBooking without the notification, for callers that notify once for several bookings.
+func updateBooking(ctx context.Context, edited *models.Booking, userLevel int) *BookingError {
+	// Begin transaction to make sure other users cannot make booking while we check and update current booking.
+	tx := db.GormDB.WithContext(ctx).Begin()
+
+	original, bookingErr := updateBookingTx(ctx, tx, edited, userLevel)
+	if bookingErr != nil {
+		tx.Rollback()
+		return bookingErr
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Error().Err(err).Msg("Error committing edited booking in database")
+		return fromDBError(err)
+	}
+
+	// Any part of the original slot that is no longer booked can be offered to the waitlist
+	OfferFreedSlot(ctx, original.RoomID, original.StartTime, original.EndTime)
+	return nil
+}
+
+// updateBookingTx validates and saves an edited booking in tx, returning the booking as it was before the edit.
+// The caller commits or rolls back tx.
+func updateBookingTx(ctx context.Context, tx *gorm.DB, edited *models.Booking, userLevel int) (*models.Booking, *BookingError) {
+	if edited.Colour < 1 || edited.Colour > models.MaxBookingColours {
+		return nil, NewBookingError("color out of range")
+	}
+
+	// Locked so that concurrent edits cannot end up with the same sequence
+	original, err := gorm.G[models.Booking](tx, clause.Locking{Strength: "UPDATE"}).Where("booking_id = ?", edited.BookingID).Take(ctx)
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", edited.BookingID).Msg("Error fetching booking to update")
+		return nil, ErrBookingNotFound
+	}
+
+	if bookingErr := validateBooking(ctx, tx, edited, int(edited.BookingID), userLevel); bookingErr != nil {
+		return nil, bookingErr
+	}
+
+	// Calendar apps only apply an update to an event with a higher sequence
+	edited.IcalUID = original.IcalUID
+	edited.IcalSeq = original.IcalSeq + 1
+
+	// A booking moved to another time is reminded again
+	edited.ReminderSentAt = original.ReminderSentAt
+	if !edited.StartTime.Equal(original.StartTime) {
+		edited.ReminderSentAt = nil
+	}
+
+	rows, err := gorm.G[models.Booking](tx).
+		Where("booking_id = ?", edited.BookingID).
+		Select("title", "description", "room_id", "start_time", "end_time", "colour", "ical_seq", "reminder_sent_at").
+		Updates(ctx, *edited)
+	if err != nil {
+		log.Error().Err(err).Uint("booking_id", edited.BookingID).Msg("Error updating booking")
+		return nil, fromDBError(err)
+	}
+
+	log.Trace().Int("rows affected", rows).Msg("Booking updated.")
+	return &original, nil
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"rep-mrbs/internal/models"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
@@ -39,6 +41,26 @@ var (
 		Err:            errors.New("user is unauthorized to edit booking"),
 		Message:        "Unauthorized to edit booking. Booking can only be modified by admin or person who made original booking.",
 	}
+	ErrBookingNotFound = &BookingError{
+		HTTPStatusCode: http.StatusNotFound,
+		Err:            gorm.ErrRecordNotFound,
+		Message:        "Booking not found or not authorized.",
+	}
+	ErrInvalidRecurrence = &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            errors.New("invalid recurrence rule"),
+		Message:        fmt.Sprintf("Invalid recurrence. Provide a daily or weekly frequency, and either an end date or a number of occurrences (up to %d).", models.MaxSeriesOccurrences),
+	}
+	ErrNotInSeries = &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            errors.New("booking is not part of a recurring series"),
+		Message:        "This booking is not part of a recurring series.",
+	}
+	ErrInvalidScope = &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            errors.New("invalid series scope"),
+		Message:        fmt.Sprintf("Invalid scope. Use %q, %q or %q.", models.ScopeOccurrence, models.ScopeFollowing, models.ScopeSeries),
+	}
 	ErrRoomClash = &BookingError{
 		HTTPStatusCode: http.StatusConflict,
 		Err:            errors.New("booking clashes with an existing booking"),
@@ -49,15 +71,50 @@ var (
 		Err:            errors.New("user has an existing booking"),
 		Message:        "Booking clashes with existing booking made by you. You can only occupy one room at once. To book multiple rooms at the same time, contact the admin.",
 	}
-	ErrDailyLimit = &BookingError{
+	ErrInvalidDuration = &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            errors.New("booking must end after it starts"),
+		Message:        "Invalid duration. The booking must end after it starts.",
+	}
+	ErrInvalidRoom = &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            errors.New("room or area not found"),
+		Message:        "Invalid room selected.",
+	}
+	ErrRoomDisabled = &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            errors.New("room is disabled"),
+		Message:        "This room is currently unavailable for booking.",
+	}
+	ErrBookingReleased = &BookingError{
+		HTTPStatusCode: http.StatusConflict,
+		Err:            errors.New("booking has been released"),
+		Message:        "This booking was not checked in on time and has been released.",
+	}
+	ErrWaitlistNotFound = &BookingError{
+		HTTPStatusCode: http.StatusNotFound,
+		Err:            gorm.ErrRecordNotFound,
+		Message:        "Waitlist entry not found.",
+	}
+	ErrWaitlistInPast = &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            errors.New("waitlisted slot has already started"),
+		Message:        "You can only join the waitlist for slots that have not started.",
+	}
+	ErrAlreadyWaitlisted = &BookingError{
+		HTTPStatusCode: http.StatusConflict,
+		Err:            errors.New("user is already on the waitlist for this slot"),
+		Message:        "You are already on the waitlist for this slot.",
+	}
+	ErrOfferUnavailable = &BookingError{
 		HTTPStatusCode: http.StatusConflict,
-		Err:            fmt.Errorf("daily booking limit of %d hours exceeded", models.DailyBookingLimit*models.BookingPeriodSize/60),
-		Message:        fmt.Sprintf("You have exceeded the maximum daily booking limit of %d hours a day. Please try again tomorrow.", models.DailyBookingLimit*models.BookingPeriodSize/60),
+		Err:            errors.New("waitlist offer is not open"),
+		Message:        "This offer has expired or is no longer available.",
 	}
-	ErrProximityClash = &BookingError{
+	ErrImportClash = &BookingError{
 		HTTPStatusCode: http.StatusConflict,
-		Err:            fmt.Errorf("user has existing booking within %d hours of new booking", models.BufferDuration/60),
-		Message:        fmt.Sprintf("You have an existing booking within %d hours of the new booking. The %d-hour limit is in place to ensure fair access for everyone and should not be misused.", models.BufferDuration/60, models.DailyBookingLimit*models.BookingPeriodSize/60),
+		Err:            errors.New("clashes with another event in the import"),
+		Message:        "Booking clashes with another event in the same file.",
 	}
 	ErrInternal = &BookingError{
 		HTTPStatusCode: http.StatusInternalServerError,
@@ -65,3 +122,107 @@ var (
 		Message:        "An unknown error has occured.",
 	}
 )
+
+// NewSuspendedError - the user has too many strikes and cannot make bookings until the suspension ends.
+func NewSuspendedError(until time.Time) *BookingError {
+	return &BookingError{
+		HTTPStatusCode: http.StatusForbidden,
+		Err:            fmt.Errorf("user is suspended until %s", until.Format(time.RFC3339)),
+		Message:        fmt.Sprintf("Your booking rights are suspended until %s due to repeated no-shows. Contact the admin if you think this is a mistake.", until.Format("02 Jan 2006 15:04")),
+	}
+}
+
+// NewCheckInWindowError - check-in is only possible around the start of the booking.
+func NewCheckInWindowError(opens time.Time, closes time.Time) *BookingError {
+	return &BookingError{
+		HTTPStatusCode: http.StatusConflict,
+		Err:            fmt.Errorf("check-in is only open from %s to %s", opens.Format(time.RFC3339), closes.Format(time.RFC3339)),
+		Message:        fmt.Sprintf("Check-in is only open from %s to %s.", opens.Format("15:04"), closes.Format("15:04")),
+	}
+}
+
+// NewDailyLimitError - the daily limit depends on the area of the room booked.
+func NewDailyLimitError(limit time.Duration) *BookingError {
+	return &BookingError{
+		HTTPStatusCode: http.StatusConflict,
+		Err:            fmt.Errorf("daily booking limit of %s exceeded", formatHours(limit)),
+		Message:        fmt.Sprintf("You have exceeded the maximum daily booking limit of %s a day. Please try again tomorrow.", formatHours(limit)),
+	}
+}
+
+// NewWeeklyLimitError - remaining is the time the user can still book this week.
+func NewWeeklyLimitError(limit time.Duration, remaining time.Duration) *BookingError {
+	return &BookingError{
+		HTTPStatusCode: http.StatusConflict,
+		Err:            fmt.Errorf("weekly booking limit of %s exceeded", formatHours(limit)),
+		Message:        fmt.Sprintf("This booking exceeds the weekly booking limit of %s. You have %s remaining this week.", formatHours(limit), formatHours(remaining)),
+	}
+}
+
+// NewProximityClashError - dailyLimit is only used in the message, pass 0 if the area has no daily limit.
+func NewProximityClashError(buffer time.Duration, dailyLimit time.Duration) *BookingError {
+	message := fmt.Sprintf("You have an existing booking within %s of the new booking.", formatHours(buffer))
+	if dailyLimit > 0 {
+		message += fmt.Sprintf(" The %s limit is in place to ensure fair access for everyone and should not be misused.", formatHours(dailyLimit))
+	}
+	return &BookingError{
+		HTTPStatusCode: http.StatusConflict,
+		Err:            fmt.Errorf("user has existing booking within %s of new booking", formatHours(buffer)),
+		Message:        message,
+	}
+}
+
+func NewOutsideOpeningHoursError(area *models.Area) *BookingError {
+	return &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            fmt.Errorf("booking is outside opening hours of %s", area.DisplayName),
+		Message:        fmt.Sprintf("Bookings can only be made between %s and %s.", area.MorningStarts, area.EveningEnds),
+	}
+}
+
+func NewMaxDurationError(limit time.Duration) *BookingError {
+	return &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            fmt.Errorf("booking exceeds maximum duration of %s", formatHours(limit)),
+		Message:        fmt.Sprintf("A single booking cannot be longer than %s.", formatHours(limit)),
+	}
+}
+
+func NewResolutionError(resolution int) *BookingError {
+	return &BookingError{
+		HTTPStatusCode: http.StatusBadRequest,
+		Err:            fmt.Errorf("booking is not aligned to %d minute slots", resolution),
+		Message:        fmt.Sprintf("Bookings must start and end on %d-minute slots.", resolution),
+	}
+}
+
+// formatHours formats a duration for error messages, e.g. "3 hours", "1 hour" or "90 minutes".
+func formatHours(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "0 hours"
+	case d == time.Hour:
+		return "1 hour"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+}
+
+// overlapConstraint - exclusion constraint on mrbs.bookings that prevents a room from being double booked.
+// See migrations/00013_add_booking_overlap_constraint.sql, deleted bookings are left out since 00028.
+const overlapConstraint = "no_overlapping_bookings"
+
+// exclusionViolation - SQLSTATE raised by Postgres when an exclusion constraint is violated
+const exclusionViolation = "23P01"
+
+// fromDBError converts an error raised while writing a booking into a BookingError. Bookings that
+// raced past the clash checks and were rejected by the overlap constraint are reported as ErrRoomClash.
+func fromDBError(err error) *BookingError {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == exclusionViolation && pgErr.ConstraintName == overlapConstraint {
+		return ErrRoomClash
+	}
+	return NewBookingError(err.Error())
+}
@@ -0,0 +1,227 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/ical"
+	"rep-mrbs/internal/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ImportOptions decides how the events of an iCalendar file are turned into bookings.
+type ImportOptions struct {
+	UserID      uint            // Admin the bookings are made for
+	UserLevel   int             // Level of the admin, used for the clash checks of a dry run
+	RoomMapping map[string]uint // LOCATION of an event -> room to book
+	DefaultRoom uint            // Room for events whose location is not in RoomMapping, 0: such events are skipped
+	Colour      int
+	DryRun      bool // Only check for clashes, nothing is created
+}
+
+// EventImportResult reports what happened to one VEVENT.
+type EventImportResult struct {
+	UID         string            `json:"uid"`
+	Summary     string            `json:"summary"`
+	Location    string            `json:"location"`
+	RoomID      uint              `json:"room_id"`
+	SeriesID    uint              `json:"series_id,omitempty"`
+	Occurrences int               `json:"occurrences"` // Occurrences that have not ended yet
+	Past        int               `json:"past"`        // Occurrences that already ended, these are not imported
+	Created     int               `json:"created"`     // Dry run: occurrences that would be created
+	Clashes     []OccurrenceClash `json:"clashes"`
+	Error       string            `json:"error,omitempty"` // Set if the whole event was skipped
+}
+
+type ImportResult struct {
+	DryRun  bool                `json:"dry_run"`
+	Created int                 `json:"created"`
+	Clashes int                 `json:"clashes"`
+	Skipped int                 `json:"skipped"` // Events that were skipped entirely
+	Events  []EventImportResult `json:"events"`
+}
+
+// span is an occurrence already planned by a dry run, so that events in the same file can clash with each other.
+type span struct {
+	start, end time.Time
+}
+
+// ImportEvents books the occurrences of events through the same path as CreateBooking. Occurrences that clash
+// are skipped and reported, like CreateSeries. Imported bookings do not send notifications.
+func ImportEvents(ctx context.Context, events []ical.ImportedEvent, opts ImportOptions) *ImportResult {
+	result := &ImportResult{DryRun: opts.DryRun, Events: []EventImportResult{}}
+	planned := map[uint][]span{}
+	now := time.Now()
+
+	for i := range events {
+		event := &events[i]
+		report := EventImportResult{
+			UID:      event.UID,
+			Summary:  event.Summary,
+			Location: event.Location,
+			Clashes:  []OccurrenceClash{},
+		}
+
+		starts, err := prepareEvent(event, opts, &report)
+		if err != nil {
+			report.Error = err.Error()
+			result.Skipped++
+			result.Events = append(result.Events, report)
+			continue
+		}
+
+		duration := event.End.Sub(event.Start)
+		template := models.Booking{
+			UserID:      opts.UserID,
+			RoomID:      report.RoomID,
+			TimeCreated: now,
+			Title:       truncateTitle(event.Summary),
+			Description: event.Description,
+			Colour:      opts.Colour,
+		}
+
+		// Recurring events are linked as a series so that they can be edited and deleted together
+		if event.Rule != nil && !opts.DryRun {
+			report.SeriesID, err = newImportSeries(ctx, &template, event)
+			if err != nil {
+				log.Error().Err(err).Str("uid", event.UID).Msg("Error creating series for imported event")
+				report.Error = "Error creating recurring booking, please try again later."
+				result.Skipped++
+				result.Events = append(result.Events, report)
+				continue
+			}
+		}
+
+		for _, start := range starts {
+			occurrence := template
+			occurrence.StartTime = start
+			occurrence.EndTime = start.Add(duration)
+			if occurrence.EndTime.Before(now) {
+				report.Past++
+				continue
+			}
+			report.Occurrences++
+			if report.SeriesID != 0 {
+				occurrence.SeriesID = &report.SeriesID
+			}
+
+			var bookingErr *BookingError
+			if opts.DryRun {
+				bookingErr = checkImportedBooking(ctx, &occurrence, opts.UserLevel, planned)
+			} else {
+				bookingErr = createBooking(ctx, &occurrence)
+			}
+			if bookingErr != nil {
+				report.Clashes = append(report.Clashes, OccurrenceClash{
+					StartTime: occurrence.StartTime,
+					EndTime:   occurrence.EndTime,
+					Error:     bookingErr.Message,
+				})
+				continue
+			}
+			report.Created++
+		}
+
+		if report.SeriesID != 0 && report.Created == 0 {
+			discardSeries(ctx, report.SeriesID)
+			report.SeriesID = 0
+		}
+
+		result.Created += report.Created
+		result.Clashes += len(report.Clashes)
+		result.Events = append(result.Events, report)
+	}
+
+	log.Info().Uint("user_id", opts.UserID).Bool("dry_run", opts.DryRun).Int("events", len(events)).
+		Int("created", result.Created).Int("clashes", result.Clashes).Int("skipped", result.Skipped).
+		Msg("Calendar imported")
+	return result
+}
+
+// prepareEvent picks the room of the event and expands it into the start time of each occurrence.
+func prepareEvent(event *ical.ImportedEvent, opts ImportOptions, report *EventImportResult) ([]time.Time, error) {
+	if event.Err != nil {
+		return nil, event.Err
+	}
+	if event.Status == ical.StatusCancelled {
+		return nil, errors.New("event is cancelled")
+	}
+	if event.Summary == "" {
+		return nil, errors.New("event has no SUMMARY to use as the title")
+	}
+	if !event.End.After(event.Start) {
+		return nil, errors.New("event ends before it starts")
+	}
+
+	roomID, ok := opts.RoomMapping[event.Location]
+	if !ok {
+		roomID = opts.DefaultRoom
+	}
+	if roomID == 0 {
+		return nil, errors.New("no room is mapped to the location of the event")
+	}
+	report.RoomID = roomID
+
+	starts, err := event.Occurrences()
+	if err != nil {
+		return nil, err
+	}
+	return starts, nil
+}
+
+// checkImportedBooking runs the checks of createBooking without inserting the booking, and checks the booking
+// against the occurrences planned so far.
+func checkImportedBooking(ctx context.Context, booking *models.Booking, userLevel int, planned map[uint][]span) *BookingError {
+	if !models.IsBookableRoom(booking.RoomID) {
+		return ErrRoomDisabled
+	}
+
+	for _, s := range planned[booking.RoomID] {
+		if booking.StartTime.Before(s.end) && booking.EndTime.After(s.start) {
+			return ErrImportClash
+		}
+	}
+
+	if bookingErr := validateBooking(ctx, db.GormDB.WithContext(ctx), booking, -1, userLevel); bookingErr != nil {
+		return bookingErr
+	}
+
+	planned[booking.RoomID] = append(planned[booking.RoomID], span{booking.StartTime, booking.EndTime})
+	return nil
+}
+
+func newImportSeries(ctx context.Context, template *models.Booking, event *ical.ImportedEvent) (uint, error) {
+	series := models.BookingSeries{
+		UserID:      template.UserID,
+		RoomID:      template.RoomID,
+		Frequency:   event.Rule.Frequency,
+		Interval:    event.Rule.Interval,
+		StartTime:   event.Start,
+		EndTime:     event.End,
+		TimeCreated: time.Now(),
+	}
+	if !event.Rule.Until.IsZero() {
+		series.Until = &event.Rule.Until
+	}
+	if event.Rule.Count > 0 {
+		series.Count = &event.Rule.Count
+	}
+
+	if err := gorm.G[models.BookingSeries](db.GormDB).Create(ctx, &series); err != nil {
+		return 0, err
+	}
+	return series.SeriesID, nil
+}
+
+// truncateTitle shortens a title to models.MaxTitleLength characters.
+func truncateTitle(title string) string {
+	runes := []rune(title)
+	if len(runes) <= models.MaxTitleLength {
+		return title
+	}
+	return string(runes[:models.MaxTitleLength])
+}
@@ -0,0 +1,89 @@
+package booking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rep-mrbs/internal/ical"
+	"rep-mrbs/internal/models"
+)
+
+func TestPrepareEventSkips(t *testing.T) {
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	valid := ical.ImportedEvent{UID: "1", Summary: "Team sync", Location: "Da Vinci", Start: start, End: start.Add(time.Hour)}
+	opts := ImportOptions{RoomMapping: map[string]uint{"Da Vinci": 4}}
+
+	tests := []struct {
+		name    string
+		edit    func(e *ical.ImportedEvent)
+		opts    ImportOptions
+		wantErr bool
+		room    uint
+	}{
+		{"mapped location", func(e *ical.ImportedEvent) {}, opts, false, 4},
+		{"default room", func(e *ical.ImportedEvent) { e.Location = "Elsewhere" }, ImportOptions{DefaultRoom: 2}, false, 2},
+		{"unmapped location", func(e *ical.ImportedEvent) { e.Location = "Elsewhere" }, opts, true, 0},
+		{"cancelled", func(e *ical.ImportedEvent) { e.Status = ical.StatusCancelled }, opts, true, 0},
+		{"no summary", func(e *ical.ImportedEvent) { e.Summary = "" }, opts, true, 0},
+		{"ends before it starts", func(e *ical.ImportedEvent) { e.End = e.Start }, opts, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := valid
+			tt.edit(&event)
+			var report EventImportResult
+			starts, err := prepareEvent(&event, tt.opts, &report)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if report.RoomID != tt.room {
+				t.Fatalf("expected room %d, got %d", tt.room, report.RoomID)
+			}
+			if len(starts) != 1 || !starts[0].Equal(start) {
+				t.Fatalf("expected one occurrence at %v, got %v", start, starts)
+			}
+		})
+	}
+}
+
+// TestImportEventsClashes imports two overlapping events into the same room. The second is reported as a clash,
+// against the planned occurrences in a dry run and against the created booking otherwise.
+func TestImportEventsClashes(t *testing.T) {
+	setupTestDB(t)
+
+	var room models.Room
+	for _, r := range models.GetRooms() {
+		if !r.Disabled {
+			room = r
+			break
+		}
+	}
+	if room.RoomID == 0 {
+		t.Skip("no bookable room in the test database")
+	}
+	user := createTestUser(t, "importer")
+
+	loc := time.FixedZone("GMT", 8*3600)
+	day := time.Now().In(loc).AddDate(0, 0, 120)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 10, 0, 0, 0, loc)
+	events := []ical.ImportedEvent{
+		{UID: "first", Summary: "First", Location: "Room", Start: start, End: start.Add(time.Hour)},
+		{UID: "second", Summary: "Second", Location: "Room", Start: start.Add(30 * time.Minute), End: start.Add(90 * time.Minute)},
+	}
+	opts := ImportOptions{UserID: user.UserID, UserLevel: 2, RoomMapping: map[string]uint{"Room": room.RoomID}, Colour: 1}
+
+	for _, dryRun := range []bool{true, false} {
+		opts.DryRun = dryRun
+		result := ImportEvents(context.Background(), events, opts)
+		if result.Created != 1 || result.Clashes != 1 || result.Skipped != 0 {
+			t.Fatalf("dry run %v: expected 1 created and 1 clash, got %+v", dryRun, result)
+		}
+		if len(result.Events[1].Clashes) != 1 {
+			t.Fatalf("dry run %v: expected the second event to clash, got %+v", dryRun, result.Events)
+		}
+	}
+}
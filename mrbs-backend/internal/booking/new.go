@@ -3,11 +3,12 @@ package booking
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/ical"
 	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
 
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
@@ -17,19 +18,58 @@ import (
 type Clash struct {
 	RoomClashes      int // Someone else has booked the room for the same time
 	UserClashes      int // User already has another booking for the same time.
-	ExistingPeriods  int // Number of periods user has already booked today.
+	ExistingMinutes  int // Number of minutes user has already booked today, in the area of the booked room.
+	WeeklyMinutes    int // Number of minutes user has already booked this week, in the area of the booked room.
 	ProximityClashes int // Number of bookings made within buffer window
 }
 
+// CreateBooking validates and inserts a new booking, then notifies the user.
 func CreateBooking(ctx context.Context, booking *models.Booking) *BookingError {
+	if bookingErr := createBooking(ctx, booking); bookingErr != nil {
+		return bookingErr
+	}
+
+	notify.Dispatch(notify.Event{Kind: notify.KindBookingCreated, UserID: booking.UserID, ActorID: booking.UserID, Bookings: []models.Booking{*booking}})
+	return nil
+}
+
+// createBooking is CreateBooking without the notification, for callers that notify once for several bookings.
+func createBooking(ctx context.Context, booking *models.Booking) *BookingError {
+	tx := db.GormDB.WithContext(ctx).Begin()
+	if bookingErr := createBookingTx(ctx, tx, booking); bookingErr != nil {
+		tx.Rollback()
+		return bookingErr
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Error().Err(err).Msg("Error committing new booking in database")
+		return fromDBError(err)
+	}
+	return nil
+}
+
+// createBookingTx validates and inserts a booking in tx. The caller commits or rolls back tx.
+func createBookingTx(ctx context.Context, tx *gorm.DB, booking *models.Booking) *BookingError {
 	// 1. Logic-only validation (e.g., color range)
 	if booking.Colour < 1 || booking.Colour > models.MaxBookingColours {
 		return NewBookingError("color out of range")
 	}
 
-	numPeriods := int(booking.EndTime.Sub(booking.StartTime).Minutes()) / models.BookingPeriodSize
+	if !models.IsBookableRoom(booking.RoomID) {
+		log.Warn().Uint("room_id", booking.RoomID).Msg("Booking requested for missing or disabled room")
+		return ErrRoomDisabled
+	}
 
-	tx := db.GormDB.WithContext(ctx).Begin()
+	// A UID provided by the caller is kept, otherwise a new one is generated
+	if booking.IcalUID == "" {
+		uid, err := ical.NewUID()
+		if err != nil {
+			log.Error().Err(err).Msg("Error generating iCalendar UID")
+			return NewBookingError(err.Error())
+		}
+		booking.IcalUID = uid
+	}
+	booking.IcalSeq = 0
 
 	// Fetch user level from db.
 	// Note: for requests routed from Gin, there is already one check for the user level, so this
@@ -45,83 +85,47 @@ func CreateBooking(ctx context.Context, booking *models.Booking) *BookingError {
 		return ErrUnknownUser
 	}
 
-	// 3. Validation Logic (extracted from your HandleNewBooking)
-	if user.Level < 2 {
-		clashes, err := CheckClashes(booking, tx, -1)
-		if err != nil {
-			tx.Rollback()
-			return NewBookingError(err.Error())
-		}
-
-		if clashes.RoomClashes > 0 {
-			tx.Rollback()
-			return ErrRoomClash
-		}
-		if clashes.UserClashes > 0 {
-			tx.Rollback()
-			return ErrUserClash
-		}
-		if (clashes.ExistingPeriods + numPeriods) > models.DailyBookingLimit {
-			tx.Rollback()
-			return ErrDailyLimit
-		}
-		if clashes.ProximityClashes > 0 {
-			tx.Rollback()
-			return ErrProximityClash
-		}
-	} else {
-		// Admin clash logic
-		numClashes, err := gorm.G[int](tx).
-			Table("mrbs.bookings").
-			Where("room_id = ? AND start_time < ? AND end_time > ?", booking.RoomID, booking.EndTime, booking.StartTime).
-			Count(context.Background(), "booking_id")
+	// 2. Suspended users cannot make new bookings
+	if user.Level == 1 {
+		until, err := SuspendedUntil(ctx, tx, user.UserID)
 		if err != nil {
-			log.Error().Err(err).Msg("Error encountered when checking for clashes")
-			tx.Rollback()
+			log.Error().Err(err).Uint("user_id", user.UserID).Msg("Error checking user suspension")
 			return NewBookingError(err.Error())
 		}
-		if numClashes > 0 {
-			log.Warn().Msg("Booking by admin clashes with existing booking.")
-			tx.Rollback()
-			return ErrUserClash
+		if until != nil {
+			log.Info().Uint("user_id", user.UserID).Time("suspended_until", *until).Msg("Booking rejected, user is suspended")
+			return NewSuspendedError(*until)
 		}
 	}
 
+	// 3. Clash and quota checks
+	if bookingErr := validateBooking(ctx, tx, booking, -1, user.Level); bookingErr != nil {
+		return bookingErr
+	}
+
 	// 4. Final Insertion
 	result := gorm.WithResult()
 	if err = gorm.G[models.Booking](tx).Create(ctx, booking); err != nil {
 		log.Error().Err(err).Msg("error creating new booking")
-		tx.Rollback()
-		return NewBookingError(err.Error())
+		return fromDBError(err)
 	}
 
 	log.Debug().Int64("rows affected", result.RowsAffected).Msg("booking inserted into database")
-
-	err = tx.Commit().Error
-	if err != nil {
-		log.Error().Err(err).Msg("Error committing new booking in database")
-		return NewBookingError(err.Error())
-	}
-
 	return nil
 }
 
-// CheckClashes clash checking function, to be reused by edit-booking.go
-func CheckClashes(booking *models.Booking, tx *gorm.DB, bookingID int) (*Clash, error) {
-	// Define the start/end of the day for the quota check
-	dayStart := time.Date(booking.StartTime.Year(), booking.StartTime.Month(), booking.StartTime.Day(), 8, 0, 0, 0, booking.StartTime.Location())
-	// Move back 1 day if the time is after 12am
-	if booking.StartTime.Hour() >= 0 && booking.StartTime.Hour() <= 2 {
-		dayStart = dayStart.AddDate(0, 0, -1)
-	}
-
-	dayEnd := dayStart.Add(18 * time.Hour) // Room closes at 2am.
+// CheckClashes clash checking function, shared by the create and edit paths. bookingID is excluded from
+// the checks so that an edited booking does not clash with itself (use -1 for new bookings).
+func CheckClashes(booking *models.Booking, area *models.Area, tx *gorm.DB, bookingID int) (*Clash, error) {
+	// Define the start/end of the business day for the quota check
+	dayStart, dayEnd := area.BusinessDay(booking.StartTime)
+	weekStart, weekEnd := BookingWeek(area, booking.StartTime)
 
 	// Calculate buffer times
-	bufferStart := booking.StartTime.Add(-models.BufferDuration * time.Minute)
-	bufferEnd := booking.EndTime.Add(models.BufferDuration * time.Minute)
-	secondsPerPeriod := models.BookingPeriodSize * 60
-	durationSQL := fmt.Sprintf("(EXTRACT(EPOCH FROM (end_time - start_time)) / %d)::integer", secondsPerPeriod)
+	buffer := time.Duration(area.BufferDuration) * time.Minute
+	bufferStart := booking.StartTime.Add(-buffer)
+	bufferEnd := booking.EndTime.Add(buffer)
+	durationSQL := "(EXTRACT(EPOCH FROM (end_time - start_time)) / 60)::integer"
 	log.Debug().Time("buffer start", bufferStart).Time("buffer end", bufferEnd).Msg("buffer args")
 
 	clashes, err := gorm.G[Clash](tx).Table("mrbs.bookings").
@@ -138,14 +142,21 @@ func CheckClashes(booking *models.Booking, tx *gorm.DB, bookingID int) (*Clash,
 					ELSE 0 
 				END), 0) as user_clashes,
 
-				-- 3. Calculate Existing Duration for Today (Same Day)
+				-- 3. Calculate Existing Duration for Today (Same Day), in rooms of the same area
 				COALESCE(SUM(CASE 
-					WHEN user_id = ? AND start_time >= ? AND end_time < ? AND booking_id != ?
+					WHEN user_id = ? AND start_time >= ? AND end_time <= ? AND booking_id != ? AND room_id IN (SELECT room_id FROM mrbs.rooms WHERE area_id = ?)
 					THEN `+durationSQL+`
 					ELSE 0 
-				END), 0) as existing_periods,
+				END), 0) as existing_minutes,
 				
-				-- 4. Check if there are any bookings within window 
+				-- 4. Calculate Existing Duration for this Week, in rooms of the same area
+				COALESCE(SUM(CASE
+					WHEN user_id = ? AND start_time >= ? AND start_time < ? AND booking_id != ? AND room_id IN (SELECT room_id FROM mrbs.rooms WHERE area_id = ?)
+					THEN `+durationSQL+`
+					ELSE 0
+				END), 0) as weekly_minutes,
+
+				-- 5. Check if there are any bookings within window 
 				COALESCE(SUM(CASE
 					WHEN user_id = ? AND end_time > ? AND start_time < ? AND booking_id != ? THEN 1
 					ELSE 0
@@ -156,16 +167,23 @@ func CheckClashes(booking *models.Booking, tx *gorm.DB, bookingID int) (*Clash,
 			// Args for User Clash
 			booking.UserID, booking.EndTime, booking.StartTime, bookingID,
 			// Args for Quota
-			booking.UserID, dayStart, dayEnd, bookingID,
+			booking.UserID, dayStart, dayEnd, bookingID, area.AreaID,
+			// Args for weekly Quota
+			booking.UserID, weekStart, weekEnd, bookingID, area.AreaID,
 			// Args for window
 			booking.UserID, bufferStart, bufferEnd, bookingID,
 		).
 		Where(`
+				deleted_at IS NULL AND (
 				(start_time < ? AND end_time > ? AND (room_id = ? OR user_id = ?))
 				OR 
-				(user_id = ? AND start_time >= ? AND end_time < ?)
+				(user_id = ? AND start_time >= ? AND start_time < ?)
+				OR
+				(user_id = ? AND end_time > ? AND start_time < ?)
+				)
 			`, booking.EndTime, booking.StartTime, booking.RoomID, booking.UserID, // Overlap args
-			booking.UserID, dayStart, dayEnd, // Quota args
+			booking.UserID, weekStart, weekEnd, // Quota args, the week always contains the day
+			booking.UserID, bufferStart, bufferEnd, // Buffer args
 		).Take(context.Background())
 	if err != nil {
 		return nil, err
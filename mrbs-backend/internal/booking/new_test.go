@@ -0,0 +1,165 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// testDSN - connection string of a disposable Postgres database, migrations are applied to it.
+// Tests that need the database are skipped if it is not set.
+const testDSN = "MRBS_TEST_DSN"
+
+// setupTestDB connects db.GormDB to the test database and loads the room and area caches.
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	dsn, ok := os.LookupEnv(testDSN)
+	if !ok {
+		t.Skipf("%s not set, skipping database test", testDSN)
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	sqlDB := stdlib.OpenDBFromPool(pool)
+	if err = goose.Up(sqlDB, "../../migrations"); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DSN: "TimeZone=Asia/Singapore"}), &gorm.Config{NamingStrategy: schema.NamingStrategy{
+		TablePrefix:   "mrbs.",
+		SingularTable: false,
+	}})
+	if err != nil {
+		t.Fatalf("opening gorm: %v", err)
+	}
+	db.GormDB = gormDB
+
+	if err = models.InitAreas(); err != nil {
+		t.Fatalf("loading areas: %v", err)
+	}
+	if err = models.InitRooms(); err != nil {
+		t.Fatalf("loading rooms: %v", err)
+	}
+}
+
+// createTestUser inserts a regular user that is removed, with its bookings, when the test ends.
+func createTestUser(t *testing.T, name string) models.User {
+	t.Helper()
+	ctx := context.Background()
+
+	unique := fmt.Sprintf("%s-%d", name, time.Now().UnixNano())
+	user := models.User{PublicUser: models.PublicUser{Level: 1, Name: unique, DisplayName: name, Email: unique + "@example.com"}}
+	if err := gorm.G[models.User](db.GormDB).Create(ctx, &user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = gorm.G[models.Booking](db.GormDB.Unscoped()).Where("user_id = ?", user.UserID).Delete(ctx)
+		_, _ = gorm.G[models.User](db.GormDB).Where("user_id = ?", user.UserID).Delete(ctx)
+	})
+	return user
+}
+
+func TestCreateBookingConcurrentClash(t *testing.T) {
+	setupTestDB(t)
+
+	var room models.Room
+	for _, r := range models.GetRooms() {
+		if !r.Disabled {
+			room = r
+			break
+		}
+	}
+	if room.RoomID == 0 {
+		t.Skip("no bookable room in the test database")
+	}
+
+	// Two users, so that only the room can clash
+	users := []models.User{createTestUser(t, "alice"), createTestUser(t, "bob")}
+
+	// A slot well in the future, in the opening hours of the default area
+	loc := time.FixedZone("GMT", 8*3600)
+	day := time.Now().In(loc).AddDate(0, 0, 60)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 10, 0, 0, 0, loc)
+
+	var wg sync.WaitGroup
+	ready := make(chan struct{})
+	results := make([]*BookingError, len(users))
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-ready
+			results[i] = CreateBooking(context.Background(), &models.Booking{
+				UserID:    user.UserID,
+				RoomID:    room.RoomID,
+				StartTime: start,
+				EndTime:   start.Add(time.Hour),
+				Title:     "Race " + user.DisplayName,
+				Colour:    1,
+			})
+		}()
+	}
+	close(ready)
+	wg.Wait()
+
+	successes, clashes := 0, 0
+	for _, result := range results {
+		switch result {
+		case nil:
+			successes++
+		case ErrRoomClash:
+			clashes++
+		default:
+			t.Errorf("unexpected error: %v", result.Message)
+		}
+	}
+	if successes != 1 || clashes != 1 {
+		t.Fatalf("expected 1 success and 1 room clash, got %d successes and %d clashes", successes, clashes)
+	}
+}
+
+// TestOverlapConstraintMapsToRoomClash inserts a clashing booking past the application checks, as a booking that
+// races past them would be, and checks that the constraint violation is reported as ErrRoomClash.
+func TestOverlapConstraintMapsToRoomClash(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	rooms := models.GetRooms()
+	if len(rooms) == 0 {
+		t.Skip("no room in the test database")
+	}
+	user := createTestUser(t, "carol")
+
+	start := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Hour)
+	first := models.Booking{UserID: user.UserID, RoomID: rooms[0].RoomID, StartTime: start, EndTime: start.Add(time.Hour), Title: "First", Colour: 1}
+	if err := gorm.G[models.Booking](db.GormDB).Create(ctx, &first); err != nil {
+		t.Fatalf("creating first booking: %v", err)
+	}
+
+	second := models.Booking{UserID: user.UserID, RoomID: rooms[0].RoomID, StartTime: start.Add(30 * time.Minute), EndTime: start.Add(90 * time.Minute), Title: "Second", Colour: 1}
+	err := gorm.G[models.Booking](db.GormDB).Create(ctx, &second)
+	if err == nil {
+		t.Fatal("expected the overlap constraint to reject the second booking")
+	}
+	if got := fromDBError(err); got != ErrRoomClash {
+		t.Fatalf("expected ErrRoomClash, got %v", got.Message)
+	}
+}
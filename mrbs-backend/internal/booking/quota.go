@@ -0,0 +1,91 @@
+package booking
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// weekStart - first day of the week used for weekly quotas, set with BOOKING_WEEK_START in config/.env
+var weekStart = time.Monday
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	day, exists := os.LookupEnv("BOOKING_WEEK_START")
+	if !exists {
+		log.Warn().Msg("BOOKING_WEEK_START not set in /config/.env, weekly quotas start on Monday.")
+		return
+	}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(day, d.String()) {
+			weekStart = d
+			return
+		}
+	}
+	log.Warn().Str("BOOKING_WEEK_START", day).Msg("Invalid BOOKING_WEEK_START, weekly quotas start on Monday.")
+}
+
+// BookingWeek returns the start and end of the booking week that t falls in. The week starts at the
+// opening time of the first business day of the week, so late-night bookings count towards the previous day.
+func BookingWeek(area *models.Area, t time.Time) (time.Time, time.Time) {
+	opening, _ := area.BusinessDay(t)
+	offset := (int(opening.Weekday()) - int(weekStart) + 7) % 7
+	start := opening.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 7)
+}
+
+// Quota - time a user can still book on a given business day, taking both daily and weekly limits into account.
+type Quota struct {
+	Limited   bool          // false if neither a daily nor a weekly limit applies
+	Remaining time.Duration // meaningful only if Limited
+}
+
+// RemainingQuota returns how much more time the user can book on the business day of start in the given room.
+// excludeID is a booking being edited, whose time is not counted, or -1. Admins are not subject to quotas.
+func RemainingQuota(ctx context.Context, userID uint, roomID uint, start time.Time, excludeID int) (*Quota, error) {
+	area, ok := models.GetAreaForRoom(roomID)
+	if !ok {
+		return nil, ErrInvalidRoom
+	}
+
+	user, err := gorm.G[models.User](db.GormDB).Where("user_id = ?", userID).Take(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user.Level >= 2 {
+		return &Quota{}, nil
+	}
+
+	// Zero length booking: only the quota columns are of interest
+	probe := &models.Booking{UserID: userID, RoomID: roomID, StartTime: start, EndTime: start}
+	clashes, err := CheckClashes(probe, area, db.GormDB.WithContext(ctx), excludeID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &Quota{}
+	if dailyLimit, ok := area.DailyLimit(); ok {
+		quota.Limited = true
+		quota.Remaining = dailyLimit - time.Duration(clashes.ExistingMinutes)*time.Minute
+	}
+	if weeklyLimit, ok := area.WeeklyLimit(); ok {
+		remaining := weeklyLimit - time.Duration(clashes.WeeklyMinutes)*time.Minute
+		if !quota.Limited || remaining < quota.Remaining {
+			quota.Remaining = remaining
+		}
+		quota.Limited = true
+	}
+	quota.Remaining = max(quota.Remaining, 0)
+
+	return quota, nil
+}
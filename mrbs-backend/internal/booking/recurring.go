@@ -0,0 +1,225 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// OccurrenceClash describes an occurrence of a recurring booking that could not be created or edited.
+type OccurrenceClash struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Error     string    `json:"error"`
+}
+
+// SeriesResult is returned by operations on recurring bookings. Occurrences that clash are skipped and
+// reported in Clashes instead of failing the whole request.
+type SeriesResult struct {
+	SeriesID uint
+	Bookings []models.Booking // Occurrences that were created or updated
+	Clashes  []OccurrenceClash
+}
+
+// errEmptySeries rolls back a series transaction in which every occurrence clashed.
+var errEmptySeries = errors.New("every occurrence clashed")
+
+// CreateSeries expands template according to rule and creates the series and its occurrences in one transaction.
+// Occurrences that clash with another user's booking are skipped and reported, any other error fails the whole
+// series. The user is notified once for the whole series.
+func CreateSeries(ctx context.Context, template *models.Booking, rule models.RecurrenceRule) (*SeriesResult, *BookingError) {
+	occurrences, err := rule.Occurrences(template.StartTime)
+	if err != nil {
+		log.Warn().Err(err).Interface("rule", rule).Msg("Invalid recurrence rule")
+		return nil, ErrInvalidRecurrence
+	}
+
+	series := models.BookingSeries{
+		UserID:      template.UserID,
+		RoomID:      template.RoomID,
+		Frequency:   rule.Frequency,
+		Interval:    rule.Interval,
+		StartTime:   template.StartTime,
+		EndTime:     template.EndTime,
+		TimeCreated: time.Now(),
+	}
+	if !rule.Until.IsZero() {
+		series.Until = &rule.Until
+	}
+	if rule.Count > 0 {
+		series.Count = &rule.Count
+	}
+
+	duration := template.EndTime.Sub(template.StartTime)
+	result := &SeriesResult{}
+
+	err = db.GormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := gorm.G[models.BookingSeries](tx).Create(ctx, &series); err != nil {
+			log.Error().Err(err).Msg("Error creating booking series")
+			return NewBookingError(err.Error())
+		}
+
+		for _, start := range occurrences {
+			occurrence := *template
+			occurrence.BookingID = 0
+			occurrence.StartTime = start
+			occurrence.EndTime = start.Add(duration)
+			occurrence.SeriesID = &series.SeriesID
+
+			// A clash may abort the statement, the savepoint keeps the occurrences created so far
+			tx.SavePoint("occurrence")
+			if bookingErr := createBookingTx(ctx, tx, &occurrence); bookingErr != nil {
+				if bookingErr != ErrRoomClash {
+					return bookingErr
+				}
+				tx.RollbackTo("occurrence")
+				result.Clashes = append(result.Clashes, OccurrenceClash{
+					StartTime: occurrence.StartTime,
+					EndTime:   occurrence.EndTime,
+					Error:     bookingErr.Message,
+				})
+				continue
+			}
+			result.Bookings = append(result.Bookings, occurrence)
+		}
+
+		if len(result.Bookings) == 0 {
+			return errEmptySeries
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEmptySeries) {
+		return nil, asBookingError(err)
+	}
+
+	if len(result.Bookings) > 0 {
+		result.SeriesID = series.SeriesID
+		notify.Dispatch(notify.Event{Kind: notify.KindBookingCreated, UserID: template.UserID, ActorID: template.UserID, Bookings: result.Bookings})
+	}
+
+	log.Info().Uint("series_id", result.SeriesID).Int("created", len(result.Bookings)).Int("clashes", len(result.Clashes)).Msg("Recurring booking created")
+	return result, nil
+}
+
+// UpdateSeries applies an edit made to one occurrence to the other occurrences selected by scope.
+// The change in start time and the new duration are applied relative to each occurrence, so moving
+// a weekly booking from 2pm to 3pm moves every selected occurrence to 3pm on its own day.
+// Occurrences that have already ended are left as they were. The occurrences and, for ScopeSeries, the series
+// itself are updated in one transaction. actorID is the user making the edit.
+func UpdateSeries(ctx context.Context, original *models.Booking, edited *models.Booking, actorID uint, userLevel int, scope models.SeriesScope) (*SeriesResult, *BookingError) {
+	if original.SeriesID == nil {
+		return nil, ErrNotInSeries
+	}
+	seriesID := *original.SeriesID
+
+	offset := edited.StartTime.Sub(original.StartTime)
+	duration := edited.EndTime.Sub(edited.StartTime)
+	result := &SeriesResult{SeriesID: seriesID}
+	var replaced []*models.Booking // Occurrences as they were before the edit, for the waitlist
+
+	err := db.GormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := gorm.G[models.Booking](tx).Where("series_id = ?", seriesID)
+		switch scope {
+		case models.ScopeFollowing:
+			query = query.Where("start_time >= ?", original.StartTime)
+		case models.ScopeSeries:
+			query = query.Where("end_time > now()")
+		}
+		targets, err := query.Order("start_time ASC").Find(ctx)
+		if err != nil {
+			log.Error().Err(err).Uint("series_id", seriesID).Msg("Error fetching bookings in series")
+			return NewBookingError(err.Error())
+		}
+
+		for _, target := range targets {
+			occurrence := target
+			occurrence.StartTime = target.StartTime.Add(offset)
+			occurrence.EndTime = occurrence.StartTime.Add(duration)
+			occurrence.RoomID = edited.RoomID
+			occurrence.Title = edited.Title
+			occurrence.Description = edited.Description
+			occurrence.Colour = edited.Colour
+
+			tx.SavePoint("occurrence")
+			before, bookingErr := updateBookingTx(ctx, tx, &occurrence, userLevel)
+			if bookingErr != nil {
+				if bookingErr.HTTPStatusCode != http.StatusConflict {
+					return bookingErr
+				}
+				tx.RollbackTo("occurrence")
+				result.Clashes = append(result.Clashes, OccurrenceClash{
+					StartTime: occurrence.StartTime,
+					EndTime:   occurrence.EndTime,
+					Error:     bookingErr.Message,
+				})
+				continue
+			}
+			result.Bookings = append(result.Bookings, occurrence)
+			replaced = append(replaced, before)
+		}
+
+		if scope != models.ScopeSeries || len(result.Bookings) == 0 {
+			return nil
+		}
+
+		// The series keeps describing the first occurrence, moved the same way as the others
+		series, err := gorm.G[models.BookingSeries](tx).Where("series_id = ?", seriesID).Take(ctx)
+		if err != nil {
+			log.Error().Err(err).Uint("series_id", seriesID).Msg("Error fetching booking series")
+			return NewBookingError(err.Error())
+		}
+		series.RoomID = edited.RoomID
+		series.StartTime = series.StartTime.Add(offset)
+		series.EndTime = series.StartTime.Add(duration)
+		_, err = gorm.G[models.BookingSeries](tx).
+			Where("series_id = ?", seriesID).
+			Select("room_id", "start_time", "end_time").
+			Updates(ctx, series)
+		if err != nil {
+			log.Error().Err(err).Uint("series_id", seriesID).Msg("Error updating booking series")
+			return NewBookingError(err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, asBookingError(err)
+	}
+
+	for _, b := range replaced {
+		OfferFreedSlot(ctx, b.RoomID, b.StartTime, b.EndTime)
+	}
+	if len(result.Bookings) > 0 {
+		notify.Dispatch(notify.Event{Kind: notify.KindBookingEdited, UserID: original.UserID, ActorID: actorID, Bookings: result.Bookings})
+	}
+
+	return result, nil
+}
+
+// asBookingError returns the BookingError a transaction was rolled back with, or wraps a database error.
+func asBookingError(err error) *BookingError {
+	var bookingErr *BookingError
+	if errors.As(err, &bookingErr) {
+		return bookingErr
+	}
+	log.Error().Err(err).Msg("Error committing recurring booking in database")
+	return fromDBError(err)
+}
+
+// discardSeries removes a series and any occurrences already created for it. The occurrences were never
+// confirmed to the user, so they are deleted for good instead of being kept for restoring.
+func discardSeries(ctx context.Context, seriesID uint) {
+	if _, err := gorm.G[models.Booking](db.GormDB.Unscoped()).Where("series_id = ?", seriesID).Delete(ctx); err != nil {
+		log.Error().Err(err).Uint("series_id", seriesID).Msg("Error deleting bookings of discarded series")
+	}
+	if _, err := gorm.G[models.BookingSeries](db.GormDB).Where("series_id = ?", seriesID).Delete(ctx); err != nil {
+		log.Error().Err(err).Uint("series_id", seriesID).Msg("Error deleting discarded series")
+	}
+}
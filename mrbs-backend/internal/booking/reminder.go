@@ -0,0 +1,89 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+// ReminderLeadTime - default time before a booking starts to remind its user on Telegram. Users can choose their
+// own lead time or turn reminders off, see models.TelegramAuth. Set with BOOKING_REMINDER_LEAD_TIME (minutes) in
+// config/.env (default 15).
+var ReminderLeadTime = 15 * time.Minute
+
+// reminderJobInterval - how often the reminder job looks for bookings that are about to start.
+const reminderJobInterval = time.Minute
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	ReminderLeadTime = time.Duration(min(lookupPositiveInt("BOOKING_REMINDER_LEAD_TIME", 15), models.MaxReminderMinutes)) * time.Minute
+}
+
+// SendDueReminders sends a reminder for every booking that starts within the lead time of its user, to users who
+// have linked Telegram and not turned reminders off. Bookings are marked as reminded in the same statement that
+// selects them, so that a reminder is never sent twice, even if several instances of the server are running. A
+// reminder that fails to send is not retried. Bookings made within the lead time are not reminded, as their
+// users have only just booked them. Returns the number of reminders sent.
+func SendDueReminders(ctx context.Context) (int, error) {
+	now := time.Now()
+	defaultMinutes := int(ReminderLeadTime.Minutes())
+
+	var due []models.Booking
+	err := db.GormDB.WithContext(ctx).Raw(`
+		UPDATE mrbs.bookings b
+		SET reminder_sent_at = ?
+		FROM mrbs.telegram_auth t
+		WHERE t.user_id = b.user_id
+			AND t.telegram_chat_id IS NOT NULL
+			AND COALESCE(t.reminder_minutes, ?) > 0
+			AND b.reminder_sent_at IS NULL
+			AND b.deleted_at IS NULL
+			AND b.released_at IS NULL
+			AND b.start_time > ?
+			AND b.start_time <= ?::timestamptz + make_interval(mins => COALESCE(t.reminder_minutes, ?))
+			AND b.time_created < b.start_time - make_interval(mins => COALESCE(t.reminder_minutes, ?))
+		RETURNING b.*`,
+		now, defaultMinutes, now, now, defaultMinutes, defaultMinutes).
+		Scan(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, b := range due {
+		notify.Dispatch(notify.Event{Kind: notify.KindBookingReminder, UserID: b.UserID, Bookings: []models.Booking{b}})
+	}
+
+	if len(due) > 0 {
+		log.Info().Int("count", len(due)).Msg("Booking reminders sent")
+	}
+	return len(due), nil
+}
+
+// StartReminderJob runs SendDueReminders in the background until ctx is cancelled. The bookings reminded are
+// recorded in the database, so reminders that fell due while the server was down are sent when it restarts, as
+// long as the booking has not started yet.
+func StartReminderJob(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(reminderJobInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := SendDueReminders(ctx); err != nil {
+					log.Error().Err(err).Msg("Error sending booking reminders")
+				}
+			}
+		}
+	}()
+	log.Info().Dur("lead time", ReminderLeadTime).Msg("Booking reminder job started")
+}
@@ -0,0 +1,81 @@
+package booking
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"rep-mrbs/internal/models"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Strike policy, set in config/.env:
+//   - STRIKE_LIMIT: number of strikes within the window that suspends a user (default 3)
+//   - STRIKE_WINDOW_DAYS: length of the rolling window (default 30)
+//   - SUSPENSION_DAYS: length of the suspension, counted from the strike that reached the limit (default 7)
+//
+// Only level 1 users are suspended.
+var (
+	StrikeLimit      = 3
+	StrikeWindow     = 30 * 24 * time.Hour
+	SuspensionPeriod = 7 * 24 * time.Hour
+)
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	StrikeLimit = lookupPositiveInt("STRIKE_LIMIT", StrikeLimit)
+	StrikeWindow = time.Duration(lookupPositiveInt("STRIKE_WINDOW_DAYS", 30)) * 24 * time.Hour
+	SuspensionPeriod = time.Duration(lookupPositiveInt("SUSPENSION_DAYS", 7)) * 24 * time.Hour
+}
+
+// lookupPositiveInt reads a positive integer from the environment, falling back to the default if it is unset or invalid.
+func lookupPositiveInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		log.Warn().Int("default", fallback).Msgf("%s not set in /config/.env, using default.", key)
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Warn().Str(key, value).Int("default", fallback).Msgf("Invalid %s, using default.", key)
+		return fallback
+	}
+	return n
+}
+
+// SuspendedUntil returns the end of the user's current suspension, or nil if the user is not suspended.
+// A suspension starts whenever StrikeLimit uncleared strikes fall within StrikeWindow, so clearing strikes
+// lifts the suspension. The user level is not checked here.
+func SuspendedUntil(ctx context.Context, tx *gorm.DB, userID uint) (*time.Time, error) {
+	now := time.Now()
+
+	// Only strikes in the last window + suspension period can be part of a suspension that has not ended
+	strikes, err := gorm.G[models.Strike](tx).
+		Where("user_id = ? AND cleared_at IS NULL AND time_created > ?", userID, now.Add(-StrikeWindow-SuspensionPeriod)).
+		Order("time_created ASC").
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var until *time.Time
+	for i := StrikeLimit - 1; i < len(strikes); i++ {
+		first, last := strikes[i-StrikeLimit+1].TimeCreated, strikes[i].TimeCreated
+		if last.Sub(first) > StrikeWindow {
+			continue
+		}
+
+		end := last.Add(SuspensionPeriod)
+		if end.After(now) && (until == nil || end.After(*until)) {
+			until = &end
+		}
+	}
+
+	return until, nil
+}
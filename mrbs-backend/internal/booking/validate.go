@@ -0,0 +1,92 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"rep-mrbs/internal/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// validateBooking runs the policy, clash and quota checks for a new or edited booking inside tx.
+// excludeID is the id of the booking being edited, or -1 for new bookings.
+// The policy (opening hours, quotas, etc.) is taken from the area of the booked room.
+func validateBooking(ctx context.Context, tx *gorm.DB, booking *models.Booking, excludeID int, userLevel int) *BookingError {
+	if !booking.EndTime.After(booking.StartTime) {
+		return ErrInvalidDuration
+	}
+
+	area, ok := models.GetAreaForRoom(booking.RoomID)
+	if !ok {
+		log.Warn().Uint("room_id", booking.RoomID).Msg("Room or area not found")
+		return ErrInvalidRoom
+	}
+
+	if userLevel < 2 {
+		if bookingErr := checkPolicy(booking, area); bookingErr != nil {
+			return bookingErr
+		}
+
+		clashes, err := CheckClashes(booking, area, tx, excludeID)
+		if err != nil {
+			log.Error().Err(err).Msg("Error encountered when checking for clashes")
+			return NewBookingError(err.Error())
+		}
+
+		if clashes.RoomClashes > 0 {
+			return ErrRoomClash
+		}
+		if clashes.UserClashes > 0 {
+			return ErrUserClash
+		}
+		dailyLimit, hasDailyLimit := area.DailyLimit()
+		existing := time.Duration(clashes.ExistingMinutes) * time.Minute
+		if hasDailyLimit && existing+booking.EndTime.Sub(booking.StartTime) > dailyLimit {
+			log.Warn().Uint("user_id", booking.UserID).Msg("Daily booking limit exceeded")
+			return NewDailyLimitError(dailyLimit)
+		}
+		weeklyLimit, hasWeeklyLimit := area.WeeklyLimit()
+		existingThisWeek := time.Duration(clashes.WeeklyMinutes) * time.Minute
+		if hasWeeklyLimit && existingThisWeek+booking.EndTime.Sub(booking.StartTime) > weeklyLimit {
+			log.Warn().Uint("user_id", booking.UserID).Msg("Weekly booking limit exceeded")
+			return NewWeeklyLimitError(weeklyLimit, max(weeklyLimit-existingThisWeek, 0))
+		}
+		// Check if users are abusing the system by making multiple small bookings in close proximity
+		if clashes.ProximityClashes > 0 {
+			log.Warn().Uint("user_id", booking.UserID).Msg("User attempting to book too close to existing booking")
+			return NewProximityClashError(time.Duration(area.BufferDuration)*time.Minute, dailyLimit)
+		}
+		return nil
+	}
+
+	// Admin clash logic: area policy does not apply, but the room can still not be double booked.
+	numClashes, err := gorm.G[models.Booking](tx).
+		Where("room_id = ? AND start_time < ? AND end_time > ? AND booking_id != ?", booking.RoomID, booking.EndTime, booking.StartTime, excludeID).
+		Count(ctx, "booking_id")
+	if err != nil {
+		log.Error().Err(err).Msg("Error encountered when checking for clashes")
+		return NewBookingError(err.Error())
+	}
+	if numClashes > 0 {
+		log.Warn().Msg("Booking by admin clashes with existing booking.")
+		return ErrRoomClash
+	}
+
+	return nil
+}
+
+// checkPolicy checks the booking against the opening hours, resolution and maximum duration of its area.
+func checkPolicy(booking *models.Booking, area *models.Area) *BookingError {
+	if !area.IsOpen(booking.StartTime, booking.EndTime) {
+		return NewOutsideOpeningHoursError(area)
+	}
+	if !area.IsAligned(booking.StartTime) || !area.IsAligned(booking.EndTime) {
+		return NewResolutionError(area.Resolution)
+	}
+	if maxDuration, ok := area.MaxBookingDuration(); ok && booking.EndTime.Sub(booking.StartTime) > maxDuration {
+		return NewMaxDurationError(maxDuration)
+	}
+	return nil
+}
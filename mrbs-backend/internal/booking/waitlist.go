@@ -0,0 +1,254 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// WaitlistOfferPeriod - how long a user has to claim a freed slot before it is offered to the next user.
+// Set with WAITLIST_OFFER_PERIOD (minutes) in config/.env
+var WaitlistOfferPeriod = 15 * time.Minute
+
+// waitlistJobInterval - how often expired offers are passed on to the next user.
+const waitlistJobInterval = time.Minute
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	WaitlistOfferPeriod = time.Duration(lookupPositiveInt("WAITLIST_OFFER_PERIOD", 15)) * time.Minute
+}
+
+// JoinWaitlist adds the user to the waitlist of a room for the time range in entry.
+func JoinWaitlist(ctx context.Context, entry *models.WaitlistEntry) *BookingError {
+	if !entry.EndTime.After(entry.StartTime) {
+		return ErrInvalidDuration
+	}
+	if !entry.StartTime.After(time.Now()) {
+		return ErrWaitlistInPast
+	}
+	if !models.IsBookableRoom(entry.RoomID) {
+		return ErrRoomDisabled
+	}
+	// Only a slot that could be booked can be offered
+	if area, ok := models.GetAreaForRoom(entry.RoomID); ok && (!area.IsAligned(entry.StartTime) || !area.IsAligned(entry.EndTime)) {
+		return NewResolutionError(area.Resolution)
+	}
+
+	duplicates, err := gorm.G[models.WaitlistEntry](db.GormDB).
+		Where("user_id = ? AND room_id = ? AND start_time = ? AND end_time = ?", entry.UserID, entry.RoomID, entry.StartTime, entry.EndTime).
+		Where("status IN ?", []string{models.WaitlistWaiting, models.WaitlistOffered}).
+		Count(ctx, "waitlist_id")
+	if err != nil {
+		log.Error().Err(err).Msg("Error checking for duplicate waitlist entries")
+		return NewBookingError(err.Error())
+	}
+	if duplicates > 0 {
+		return ErrAlreadyWaitlisted
+	}
+
+	entry.Status = models.WaitlistWaiting
+	if err = gorm.G[models.WaitlistEntry](db.GormDB).Create(ctx, entry); err != nil {
+		log.Error().Err(err).Msg("Error creating waitlist entry")
+		return NewBookingError(err.Error())
+	}
+
+	log.Info().Uint("waitlist_id", entry.WaitlistID).Uint("user_id", entry.UserID).Uint("room_id", entry.RoomID).Msg("User joined waitlist")
+
+	// The slot may already be free, e.g. if it was freed while the user was looking at the clash
+	OfferFreedSlot(ctx, entry.RoomID, entry.StartTime, entry.EndTime)
+	return nil
+}
+
+// LeaveWaitlist cancels a waiting entry or an unclaimed offer. If an offer is declined, the slot is offered to the next user.
+func LeaveWaitlist(ctx context.Context, waitlistID uint, userID uint) *BookingError {
+	entry, err := gorm.G[models.WaitlistEntry](db.GormDB).Where("waitlist_id = ? AND user_id = ?", waitlistID, userID).Take(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrWaitlistNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("waitlist_id", waitlistID).Msg("Error fetching waitlist entry")
+		return NewBookingError(err.Error())
+	}
+
+	rows, err := gorm.G[models.WaitlistEntry](db.GormDB).
+		Where("waitlist_id = ? AND status IN ?", waitlistID, []string{models.WaitlistWaiting, models.WaitlistOffered}).
+		Update(ctx, "status", models.WaitlistCancelled)
+	if err != nil {
+		log.Error().Err(err).Uint("waitlist_id", waitlistID).Msg("Error cancelling waitlist entry")
+		return NewBookingError(err.Error())
+	}
+	if rows == 0 {
+		return ErrWaitlistNotFound
+	}
+
+	if entry.Status == models.WaitlistOffered {
+		OfferFreedSlot(ctx, entry.RoomID, entry.StartTime, entry.EndTime)
+	}
+	return nil
+}
+
+// ClaimWaitlistOffer books the slot of an offer. The booking goes through the same checks as any other booking,
+// the offer stays open until it expires if the booking fails.
+func ClaimWaitlistOffer(ctx context.Context, waitlistID uint, userID uint) (*models.Booking, *BookingError) {
+	entry, err := gorm.G[models.WaitlistEntry](db.GormDB).Where("waitlist_id = ? AND user_id = ?", waitlistID, userID).Take(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWaitlistNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("waitlist_id", waitlistID).Msg("Error fetching waitlist entry")
+		return nil, NewBookingError(err.Error())
+	}
+
+	if entry.Status != models.WaitlistOffered || entry.OfferExpiresAt == nil || time.Now().After(*entry.OfferExpiresAt) {
+		return nil, ErrOfferUnavailable
+	}
+
+	newBooking := models.Booking{
+		UserID:      entry.UserID,
+		RoomID:      entry.RoomID,
+		StartTime:   entry.StartTime,
+		EndTime:     entry.EndTime,
+		TimeCreated: time.Now(),
+		Title:       entry.Title,
+		Colour:      1,
+	}
+	if bookingErr := CreateBooking(ctx, &newBooking); bookingErr != nil {
+		return nil, bookingErr
+	}
+
+	if _, err = gorm.G[models.WaitlistEntry](db.GormDB).
+		Where("waitlist_id = ?", waitlistID).
+		Select("status", "booking_id").
+		Updates(ctx, models.WaitlistEntry{Status: models.WaitlistClaimed, BookingID: &newBooking.BookingID}); err != nil {
+		// The booking has been made, only the waitlist is out of date
+		log.Error().Err(err).Uint("waitlist_id", waitlistID).Msg("Error marking waitlist offer as claimed")
+	}
+
+	log.Info().Uint("waitlist_id", waitlistID).Uint("booking_id", newBooking.BookingID).Msg("Waitlist offer claimed")
+	return &newBooking, nil
+}
+
+// OfferFreedSlot is called after time in a room has been freed (booking deleted, shortened, moved or released).
+// The oldest waiting entry overlapping the freed range, whose whole range is now free, receives an offer.
+// Entries that overlap an offer which is still open are skipped, so a slot is only offered to one user at a time.
+// Errors are logged, the caller has already completed its own change.
+func OfferFreedSlot(ctx context.Context, roomID uint, start time.Time, end time.Time) {
+	now := time.Now()
+	entries, err := gorm.G[models.WaitlistEntry](db.GormDB).
+		Where("room_id = ? AND status = ? AND start_time < ? AND end_time > ? AND start_time > ?", roomID, models.WaitlistWaiting, end, start, now).
+		Order("time_created ASC").
+		Find(ctx)
+	if err != nil {
+		log.Error().Err(err).Uint("room_id", roomID).Msg("Error fetching waitlist")
+		return
+	}
+
+	for _, entry := range entries {
+		// The whole range must be free, and not already offered to someone else
+		blocking, err := gorm.G[models.Booking](db.GormDB).
+			Where("room_id = ? AND start_time < ? AND end_time > ?", roomID, entry.EndTime, entry.StartTime).
+			Count(ctx, "booking_id")
+		if err != nil {
+			log.Error().Err(err).Uint("waitlist_id", entry.WaitlistID).Msg("Error checking waitlisted slot")
+			return
+		}
+		offered, err := gorm.G[models.WaitlistEntry](db.GormDB).
+			Where("room_id = ? AND status = ? AND start_time < ? AND end_time > ?", roomID, models.WaitlistOffered, entry.EndTime, entry.StartTime).
+			Count(ctx, "waitlist_id")
+		if err != nil {
+			log.Error().Err(err).Uint("waitlist_id", entry.WaitlistID).Msg("Error checking waitlisted slot")
+			return
+		}
+		if blocking > 0 || offered > 0 {
+			continue
+		}
+
+		// Suspended users are not eligible, keep them waiting in case the suspension ends in time
+		if isSuspended(ctx, entry.UserID) {
+			continue
+		}
+
+		expires := now.Add(WaitlistOfferPeriod)
+		if expires.After(entry.StartTime) {
+			expires = entry.StartTime
+		}
+		rows, err := gorm.G[models.WaitlistEntry](db.GormDB).
+			Where("waitlist_id = ? AND status = ?", entry.WaitlistID, models.WaitlistWaiting).
+			Select("status", "offered_at", "offer_expires_at").
+			Updates(ctx, models.WaitlistEntry{Status: models.WaitlistOffered, OfferedAt: &now, OfferExpiresAt: &expires})
+		if err != nil {
+			log.Error().Err(err).Uint("waitlist_id", entry.WaitlistID).Msg("Error creating waitlist offer")
+			return
+		}
+		if rows == 0 {
+			continue
+		}
+
+		entry.Status = models.WaitlistOffered
+		entry.OfferedAt = &now
+		entry.OfferExpiresAt = &expires
+		log.Info().Uint("waitlist_id", entry.WaitlistID).Uint("user_id", entry.UserID).Time("expires", expires).Msg("Waitlisted slot offered")
+		notify.Dispatch(notify.Event{Kind: notify.KindWaitlistOffer, UserID: entry.UserID, Waitlist: &entry})
+	}
+}
+
+// isSuspended returns true if the user is a level 1 user with an ongoing suspension.
+func isSuspended(ctx context.Context, userID uint) bool {
+	user, err := gorm.G[models.User](db.GormDB).Where("user_id = ?", userID).Take(ctx)
+	if err != nil || user.Level != 1 {
+		return false
+	}
+	until, err := SuspendedUntil(ctx, db.GormDB.WithContext(ctx), userID)
+	return err == nil && until != nil
+}
+
+// StartWaitlistJob expires unclaimed offers in the background until ctx is cancelled, offering each slot to the next user.
+func StartWaitlistJob(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(waitlistJobInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expireWaitlistOffers(ctx)
+			}
+		}
+	}()
+	log.Info().Dur("offer period", WaitlistOfferPeriod).Msg("Waitlist job started")
+}
+
+func expireWaitlistOffers(ctx context.Context) {
+	now := time.Now()
+	expired, err := gorm.G[models.WaitlistEntry](db.GormDB).
+		Where("status = ? AND offer_expires_at <= ?", models.WaitlistOffered, now).
+		Find(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching expired waitlist offers")
+		return
+	}
+
+	for _, entry := range expired {
+		rows, err := gorm.G[models.WaitlistEntry](db.GormDB).
+			Where("waitlist_id = ? AND status = ?", entry.WaitlistID, models.WaitlistOffered).
+			Update(ctx, "status", models.WaitlistExpired)
+		if err != nil {
+			log.Error().Err(err).Uint("waitlist_id", entry.WaitlistID).Msg("Error expiring waitlist offer")
+			continue
+		}
+		if rows > 0 {
+			OfferFreedSlot(ctx, entry.RoomID, entry.StartTime, entry.EndTime)
+		}
+	}
+}
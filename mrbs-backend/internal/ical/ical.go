@@ -0,0 +1,137 @@
+// Package ical builds iCalendar (RFC 5545) documents for bookings, used for email attachments and calendar feeds.
+package ical
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/models"
+)
+
+const prodID = "-//REP MRBS//Meeting Room Booking System//EN"
+
+// Methods (RFC 5546) used in email attachments. Feeds do not set a method.
+const (
+	MethodPublish = "PUBLISH"
+	MethodRequest = "REQUEST"
+	MethodCancel  = "CANCEL"
+)
+
+// Event statuses
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+const utcFormat = "20060102T150405Z"
+
+type Event struct {
+	UID          string
+	Sequence     int
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	Status       string
+	LastModified time.Time
+}
+
+// NewUID returns a globally unique UID for a new booking (RFC 5545 3.8.4.7), a random UUID followed by the domain.
+func NewUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // Variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x@rep-mrbs", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// FromBooking converts a booking into an event with the given status.
+func FromBooking(b *models.Booking, status string) Event {
+	return Event{
+		UID:          b.IcalUID,
+		Sequence:     b.IcalSeq,
+		Start:        b.StartTime,
+		End:          b.EndTime,
+		Summary:      b.Title,
+		Description:  b.Description,
+		Location:     models.GetRoomNameFromID(int(b.RoomID)),
+		Status:       status,
+		LastModified: time.Now(),
+	}
+}
+
+// Calendar returns a VCALENDAR containing events. method may be empty (e.g. for subscription feeds), name sets
+// the calendar name shown by calendar apps and may also be empty.
+func Calendar(method string, name string, events []Event) []byte {
+	var sb strings.Builder
+	writeLine(&sb, "BEGIN:VCALENDAR")
+	writeLine(&sb, "VERSION:2.0")
+	writeLine(&sb, "PRODID:"+prodID)
+	writeLine(&sb, "CALSCALE:GREGORIAN")
+	if method != "" {
+		writeLine(&sb, "METHOD:"+method)
+	}
+	if name != "" {
+		writeLine(&sb, "X-WR-CALNAME:"+escapeText(name))
+	}
+
+	now := time.Now().UTC().Format(utcFormat)
+	for _, e := range events {
+		writeLine(&sb, "BEGIN:VEVENT")
+		writeLine(&sb, "UID:"+escapeText(e.UID))
+		writeLine(&sb, "DTSTAMP:"+now)
+		writeLine(&sb, "SEQUENCE:"+strconv.Itoa(e.Sequence))
+		writeLine(&sb, "DTSTART:"+e.Start.UTC().Format(utcFormat))
+		writeLine(&sb, "DTEND:"+e.End.UTC().Format(utcFormat))
+		writeLine(&sb, "SUMMARY:"+escapeText(e.Summary))
+		if e.Description != "" {
+			writeLine(&sb, "DESCRIPTION:"+escapeText(e.Description))
+		}
+		if e.Location != "" {
+			writeLine(&sb, "LOCATION:"+escapeText(e.Location))
+		}
+		if e.Status != "" {
+			writeLine(&sb, "STATUS:"+e.Status)
+		}
+		if !e.LastModified.IsZero() {
+			writeLine(&sb, "LAST-MODIFIED:"+e.LastModified.UTC().Format(utcFormat))
+		}
+		writeLine(&sb, "END:VEVENT")
+	}
+
+	writeLine(&sb, "END:VCALENDAR")
+	return []byte(sb.String())
+}
+
+// escapeText escapes a TEXT value (RFC 5545 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeLine writes a content line, folding it so that no line is longer than 75 octets (RFC 5545 3.1).
+func writeLine(sb *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		// Do not split multi-byte characters
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	sb.WriteString(line)
+	sb.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
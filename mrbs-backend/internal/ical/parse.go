@@ -0,0 +1,352 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"rep-mrbs/internal/models"
+)
+
+// defaultLocation is used for floating times and time zones that cannot be loaded, same as models.ParseDateTime.
+var defaultLocation = time.FixedZone("GMT", 8*3600)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var ErrNoCalendar = errors.New("file is not an iCalendar file")
+
+// ImportedEvent is a VEVENT read from an iCalendar file. Err is set if the event could not be understood,
+// the other fields are filled in as far as possible so that the event can still be reported.
+type ImportedEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+	Start       time.Time
+	End         time.Time
+	Rule        *models.RecurrenceRule // nil: the event does not repeat
+	ExDates     []time.Time            // Occurrences removed from the rule
+	Err         error
+}
+
+// Occurrences returns the start time of every occurrence of the event.
+func (e *ImportedEvent) Occurrences() ([]time.Time, error) {
+	if e.Rule == nil {
+		return []time.Time{e.Start}, nil
+	}
+
+	occurrences, err := e.Rule.Occurrences(e.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := occurrences[:0]
+	for _, t := range occurrences {
+		excluded := false
+		for _, ex := range e.ExDates {
+			if t.Equal(ex) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, t)
+		}
+	}
+	return kept, nil
+}
+
+// property is a content line, e.g. DTSTART;TZID=Asia/Singapore:20260112T100000
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the VEVENTs of an iCalendar file. Only the properties needed to create bookings are read.
+func Parse(r io.Reader) ([]ImportedEvent, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, ErrNoCalendar
+	}
+
+	var events []ImportedEvent
+	var current []property
+	inEvent := false
+	depth := 0 // Nested components inside the VEVENT, e.g. VALARM
+
+	for _, line := range lines {
+		prop := parseProperty(line)
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			inEvent, current = true, nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if inEvent {
+				events = append(events, newImportedEvent(current))
+			}
+			inEvent = false
+		case inEvent && prop.name == "BEGIN":
+			depth++
+		case inEvent && prop.name == "END":
+			depth--
+		case inEvent && depth == 0:
+			current = append(current, prop)
+		}
+	}
+
+	return events, nil
+}
+
+// unfold joins folded lines (RFC 5545 3.1) and drops empty lines.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) property {
+	// The value starts at the first colon that is not inside a quoted parameter value
+	quoted := false
+	split := len(line)
+	for i, ch := range line {
+		if ch == '"' {
+			quoted = !quoted
+		}
+		if ch == ':' && !quoted {
+			split = i
+			break
+		}
+	}
+
+	prop := property{params: map[string]string{}}
+	if split < len(line) {
+		prop.value = line[split+1:]
+	}
+	parts := strings.Split(line[:split], ";")
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return prop
+}
+
+func newImportedEvent(props []property) ImportedEvent {
+	var e ImportedEvent
+	var duration time.Duration
+	var rrule *property
+	var errs []error
+
+	for _, p := range props {
+		switch p.name {
+		case "UID":
+			e.UID = p.value
+		case "SUMMARY":
+			e.Summary = unescapeText(p.value)
+		case "DESCRIPTION":
+			e.Description = unescapeText(p.value)
+		case "LOCATION":
+			e.Location = unescapeText(p.value)
+		case "STATUS":
+			e.Status = strings.ToUpper(p.value)
+		case "DTSTART":
+			t, err := parseDateTime(p)
+			errs = append(errs, err)
+			e.Start = t
+		case "DTEND":
+			t, err := parseDateTime(p)
+			errs = append(errs, err)
+			e.End = t
+		case "DURATION":
+			d, err := parseDuration(p.value)
+			errs = append(errs, err)
+			duration = d
+		case "RRULE":
+			rrule = &p
+		case "EXDATE":
+			for _, value := range strings.Split(p.value, ",") {
+				t, err := parseDateTime(property{name: p.name, params: p.params, value: value})
+				errs = append(errs, err)
+				e.ExDates = append(e.ExDates, t)
+			}
+		case "RDATE":
+			errs = append(errs, errors.New("RDATE is not supported"))
+		}
+	}
+
+	if e.Start.IsZero() && errors.Join(errs...) == nil {
+		errs = append(errs, errors.New("event has no DTSTART"))
+	}
+	if e.End.IsZero() && duration > 0 {
+		e.End = e.Start.Add(duration)
+	}
+	if e.End.IsZero() {
+		errs = append(errs, errors.New("event has no DTEND or DURATION"))
+	}
+	if rrule != nil {
+		rule, err := parseRRule(rrule.value, e.Start.Location())
+		errs = append(errs, err)
+		e.Rule = rule
+	}
+
+	e.Err = errors.Join(errs...)
+	return e
+}
+
+// parseDateTime parses a DATE-TIME value in UTC, with a TZID, or floating. All-day events (DATE values) cannot
+// be booked and are rejected.
+func parseDateTime(p property) (time.Time, error) {
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len("20060102") {
+		return time.Time{}, fmt.Errorf("%s: all-day events are not supported", p.name)
+	}
+
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse(utcFormat, p.value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: invalid date-time %q", p.name, p.value)
+		}
+		return t, nil
+	}
+
+	loc := defaultLocation
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: invalid date-time %q", p.name, p.value)
+	}
+	return t, nil
+}
+
+// parseDuration parses the time part of a DURATION value, e.g. PT1H30M. Weeks and days are also accepted.
+func parseDuration(value string) (time.Duration, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if rest == value || rest == "" {
+		return 0, fmt.Errorf("DURATION: invalid duration %q", value)
+	}
+
+	var total time.Duration
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	num := ""
+	for i := 0; i < len(rest); i++ {
+		ch := rest[i]
+		switch {
+		case ch == 'T':
+		case ch >= '0' && ch <= '9':
+			num += string(ch)
+		default:
+			unit, ok := units[ch]
+			n, err := strconv.Atoi(num)
+			if !ok || err != nil {
+				return 0, fmt.Errorf("DURATION: invalid duration %q", value)
+			}
+			total += time.Duration(n) * unit
+			num = ""
+		}
+	}
+	return total, nil
+}
+
+// parseRRule converts an RRULE into a recurrence rule. Only daily and weekly rules, optionally on several
+// weekdays, are supported.
+func parseRRule(value string, loc *time.Location) (*models.RecurrenceRule, error) {
+	rule := models.RecurrenceRule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch strings.ToUpper(val) {
+			case "DAILY":
+				rule.Frequency = models.FrequencyDaily
+			case "WEEKLY":
+				rule.Frequency = models.FrequencyWeekly
+			default:
+				return nil, fmt.Errorf("RRULE: FREQ=%s is not supported", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("RRULE: invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("RRULE: invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val, loc)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return nil, fmt.Errorf("RRULE: BYDAY=%s is not supported", val)
+				}
+				rule.Weekdays = append(rule.Weekdays, weekday)
+			}
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return nil, fmt.Errorf("RRULE: %s is not supported", key)
+		}
+	}
+
+	if len(rule.Weekdays) > 0 && rule.Frequency != models.FrequencyWeekly {
+		return nil, errors.New("RRULE: BYDAY is only supported for weekly rules")
+	}
+	return &rule, nil
+}
+
+// parseUntil parses the UNTIL of an RRULE. A date includes occurrences on that day.
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if len(value) == len("20060102") {
+		day, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("RRULE: invalid UNTIL %q", value)
+		}
+		return day.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return parseDateTime(property{name: "RRULE: UNTIL", params: map[string]string{}, value: value})
+}
+
+// unescapeText reverses escapeText.
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
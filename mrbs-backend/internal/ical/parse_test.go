@@ -0,0 +1,252 @@
+package ical
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"rep-mrbs/internal/models"
+)
+
+func TestUnfold(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "LF line endings",
+			input: "BEGIN:VCALENDAR\nSUMMARY:Team sync\nEND:VCALENDAR\n",
+			want:  []string{"BEGIN:VCALENDAR", "SUMMARY:Team sync", "END:VCALENDAR"},
+		},
+		{
+			name:  "CRLF line endings",
+			input: "BEGIN:VCALENDAR\r\nSUMMARY:Team sync\r\nEND:VCALENDAR\r\n",
+			want:  []string{"BEGIN:VCALENDAR", "SUMMARY:Team sync", "END:VCALENDAR"},
+		},
+		{
+			name:  "folded with a space",
+			input: "DESCRIPTION:Weekly review of the\r\n  room bookings\r\n",
+			want:  []string{"DESCRIPTION:Weekly review of the room bookings"},
+		},
+		{
+			name:  "folded with a tab, several times",
+			input: "DESCRIPTION:a\r\n\tb\r\n\tc\r\nUID:1\r\n",
+			want:  []string{"DESCRIPTION:abc", "UID:1"},
+		},
+		{
+			name:  "empty lines dropped",
+			input: "BEGIN:VCALENDAR\r\n\r\nEND:VCALENDAR\r\n\r\n",
+			want:  []string{"BEGIN:VCALENDAR", "END:VCALENDAR"},
+		},
+		{
+			name:  "leading continuation kept as a line",
+			input: " orphan\r\nUID:1\r\n",
+			want:  []string{" orphan", "UID:1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unfold(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseProperty(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   string
+		value  string
+		params map[string]string
+	}{
+		{
+			name:   "no parameters",
+			line:   "SUMMARY:Team sync",
+			want:   "SUMMARY",
+			value:  "Team sync",
+			params: map[string]string{},
+		},
+		{
+			name:   "parameter and colons in the value",
+			line:   "DTSTART;TZID=Asia/Singapore:20260112T100000",
+			want:   "DTSTART",
+			value:  "20260112T100000",
+			params: map[string]string{"TZID": "Asia/Singapore"},
+		},
+		{
+			name:   "quoted colon in a parameter",
+			line:   `ATTENDEE;CN="Room: Da Vinci";ROLE=REQ-PARTICIPANT:mailto:davinci@example.com`,
+			want:   "ATTENDEE",
+			value:  "mailto:davinci@example.com",
+			params: map[string]string{"CN": "Room: Da Vinci", "ROLE": "REQ-PARTICIPANT"},
+		},
+		{
+			name:   "lowercase name and parameter",
+			line:   "dtstart;value=DATE:20260112",
+			want:   "DTSTART",
+			value:  "20260112",
+			params: map[string]string{"VALUE": "DATE"},
+		},
+		{
+			name:   "no value",
+			line:   "SUMMARY",
+			want:   "SUMMARY",
+			value:  "",
+			params: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseProperty(tt.line)
+			if got.name != tt.want || got.value != tt.value {
+				t.Fatalf("expected %s = %q, got %s = %q", tt.want, tt.value, got.name, got.value)
+			}
+			if len(got.params) != len(tt.params) {
+				t.Fatalf("expected params %v, got %v", tt.params, got.params)
+			}
+			for k, v := range tt.params {
+				if got.params[k] != v {
+					t.Fatalf("expected param %s = %q, got %q", k, v, got.params[k])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    models.RecurrenceRule
+		wantErr string
+	}{
+		{
+			name:  "daily with count",
+			value: "FREQ=DAILY;COUNT=5",
+			want:  models.RecurrenceRule{Frequency: models.FrequencyDaily, Interval: 1, Count: 5},
+		},
+		{
+			name:  "weekly on several days",
+			value: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;WKST=SU;COUNT=4",
+			want:  models.RecurrenceRule{Frequency: models.FrequencyWeekly, Interval: 2, Count: 4, Weekdays: []time.Weekday{time.Monday, time.Thursday}},
+		},
+		{
+			name:  "date-only UNTIL includes the whole day",
+			value: "FREQ=WEEKLY;UNTIL=20260331",
+			want:  models.RecurrenceRule{Frequency: models.FrequencyWeekly, Interval: 1, Until: time.Date(2026, 3, 31, 23, 59, 59, 0, defaultLocation)},
+		},
+		{
+			name:  "UTC UNTIL",
+			value: "FREQ=DAILY;UNTIL=20260331T020000Z",
+			want:  models.RecurrenceRule{Frequency: models.FrequencyDaily, Interval: 1, Until: time.Date(2026, 3, 31, 2, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "unsupported FREQ",
+			value:   "FREQ=MONTHLY;COUNT=3",
+			wantErr: "FREQ=MONTHLY is not supported",
+		},
+		{
+			name:    "BYDAY on a daily rule",
+			value:   "FREQ=DAILY;BYDAY=MO;COUNT=3",
+			wantErr: "BYDAY is only supported for weekly rules",
+		},
+		{
+			name:    "BYDAY with an ordinal",
+			value:   "FREQ=WEEKLY;BYDAY=1MO;COUNT=3",
+			wantErr: "BYDAY=1MO is not supported",
+		},
+		{
+			name:    "unsupported part",
+			value:   "FREQ=WEEKLY;BYSETPOS=1;COUNT=3",
+			wantErr: "BYSETPOS is not supported",
+		},
+		{
+			name:    "invalid COUNT",
+			value:   "FREQ=DAILY;COUNT=many",
+			wantErr: `invalid COUNT "many"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRRule(tt.value, defaultLocation)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Frequency != tt.want.Frequency || got.Interval != tt.want.Interval || got.Count != tt.want.Count ||
+				!got.Until.Equal(tt.want.Until) || !slices.Equal(got.Weekdays, tt.want.Weekdays) {
+				t.Fatalf("expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestOccurrencesExDate(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:weekly@example.com",
+		"SUMMARY:Weekly review",
+		"DTSTART;TZID=Asia/Singapore:20260105T100000",
+		"DTEND;TZID=Asia/Singapore:20260105T110000",
+		"RRULE:FREQ=WEEKLY;COUNT=4",
+		"EXDATE;TZID=Asia/Singapore:20260112T100000,20260126T100000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0].Err != nil {
+		t.Fatalf("unexpected event error: %v", events[0].Err)
+	}
+
+	got, err := events[0].Occurrences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := events[0].Start.Location()
+	want := []time.Time{
+		time.Date(2026, 1, 5, 10, 0, 0, 0, loc),
+		time.Date(2026, 1, 19, 10, 0, 0, 0, loc),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestOccurrencesSingleEvent(t *testing.T) {
+	start := time.Date(2026, 1, 5, 10, 0, 0, 0, defaultLocation)
+	e := ImportedEvent{Start: start, End: start.Add(time.Hour)}
+
+	got, err := e.Occurrences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !got[0].Equal(start) {
+		t.Fatalf("expected only %v, got %v", start, got)
+	}
+}
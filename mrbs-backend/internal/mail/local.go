@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DisabledSender refuses to send, so that emails with secrets (e.g. password reset links) never end up anywhere
+// but the recipient's inbox when no transport is configured.
+type DisabledSender struct{}
+
+func (DisabledSender) Send(_ context.Context, msg Message) error {
+	log.Warn().Str("to", msg.To).Str("subject", msg.Subject).Msg("Email not sent, MAIL_SENDER is not configured")
+	return ErrDisabled
+}
+
+// LogSender writes messages to the application log instead of sending them, for local development. The body may
+// contain secrets such as password reset links, so it is only logged at debug level. Attachments are only listed.
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, msg Message) error {
+	var attachments []string
+	for _, a := range msg.Attachments {
+		attachments = append(attachments, a.Filename)
+	}
+	log.Info().Str("to", msg.To).Str("subject", msg.Subject).Strs("attachments", attachments).Msg("Email (not sent, MAIL_SENDER=log)")
+	log.Debug().Str("to", msg.To).Str("body", msg.Text).Msg("Email body")
+	return nil
+}
+
+// FileSender writes each message into Dir as an .eml file, which can be opened with any mail client.
+type FileSender struct {
+	Dir string
+}
+
+func (s FileSender) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+
+	data, err := msg.Bytes(from)
+	if err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	path := filepath.Join(s.Dir, fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), recipient))
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	log.Info().Str("to", msg.To).Str("path", path).Msg("Email written to file")
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package mail sends emails through a pluggable Sender. The sender is chosen with MAIL_SENDER in config/.env:
+//   - unset (default): emails are not sent, see DisabledSender
+//   - "log": writes the message to the application log, for local development only
+//   - "file": writes each message as a .eml file into MAIL_DIR (default ./mail)
+//   - "smtp": sends through SMTP_HOST, see NewSMTPSender
+package mail
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+type Message struct {
+	To          string
+	Subject     string
+	Text        string // Plain text body
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string // e.g. "text/calendar; method=REQUEST"
+	Data        []byte
+}
+
+// Sender delivers a message through one transport.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ErrDisabled - no MAIL_SENDER is configured, or it is misconfigured.
+var ErrDisabled = errors.New("sending emails is disabled, set MAIL_SENDER in config/.env")
+
+var sender Sender = DisabledSender{}
+
+// from - sender address of every email, set with MAIL_FROM in config/.env
+var from = "REP MRBS <no-reply@rep-mrbs>"
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	if value, exists := os.LookupEnv("MAIL_FROM"); exists {
+		from = value
+	}
+
+	kind, exists := os.LookupEnv("MAIL_SENDER")
+	if !exists {
+		log.Warn().Msg("MAIL_SENDER not set in /config/.env, emails will not be sent.")
+		return
+	}
+
+	switch kind {
+	case "log":
+		sender = LogSender{}
+	case "file":
+		dir, exists := os.LookupEnv("MAIL_DIR")
+		if !exists {
+			dir = "./mail"
+		}
+		sender = FileSender{Dir: dir}
+	case "smtp":
+		smtpSender, err := NewSMTPSender()
+		if err != nil {
+			log.Error().Err(err).Msg("SMTP is not configured, emails will not be sent.")
+			return
+		}
+		sender = smtpSender
+	default:
+		log.Warn().Str("MAIL_SENDER", kind).Msg("Unknown MAIL_SENDER, emails will not be sent.")
+	}
+}
+
+// SetSender replaces the configured sender.
+func SetSender(s Sender) {
+	sender = s
+}
+
+// Enabled reports whether a sender is configured, callers can skip preparing emails that would not be sent.
+func Enabled() bool {
+	_, disabled := sender.(DisabledSender)
+	return !disabled
+}
+
+// Send delivers msg through the configured sender.
+func Send(ctx context.Context, msg Message) error {
+	return sender.Send(ctx, msg)
+}
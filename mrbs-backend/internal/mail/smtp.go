@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SMTPSender sends messages through an SMTP server. STARTTLS is used when the server supports it.
+type SMTPSender struct {
+	Addr string // host:port
+	Auth smtp.Auth
+}
+
+// NewSMTPSender configures an SMTPSender from config/.env: SMTP_HOST, SMTP_PORT (default 587), and optionally
+// SMTP_USERNAME and SMTP_PASSWORD.
+func NewSMTPSender() (*SMTPSender, error) {
+	host, exists := os.LookupEnv("SMTP_HOST")
+	if !exists {
+		return nil, errors.New("SMTP_HOST not set in config/.env")
+	}
+
+	port, exists := os.LookupEnv("SMTP_PORT")
+	if !exists {
+		port = "587"
+	}
+
+	s := &SMTPSender{Addr: net.JoinHostPort(host, port)}
+	if username, exists := os.LookupEnv("SMTP_USERNAME"); exists {
+		s.Auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	log.Info().Str("addr", s.Addr).Msg("Emails will be sent through SMTP")
+	return s, nil
+}
+
+func (s *SMTPSender) Send(_ context.Context, msg Message) error {
+	data, err := msg.Bytes(from)
+	if err != nil {
+		return err
+	}
+
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(s.Addr, s.Auth, sender.Address, []string{msg.To}, data)
+}
@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.txt
+var templateFiles embed.FS
+
+// Template names, one per file in templates/. Each file defines a "subject" and a "body" template.
+const (
+	TemplateBookingCreated = "booking-created"
+	TemplateBookingEdited  = "booking-edited"
+	TemplateBookingDeleted = "booking-deleted"
+	TemplateResetPassword  = "reset-password"
+)
+
+var templates = map[string]*template.Template{}
+
+func init() {
+	for _, name := range []string{TemplateBookingCreated, TemplateBookingEdited, TemplateBookingDeleted, TemplateResetPassword} {
+		templates[name] = template.Must(template.ParseFS(templateFiles, "templates/"+name+".txt"))
+	}
+}
+
+// Render executes a template and returns a message without a recipient.
+func Render(name string, data any) (Message, error) {
+	var msg Message
+
+	tmpl, ok := templates[name]
+	if !ok {
+		return msg, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return msg, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return msg, err
+	}
+
+	msg.Subject = strings.TrimSpace(subject.String())
+	msg.Text = strings.TrimLeft(body.String(), "\n")
+	return msg, nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// APITokenPrefix - prefix of every personal access token, makes leaked tokens easy to recognise.
+const APITokenPrefix = "mrbs_"
+
+// Scopes of personal access tokens, from least to most access
+const (
+	ScopeBookingsRead  = "bookings:read"  // GET requests that a user can make
+	ScopeBookingsWrite = "bookings:write" // Every request that a user can make, e.g. creating and deleting own bookings
+	ScopeAdmin         = "admin"          // Every request that the owner can make, the owner must be an admin
+)
+
+// ScopeLevels - highest user level that a request made with a token of the scope is given.
+var ScopeLevels = map[string]int{
+	ScopeBookingsRead:  1,
+	ScopeBookingsWrite: 1,
+	ScopeAdmin:         2,
+}
+
+// APIToken is a personal access token, sent as "Authorization: Bearer <token>". Only the hash is stored.
+type APIToken struct {
+	TokenID     uint       `gorm:"column:token_id; primaryKey" json:"token_id"`
+	UserID      uint       `gorm:"column:user_id" json:"-"`
+	Name        string     `gorm:"column:name" json:"name"`
+	TokenHash   string     `gorm:"column:token_hash" json:"-"`
+	Scope       string     `gorm:"column:scope" json:"scope"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at" json:"expires_at"` // NULL: token does not expire
+	LastUsed    *time.Time `gorm:"column:last_used" json:"last_used"`
+	TimeCreated time.Time  `gorm:"column:time_created; default:now()" json:"time_created"`
+}
+
+func (APIToken) TableName() string {
+	return "mrbs.api_tokens"
+}
+
+// GenerateAPIToken returns a new personal access token, a random token with APITokenPrefix.
+func GenerateAPIToken() (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return APITokenPrefix + token, nil
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"rep-mrbs/internal/db"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Area holds the booking policy shared by every room in the area.
+// Durations are in minutes, quotas (MaxPerDay, MaxPerWeek) are in hours. A nil quota means no limit.
+type Area struct {
+	AreaID          uint   `gorm:"column:area_id; primaryKey" json:"area_id"`
+	DisplayName     string `gorm:"column:display_name" json:"display_name"`
+	Resolution      int    `gorm:"column:resolution" json:"resolution"`             // Bookings must start and end on multiples of this
+	DefaultDuration int    `gorm:"column:default_duration" json:"default_duration"` // Step used for the Telegram time and duration buttons
+	MorningStarts   string `gorm:"column:morning_starts" json:"morning_starts"`     // HH:MM
+	EveningEnds     string `gorm:"column:evening_ends" json:"evening_ends"`         // HH:MM, closing times before MorningStarts fall on the next day
+	MaxPerDay       *int   `gorm:"column:max_per_day" json:"max_per_day"`
+	MaxPerWeek      *int   `gorm:"column:max_per_week" json:"max_per_week"`
+	MaxDuration     *int   `gorm:"column:max_duration" json:"max_duration"`
+	BufferDuration  int    `gorm:"column:buffer_duration" json:"buffer_duration"` // Window around a booking in which the same user cannot make another booking
+}
+
+// areaQuery converts the interval and time columns of mrbs.areas into minutes and HH:MM strings.
+const areaQuery = `
+	SELECT area_id, display_name,
+		(EXTRACT(EPOCH FROM resolution) / 60)::integer AS resolution,
+		(EXTRACT(EPOCH FROM default_duration) / 60)::integer AS default_duration,
+		to_char(morning_starts, 'HH24:MI') AS morning_starts,
+		to_char(evening_ends, 'HH24:MI') AS evening_ends,
+		max_per_day,
+		max_per_week,
+		(EXTRACT(EPOCH FROM max_duration) / 60)::integer AS max_duration,
+		(EXTRACT(EPOCH FROM buffer_duration) / 60)::integer AS buffer_duration
+	FROM mrbs.areas
+	ORDER BY area_id ASC`
+
+// CachedAreas global cache for areas, use GetArea or GetAreaForRoom instead of querying the database directly.
+var CachedAreas []Area
+
+var areasMu sync.RWMutex
+
+// InitAreas fetches areas from the DB. Called once at startup, and again whenever an area is changed.
+func InitAreas() error {
+	areas, err := gorm.G[Area](db.GormDB).Raw(areaQuery).Find(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching areas from database")
+		return err
+	}
+
+	areasMu.Lock()
+	CachedAreas = areas
+	areasMu.Unlock()
+
+	log.Info().Int("count", len(areas)).Msg("Area cache initialized")
+	return nil
+}
+
+// GetAreas returns every cached area.
+func GetAreas() []Area {
+	areasMu.RLock()
+	defer areasMu.RUnlock()
+	return CachedAreas
+}
+
+// GetArea returns the cached area with the given id.
+func GetArea(areaID uint) (*Area, bool) {
+	areas := GetAreas()
+	for i := range areas {
+		if areas[i].AreaID == areaID {
+			return &areas[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetAreaForRoom returns the policy that applies to bookings of a room.
+func GetAreaForRoom(roomID uint) (*Area, bool) {
+	room, ok := GetRoom(roomID)
+	if !ok {
+		return nil, false
+	}
+	return GetArea(room.AreaID)
+}
+
+// BusinessDay returns the opening and closing time of the business day that t falls in.
+// If the area closes after midnight, times between midnight and closing belong to the previous day.
+func (a *Area) BusinessDay(t time.Time) (opening time.Time, closing time.Time) {
+	morning := parseClock(a.MorningStarts)
+	evening := parseClock(a.EveningEnds)
+
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	opening = midnight.Add(morning)
+	closing = midnight.Add(evening)
+
+	if evening <= morning {
+		// Area closes on the next day
+		if t.Before(closing) {
+			opening = opening.AddDate(0, 0, -1)
+		} else {
+			closing = closing.AddDate(0, 0, 1)
+		}
+	}
+	return opening, closing
+}
+
+// OpeningHours returns the opening and closing time of the business day starting on the date of day.
+func (a *Area) OpeningHours(day time.Time) (opening time.Time, closing time.Time) {
+	y, m, d := day.Date()
+	return a.BusinessDay(time.Date(y, m, d, 0, 0, 0, 0, day.Location()).Add(parseClock(a.MorningStarts)))
+}
+
+// IsOpen reports whether the whole of [start, end) falls within a single business day.
+func (a *Area) IsOpen(start time.Time, end time.Time) bool {
+	opening, closing := a.BusinessDay(start)
+	return !start.Before(opening) && !end.After(closing)
+}
+
+// IsAligned reports whether t falls on a multiple of the area's resolution.
+func (a *Area) IsAligned(t time.Time) bool {
+	if a.Resolution <= 0 {
+		return true
+	}
+	return (t.Hour()*60+t.Minute())%a.Resolution == 0 && t.Second() == 0
+}
+
+// DailyLimit returns the maximum time a user can book per business day, and false if there is no limit.
+func (a *Area) DailyLimit() (time.Duration, bool) {
+	if a.MaxPerDay == nil {
+		return 0, false
+	}
+	return time.Duration(*a.MaxPerDay) * time.Hour, true
+}
+
+// WeeklyLimit returns the maximum time a user can book per week, and false if there is no limit.
+func (a *Area) WeeklyLimit() (time.Duration, bool) {
+	if a.MaxPerWeek == nil {
+		return 0, false
+	}
+	return time.Duration(*a.MaxPerWeek) * time.Hour, true
+}
+
+// MaxBookingDuration returns the maximum length of a single booking, and false if there is no limit.
+func (a *Area) MaxBookingDuration() (time.Duration, bool) {
+	if a.MaxDuration == nil {
+		return 0, false
+	}
+	return time.Duration(*a.MaxDuration) * time.Minute, true
+}
+
+// parseClock converts HH:MM into the duration since midnight.
+func parseClock(clock string) time.Duration {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		log.Error().Err(err).Str("clock", clock).Msg("Invalid time of day in area, defaulting to midnight")
+		return 0
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// AuditEntry records a privileged change: who made it, what was changed, and the state before and after.
+type AuditEntry struct {
+	AuditID     uint64    `gorm:"column:audit_id; primaryKey" json:"audit_id"`
+	ActorID     *uint     `gorm:"column:actor_id" json:"actor_id"` // NULL once the admin has been deleted
+	ActorName   string    `gorm:"column:actor_name" json:"actor_name"`
+	Action      string    `gorm:"column:action" json:"action"`           // e.g. "booking.delete"
+	TargetType  string    `gorm:"column:target_type" json:"target_type"` // e.g. "booking"
+	TargetID    string    `gorm:"column:target_id" json:"target_id"`
+	Before      JSONB     `gorm:"column:before" json:"before"` // NULL for creations
+	After       JSONB     `gorm:"column:after" json:"after"`   // NULL for deletions
+	IPAddress   string    `gorm:"column:ip_address" json:"ip_address"`
+	TimeCreated time.Time `gorm:"column:time_created; default:now()" json:"time_created"`
+}
+
+func (AuditEntry) TableName() string {
+	return "mrbs.audit_log"
+}
+
+// JSONB is a jsonb column, passed through as raw JSON.
+type JSONB []byte
+
+func (j JSONB) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *JSONB) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSONB(v)
+	default:
+		return errors.New("jsonb: unsupported type")
+	}
+	return nil
+}
+
+func (j JSONB) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
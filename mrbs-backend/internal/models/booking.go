@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 /*
 * NOTE: All duration constants to be defined in MINUTES
@@ -12,42 +16,49 @@ const MaxBookingColours = 6
 // MaxTitleLength - Maximum char length of a title. This was previously hardcoded throughout the codebase.
 const MaxTitleLength = 25
 
-const BookingPeriodSize = 30 // 30 mins per period
+// MaxDeleteReasonLength - Maximum char length of the reason given when deleting a booking.
+const MaxDeleteReasonLength = 200
 
-const DailyBookingLimit = 6 // 3 hours a day = 6 booking periods
+// BookingPeriodSize - Unit of the booking duration in the API and the Telegram wizard (30 mins per period).
+// Booking policy (opening hours, quotas, maximum duration, buffer) is configured per area, see area.go.
+const BookingPeriodSize = 30
 
-// BufferDuration - Number of *minutes* around the booking to search for existing bookings made by the same user to prevent
-// abuse (creating multiple mini bookings).
-// To allow better granularity, buffer duration will not be defined in BookingPeriodSize
-const BufferDuration = 4 * 60
+type Booking struct {
+	BookingID   uint       `gorm:"column:booking_id; primaryKey"`
+	UserID      uint       `gorm:"column:user_id"`
+	StartTime   time.Time  `gorm:"column:start_time"`
+	EndTime     time.Time  `gorm:"column:end_time"`
+	RoomID      uint       `gorm:"column:room_id"`
+	TimeCreated time.Time  `gorm:"time_created"`
+	Title       string     `gorm:"column:title"`
+	Description string     `gorm:"column:description"`
+	IcalUID     string     `gorm:"column:ical_uid"` // Generated when the booking is created, see ical.NewUID
+	IcalSeq     int        `gorm:"column:ical_seq"` // Incremented every time the booking changes
+	Colour      int        `gorm:"column:colour; default:1"`
+	SeriesID    *uint      `gorm:"column:series_id"`     // NULL: booking is not part of a recurring series
+	CheckedInAt *time.Time `gorm:"column:checked_in_at"` // NULL: user has not checked in
+	ReleasedAt  *time.Time `gorm:"column:released_at"`   // NULL: booking has not been released as a no-show
 
-// MaxBookingDuration - Maximum duration of a single booking. This was previously hardcoded throughout the codebase, use this value in the future.
-// If there are any bugs regarding to the maximum booking duration in the future (i.e. the maximum booking duration is changed), look for this first.
-const MaxBookingDuration = 6 * BookingPeriodSize // 3 hours
+	ReminderSentAt *time.Time `gorm:"column:reminder_sent_at"` // NULL: the Telegram reminder has not been sent yet
 
-type Booking struct {
-	BookingID   uint      `gorm:"column:booking_id; primaryKey"`
-	UserID      uint      `gorm:"column:user_id"`
-	StartTime   time.Time `gorm:"column:start_time"`
-	EndTime     time.Time `gorm:"column:end_time"`
-	RoomID      uint      `gorm:"column:room_id"`
-	TimeCreated time.Time `gorm:"time_created"`
-	Title       string    `gorm:"column:title"`
-	Description string    `gorm:"column:description"`
-	IcalUID     string    `gorm:"column:ical_uid"`
-	IcalSeq     string    `gorm:"column:ical_seq; default:1"`
-	Colour      int       `gorm:"column:colour; default:1"`
+	// Deleted bookings are kept until they are purged. gorm leaves them out of queries unless the DB is Unscoped,
+	// raw SQL must filter on deleted_at.
+	DeletedAt    gorm.DeletedAt `gorm:"column:deleted_at"`
+	DeletedBy    *uint          `gorm:"column:deleted_by"` // NULL: not deleted, or the user who deleted it has been removed
+	DeleteReason string         `gorm:"column:delete_reason"`
+}
+
+// NoShow records a booking that was not checked in within the grace period.
+type NoShow struct {
+	NoShowID        uint      `gorm:"column:no_show_id; primaryKey" json:"no_show_id"`
+	UserID          uint      `gorm:"column:user_id" json:"user_id"`
+	BookingID       *uint     `gorm:"column:booking_id" json:"booking_id"` // NULL: booking has since been deleted
+	RoomID          uint      `gorm:"column:room_id" json:"room_id"`
+	StartTime       time.Time `gorm:"column:start_time" json:"start_time"`
+	ReleasedMinutes int       `gorm:"column:released_minutes" json:"released_minutes"` // Time given back to the room and the user's quota
+	TimeCreated     time.Time `gorm:"column:time_created; default:now()" json:"time_created"`
 }
 
-// BookingState tracks user progress when making a new booking via telegram bot
-// There is no user id associated with the booking. To get the user id, check the
-// telegram_auth table
-type BookingState struct {
-	MessageID   int // track the MessageID to edit
-	Step        int // 0: Date, 1: Room, 2: Time, 3: Duration, 4: Title...
-	RoomID      int
-	StartTime   time.Time
-	NumPeriods  int
-	Title       string
-	Description string
+func (NoShow) TableName() string {
+	return "mrbs.no_shows"
 }
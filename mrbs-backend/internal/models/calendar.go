@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// CalendarToken grants read access to the calendar feeds without a session. Each user has at most one token,
+// only its hash is stored.
+type CalendarToken struct {
+	UserID      uint       `gorm:"column:user_id; primaryKey"`
+	TokenHash   string     `gorm:"column:token_hash"`
+	TimeCreated time.Time  `gorm:"column:time_created; default:now()"`
+	LastUsed    *time.Time `gorm:"column:last_used"` // NULL: feed has not been fetched yet
+}
+
+func (CalendarToken) TableName() string {
+	return "mrbs.calendar_tokens"
+}
+
+// BookingTombstone is what remains of a deleted booking, so that calendar feeds can publish it as cancelled.
+type BookingTombstone struct {
+	BookingID   uint      `gorm:"column:booking_id; primaryKey"`
+	UserID      uint      `gorm:"column:user_id"`
+	RoomID      uint      `gorm:"column:room_id"`
+	StartTime   time.Time `gorm:"column:start_time"`
+	EndTime     time.Time `gorm:"column:end_time"`
+	Title       string    `gorm:"column:title"`
+	IcalUID     string    `gorm:"column:ical_uid"`
+	IcalSeq     int       `gorm:"column:ical_seq"` // Sequence of the cancellation, one more than the booking's last sequence
+	TimeDeleted time.Time `gorm:"column:time_deleted; default:now()"`
+}
+
+func (BookingTombstone) TableName() string {
+	return "mrbs.booking_tombstones"
+}
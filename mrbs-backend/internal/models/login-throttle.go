@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// LoginThrottle counts the failed logins for a username or a client IP. Keys are "user:<username>" and "ip:<address>".
+// Password reset requests are counted the same way, keys "reset:<email>" and "reset-ip:<address>".
+type LoginThrottle struct {
+	ThrottleKey  string     `gorm:"column:throttle_key; primaryKey" json:"key"`
+	Failures     int        `gorm:"column:failures" json:"failures"`
+	LastFailure  time.Time  `gorm:"column:last_failure" json:"last_failure"`
+	BlockedUntil *time.Time `gorm:"column:blocked_until" json:"blocked_until"` // NULL: logins are not delayed
+}
+
+func (LoginThrottle) TableName() string {
+	return "mrbs.login_throttles"
+}
+
+// LoginThrottle key prefixes
+const (
+	ThrottleUserPrefix    = "user:"
+	ThrottleIPPrefix      = "ip:"
+	ThrottleResetPrefix   = "reset:"
+	ThrottleResetIPPrefix = "reset-ip:"
+)
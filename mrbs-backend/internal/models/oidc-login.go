@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// OIDCLogin is an SSO login waiting for the user to come back from the identity provider. Only the hash of the state
+// is stored, the state itself is also kept in a cookie so that the login can only be completed in the same browser.
+type OIDCLogin struct {
+	StateHash    string    `gorm:"column:state_hash; primaryKey"`
+	Nonce        string    `gorm:"column:nonce"`
+	CodeVerifier string    `gorm:"column:code_verifier"` // PKCE verifier
+	Redirect     string    `gorm:"column:redirect"`      // Path of the UI to return to
+	ExpiresAt    time.Time `gorm:"column:expires_at"`
+}
+
+func (OIDCLogin) TableName() string {
+	return "mrbs.oidc_logins"
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"sync"
 
 	"rep-mrbs/internal/db"
 
@@ -10,47 +11,68 @@ import (
 )
 
 type Room struct {
-	RoomID      uint   `gorm:"column:room_id; primaryKey"`
-	AreaID      uint   `gorm:"column:area_id"`
-	DisplayName string `gorm:"column:display_name"`
-	SortKey     string `gorm:"column:sort_key"`
-	Description string `gorm:"column:description"`
-	Capacity    uint   `gorm:"column:capacity"`
-	AdminEmail  string `gorm:"column:admin_email"`
+	RoomID      uint   `gorm:"column:room_id; primaryKey" json:"room_id"`
+	AreaID      uint   `gorm:"column:area_id" json:"area_id"`
+	DisplayName string `gorm:"column:display_name" json:"display_name"`
+	SortKey     string `gorm:"column:sort_key" json:"sort_key"`
+	Description string `gorm:"column:description" json:"description"`
+	Capacity    uint   `gorm:"column:capacity" json:"capacity"`
+	AdminEmail  string `gorm:"column:admin_email" json:"admin_email"`
+	Disabled    bool   `gorm:"column:disabled" json:"disabled"` // Disabled rooms keep their bookings but cannot be booked
 }
 
 // CachedRooms global cache for rooms, use this instead of querying database directly.
+// The cache is replaced (never modified in place) by InitRooms, read it through GetRooms.
 var CachedRooms []Room
 
-// InitRooms fetches rooms from the DB once at startup
+var roomsMu sync.RWMutex
+
+// InitRooms fetches rooms from the DB. Called once at startup, and again whenever a room is changed.
 func InitRooms() error {
-	var err error
-	CachedRooms, err = gorm.G[Room](db.GormDB).Order("room_id ASC").Find(context.Background())
+	rooms, err := gorm.G[Room](db.GormDB).Order("sort_key ASC, room_id ASC").Find(context.Background())
 	if err != nil {
 		log.Error().Err(err).Msg("Error fetching rooms from database")
 		return err
 	}
-	log.Info().Int("count", len(CachedRooms)).Msg("Room cache initialized")
+
+	roomsMu.Lock()
+	CachedRooms = rooms
+	roomsMu.Unlock()
+
+	log.Info().Int("count", len(rooms)).Msg("Room cache initialized")
 	return nil
 }
 
-var roomNames = [...]string{
-	"",
-	"Seminar Room 1",
-	"Seminar Room 2",
-	"Alan Turing",
-	"Da Vinci",
-	"Isaac Newton",
-	"Marie Curie",
-	"Michael Faraday",
-	"Nikola Tesla",
-	"Thomas Edison",
+// GetRooms returns every cached room, including disabled rooms, ordered by sort key.
+func GetRooms() []Room {
+	roomsMu.RLock()
+	defer roomsMu.RUnlock()
+	return CachedRooms
+}
+
+// GetRoom returns the cached room with the given id.
+func GetRoom(roomID uint) (Room, bool) {
+	for _, room := range GetRooms() {
+		if room.RoomID == roomID {
+			return room, true
+		}
+	}
+	return Room{}, false
+}
+
+// IsBookableRoom returns true if the room exists and has not been disabled.
+func IsBookableRoom(roomID uint) bool {
+	room, ok := GetRoom(roomID)
+	return ok && !room.Disabled
 }
 
 func GetRoomNameFromID(r int) string {
-	if r < 1 || int(r) >= len(roomNames) {
+	if r < 1 {
+		return ""
+	}
+	room, ok := GetRoom(uint(r))
+	if !ok {
 		return ""
 	}
-	// Simply return the string at the index
-	return roomNames[r]
+	return room.DisplayName
 }
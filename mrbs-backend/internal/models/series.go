@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// MaxSeriesOccurrences - Upper bound on the number of bookings a single recurring booking can expand into.
+const MaxSeriesOccurrences = 52
+
+// Supported recurrence frequencies
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// SeriesScope decides which occurrences of a recurring booking an edit or delete applies to.
+type SeriesScope string
+
+const (
+	ScopeOccurrence SeriesScope = "this"      // Only the selected occurrence
+	ScopeFollowing  SeriesScope = "following" // The selected occurrence and every later occurrence
+	ScopeSeries     SeriesScope = "series"    // Every occurrence in the series
+)
+
+// IsValid reports whether s is one of the scopes, or empty (the selected occurrence only).
+func (s SeriesScope) IsValid() bool {
+	switch s {
+	case "", ScopeOccurrence, ScopeFollowing, ScopeSeries:
+		return true
+	}
+	return false
+}
+
+// BookingSeries links the occurrences of a recurring booking together. The rule is stored for reference,
+// the occurrences themselves are regular rows in mrbs.bookings.
+type BookingSeries struct {
+	SeriesID    uint       `gorm:"column:series_id; primaryKey" json:"series_id"`
+	UserID      uint       `gorm:"column:user_id" json:"user_id"`
+	RoomID      uint       `gorm:"column:room_id" json:"room_id"`
+	Frequency   string     `gorm:"column:frequency" json:"frequency"`
+	Interval    int        `gorm:"column:rep_interval" json:"interval"`
+	Until       *time.Time `gorm:"column:rep_until" json:"until"`
+	Count       *int       `gorm:"column:rep_count" json:"count"`
+	StartTime   time.Time  `gorm:"column:start_time" json:"start_time"`
+	EndTime     time.Time  `gorm:"column:end_time" json:"end_time"`
+	TimeCreated time.Time  `gorm:"column:time_created" json:"time_created"`
+}
+
+func (BookingSeries) TableName() string {
+	return "mrbs.booking_series"
+}
+
+// RecurrenceRule describes how a booking repeats. Exactly one of Until or Count should be set.
+type RecurrenceRule struct {
+	Frequency string    // FrequencyDaily or FrequencyWeekly
+	Interval  int       // Repeat every Interval days/weeks
+	Until     time.Time // Last day (inclusive) on which an occurrence may start
+	Count     int       // Total number of occurrences, including the first
+
+	// Weekdays on which a weekly rule repeats (e.g. every Monday and Thursday), only used by calendar imports.
+	// Empty: the weekday of the first occurrence.
+	Weekdays []time.Weekday
+}
+
+var (
+	ErrInvalidFrequency   = errors.New("frequency must be daily or weekly")
+	ErrInvalidInterval    = errors.New("interval must be at least 1")
+	ErrMissingRepeatEnd   = errors.New("either until or count must be provided")
+	ErrAmbiguousRepeat    = errors.New("until and count cannot both be provided")
+	ErrTooManyOccurrences = errors.New("recurrence exceeds the maximum number of occurrences")
+)
+
+// Occurrences expands the rule into the start times of each occurrence, beginning with start.
+func (r *RecurrenceRule) Occurrences(start time.Time) ([]time.Time, error) {
+	var step func(t time.Time) time.Time
+	switch r.Frequency {
+	case FrequencyDaily:
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, r.Interval) }
+	case FrequencyWeekly:
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 7*r.Interval) }
+	default:
+		return nil, ErrInvalidFrequency
+	}
+
+	if r.Interval < 1 {
+		return nil, ErrInvalidInterval
+	}
+	if r.Until.IsZero() && r.Count == 0 {
+		return nil, ErrMissingRepeatEnd
+	}
+	if !r.Until.IsZero() && r.Count != 0 {
+		return nil, ErrAmbiguousRepeat
+	}
+	if r.Count > MaxSeriesOccurrences {
+		return nil, ErrTooManyOccurrences
+	}
+
+	var occurrences []time.Time
+	// add appends t, returning false once the rule has no more occurrences.
+	add := func(t time.Time) (bool, error) {
+		if r.Count > 0 && len(occurrences) == r.Count {
+			return false, nil
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false, nil
+		}
+		if len(occurrences) == MaxSeriesOccurrences {
+			return false, ErrTooManyOccurrences
+		}
+		occurrences = append(occurrences, t)
+		return true, nil
+	}
+
+	if r.Frequency == FrequencyWeekly && len(r.Weekdays) > 0 {
+		// Weeks start on Monday, the first week is the one containing start
+		var offsets []int
+		for d := 0; d < 7; d++ {
+			if slices.Contains(r.Weekdays, time.Weekday((d+1)%7)) {
+				offsets = append(offsets, d)
+			}
+		}
+		monday := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+
+		for week := monday; ; week = step(week) {
+			for _, offset := range offsets {
+				t := week.AddDate(0, 0, offset)
+				if t.Before(start) {
+					continue
+				}
+				more, err := add(t)
+				if err != nil {
+					return nil, err
+				}
+				if !more {
+					return occurrences, nil
+				}
+			}
+		}
+	}
+
+	for t := start; ; t = step(t) {
+		more, err := add(t)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return occurrences, nil
+		}
+	}
+}
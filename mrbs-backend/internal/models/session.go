@@ -9,11 +9,19 @@ import (
 )
 
 type Session struct {
-	SessionKey  string    `gorm:"column:session_key;primaryKey"`
-	UserID      uint      `gorm:"column:user_id"`
-	TimeCreated time.Time `gorm:"column:time_created"`
+	SessionKey  string    `gorm:"column:session_key;primaryKey" json:"-"`
+	SessionID   uint      `gorm:"column:session_id; <-:false" json:"session_id"` // Generated by the database, safe to show unlike the key
+	UserID      uint      `gorm:"column:user_id" json:"-"`
+	TimeCreated time.Time `gorm:"column:time_created" json:"time_created"`
+	UserAgent   string    `gorm:"column:user_agent" json:"user_agent"`
+	IPAddress   string    `gorm:"column:ip_address" json:"ip_address"`
+	LastSeen    time.Time `gorm:"column:last_seen; default:now()" json:"last_seen"`
 }
 
+// SessionLastSeenInterval - last_seen is only written when it is older than this, so that every request does not
+// cause a write.
+const SessionLastSeenInterval = time.Minute
+
 func GenerateSessionKey() (string, error) {
 	b := make([]byte, 48)
 	_, err := rand.Read(b)
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// StrikeReasonNoShow - reason recorded for strikes added by the no-show release job.
+const StrikeReasonNoShow = "no-show"
+
+// Strike counts towards a temporary suspension of a user's booking rights. Cleared strikes are kept for reference
+// but no longer count.
+type Strike struct {
+	StrikeID    uint       `gorm:"column:strike_id; primaryKey" json:"strike_id"`
+	UserID      uint       `gorm:"column:user_id" json:"user_id"`
+	NoShowID    *uint      `gorm:"column:no_show_id" json:"no_show_id"` // NULL: strike was added manually
+	Reason      string     `gorm:"column:reason" json:"reason"`
+	CreatedBy   *uint      `gorm:"column:created_by" json:"created_by"` // NULL: strike was added by the no-show job
+	TimeCreated time.Time  `gorm:"column:time_created; default:now()" json:"time_created"`
+	ClearedAt   *time.Time `gorm:"column:cleared_at" json:"cleared_at"`
+	ClearedBy   *uint      `gorm:"column:cleared_by" json:"cleared_by"`
+}
+
+func (Strike) TableName() string {
+	return "mrbs.strikes"
+}
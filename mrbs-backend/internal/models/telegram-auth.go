@@ -2,11 +2,15 @@ package models
 
 import "time"
 
+// MaxReminderMinutes - longest lead time users can choose for booking reminders (1 day).
+const MaxReminderMinutes = 24 * 60
+
 type TelegramAuth struct {
-	UserID         uint      `gorm:"column:user_id; primaryKey" json:"user_id"`
-	TelegramChatID *int64    `gorm:"column:telegram_chat_id; unique" json:"telegram_chat_id"` // NULL: not set yet
-	AuthToken      string    `gorm:"column:auth_token" json:"auth_token"`                     // start token
-	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at"`
+	UserID          uint      `gorm:"column:user_id; primaryKey" json:"user_id"`
+	TelegramChatID  *int64    `gorm:"column:telegram_chat_id; unique" json:"telegram_chat_id"` // NULL: not set yet
+	AuthToken       string    `gorm:"column:auth_token" json:"auth_token"`                     // start token
+	CreatedAt       time.Time `gorm:"column:created_at" json:"created_at"`
+	ReminderMinutes *int      `gorm:"column:reminder_minutes" json:"reminder_minutes"` // NULL: default lead time, 0: reminders are off
 }
 
 func (TelegramAuth) TableName() string {
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// BookingState tracks user progress when making a new booking via telegram bot
+// There is no user id associated with the booking. To get the user id, check the
+// telegram_auth table
+type BookingState struct {
+	ChatID      int64     `gorm:"column:chat_id; primaryKey"`
+	MessageID   int       `gorm:"column:message_id"` // track the MessageID to edit
+	Step        int       `gorm:"column:step"`       // 0: Date, 1: Room, 2: Time, 3: Duration, 4: Title...
+	RoomID      int       `gorm:"column:room_id"`
+	StartTime   time.Time `gorm:"column:start_time"`
+	NumPeriods  int       `gorm:"column:num_periods"`
+	Title       string    `gorm:"column:title"`
+	Description string    `gorm:"column:description"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"` // Wizards that are not updated within the TTL expire
+
+	EditBookingID *uint `gorm:"column:edit_booking_id"` // NULL: the wizard makes a new booking
+}
+
+func (BookingState) TableName() string {
+	return "mrbs.telegram_wizards"
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the user has lost their authenticator.
+type RecoveryCode struct {
+	CodeID      uint       `gorm:"column:code_id; primaryKey"`
+	UserID      uint       `gorm:"column:user_id"`
+	CodeHash    string     `gorm:"column:code_hash"`
+	UsedAt      *time.Time `gorm:"column:used_at"` // NULL: code has not been used
+	TimeCreated time.Time  `gorm:"column:time_created; default:now()"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "mrbs.recovery_codes"
+}
+
+// Purposes of a login challenge
+const (
+	ChallengeVerify = "verify" // User enters a code from their authenticator or a recovery code
+	ChallengeEnrol  = "enrol"  // 2FA is mandatory for the user, who has to enrol before logging in
+)
+
+// LoginChallenge is the second step of a login, issued after the password has been checked. Only the hash of the
+// challenge is stored.
+type LoginChallenge struct {
+	ChallengeHash string    `gorm:"column:challenge_hash; primaryKey"`
+	UserID        uint      `gorm:"column:user_id"`
+	Purpose       string    `gorm:"column:purpose"`
+	Attempts      int       `gorm:"column:attempts"`
+	ExpiresAt     time.Time `gorm:"column:expires_at"`
+}
+
+func (LoginChallenge) TableName() string {
+	return "mrbs.login_challenges"
+}
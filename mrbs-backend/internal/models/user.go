@@ -5,8 +5,12 @@ import "time"
 
 type User struct {
 	PublicUser
-	PasswordHash string `gorm:"column:password_hash" json:"-"`
-	ResetKeyHash string `gorm:"column:reset_key_hash" json:"-"`
+	PasswordHash      string     `gorm:"column:password_hash" json:"-"`
+	ResetKeyHash      *string    `gorm:"column:reset_key_hash" json:"-"` // SHA-256 of the password reset token, NULL if no reset is pending
+	ResetKeyExpiresAt *time.Time `gorm:"column:reset_key_expires_at" json:"-"`
+	TOTPSecret        *string    `gorm:"column:totp_secret" json:"-"`     // NULL if the user has not started enrolling in 2FA
+	TOTPEnabledAt     *time.Time `gorm:"column:totp_enabled_at" json:"-"` // NULL: 2FA is off, or enrolment is not confirmed yet
+	TOTPLastStep      int64      `gorm:"column:totp_last_step" json:"-"`  // Time step of the last code used, codes cannot be reused
 }
 
 type PublicUser struct {
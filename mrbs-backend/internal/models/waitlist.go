@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Waitlist entry statuses
+const (
+	WaitlistWaiting   = "waiting"   // Waiting for the slot to be freed
+	WaitlistOffered   = "offered"   // Slot is free and has been offered to the user until OfferExpiresAt
+	WaitlistClaimed   = "claimed"   // User has booked the slot, see BookingID
+	WaitlistExpired   = "expired"   // Offer was not claimed in time
+	WaitlistCancelled = "cancelled" // User left the waitlist
+)
+
+// WaitlistEntry - a user waiting for a room to be freed for a specific time range.
+type WaitlistEntry struct {
+	WaitlistID     uint       `gorm:"column:waitlist_id; primaryKey" json:"waitlist_id"`
+	UserID         uint       `gorm:"column:user_id" json:"user_id"`
+	RoomID         uint       `gorm:"column:room_id" json:"room_id"`
+	StartTime      time.Time  `gorm:"column:start_time" json:"start_time"`
+	EndTime        time.Time  `gorm:"column:end_time" json:"end_time"`
+	Title          string     `gorm:"column:title" json:"title"` // Title of the booking made when the offer is claimed
+	Status         string     `gorm:"column:status; default:waiting" json:"status"`
+	OfferedAt      *time.Time `gorm:"column:offered_at" json:"offered_at"`
+	OfferExpiresAt *time.Time `gorm:"column:offer_expires_at" json:"offer_expires_at"`
+	BookingID      *uint      `gorm:"column:booking_id" json:"booking_id"` // Set once claimed
+	TimeCreated    time.Time  `gorm:"column:time_created; default:now()" json:"time_created"`
+}
+
+func (WaitlistEntry) TableName() string {
+	return "mrbs.waitlist"
+}
@@ -0,0 +1,105 @@
+// Package email sends booking notifications by email, with an .ics attachment so that the booking
+// can be added to, updated in or removed from the user's calendar.
+package email
+
+import (
+	"context"
+	"fmt"
+
+	"rep-mrbs/internal/booking"
+	"rep-mrbs/internal/constants"
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/ical"
+	"rep-mrbs/internal/mail"
+	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+
+	"gorm.io/gorm"
+)
+
+// Notifier implements notify.Notifier. Register it once at startup.
+type Notifier struct{}
+
+// bookingData is passed to the booking templates.
+type bookingData struct {
+	Name           string
+	Actor          string // Display name of the user who made the change, empty if it was the recipient
+	Bookings       []bookingLine
+	CheckInMinutes int
+	URL            string
+}
+
+type bookingLine struct {
+	Title, Room, Date, Start, End string
+}
+
+func (Notifier) Name() string {
+	return "email"
+}
+
+func (Notifier) Notify(ctx context.Context, event notify.Event) error {
+	if !mail.Enabled() {
+		return nil
+	}
+
+	var templateName, method, status string
+	switch event.Kind {
+	case notify.KindBookingCreated:
+		templateName, method, status = mail.TemplateBookingCreated, ical.MethodRequest, ical.StatusConfirmed
+	case notify.KindBookingEdited:
+		templateName, method, status = mail.TemplateBookingEdited, ical.MethodRequest, ical.StatusConfirmed
+	case notify.KindBookingDeleted:
+		templateName, method, status = mail.TemplateBookingDeleted, ical.MethodCancel, ical.StatusCancelled
+	default:
+		return nil
+	}
+	if len(event.Bookings) == 0 {
+		return nil
+	}
+
+	user, err := gorm.G[models.User](db.GormDB).Where("user_id = ?", event.UserID).Take(ctx)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	data := bookingData{
+		Name:           user.DisplayName,
+		CheckInMinutes: int(booking.CheckInGracePeriod.Minutes()),
+		URL:            constants.MRBSWebsiteURL,
+	}
+	if event.ActorID != 0 && event.ActorID != event.UserID {
+		actor, err := gorm.G[models.User](db.GormDB).Where("user_id = ?", event.ActorID).Take(ctx)
+		if err != nil {
+			return err
+		}
+		data.Actor = actor.DisplayName
+	}
+
+	events := make([]ical.Event, 0, len(event.Bookings))
+	for _, b := range event.Bookings {
+		data.Bookings = append(data.Bookings, bookingLine{
+			Title: b.Title,
+			Room:  models.GetRoomNameFromID(int(b.RoomID)),
+			Date:  b.StartTime.Format("Mon, 02 Jan 2006"),
+			Start: b.StartTime.Format("15:04"),
+			End:   b.EndTime.Format("15:04"),
+		})
+		events = append(events, ical.FromBooking(&b, status))
+	}
+
+	msg, err := mail.Render(templateName, data)
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+	msg.Attachments = []mail.Attachment{{
+		Filename:    "booking.ics",
+		ContentType: fmt.Sprintf("text/calendar; charset=UTF-8; method=%s", method),
+		Data:        ical.Calendar(method, "", events),
+	}}
+
+	return mail.Send(ctx, msg)
+}
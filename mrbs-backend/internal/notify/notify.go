@@ -0,0 +1,66 @@
+// Package notify delivers events (e.g. booking confirmations, waitlist offers) to users through every registered channel.
+// Channels such as the Telegram bot register a Notifier at startup, so that the service logic in
+// the booking package does not depend on them.
+package notify
+
+import (
+	"context"
+	"sync"
+
+	"rep-mrbs/internal/models"
+
+	"github.com/rs/zerolog/log"
+)
+
+type Kind string
+
+const (
+	KindWaitlistOffer   Kind = "waitlist_offer"   // A waitlisted slot is free and can be claimed until the offer expires
+	KindBookingCreated  Kind = "booking_created"  // Bookings holds the new booking, or every occurrence of a new series
+	KindBookingEdited   Kind = "booking_edited"   // Bookings holds the bookings after the edit
+	KindBookingDeleted  Kind = "booking_deleted"  // Only sent when the bookings were deleted by someone other than the owner
+	KindBookingReminder Kind = "booking_reminder" // Bookings holds the booking that is about to start
+)
+
+type Event struct {
+	Kind     Kind
+	UserID   uint // Recipient
+	ActorID  uint // User who made the change, 0 if it was made by the system
+	Bookings []models.Booking
+	Waitlist *models.WaitlistEntry
+}
+
+// Notifier delivers events through one channel. Implementations should skip users they cannot reach.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+var (
+	notifiers []Notifier
+	mu        sync.RWMutex
+)
+
+// Register adds a channel. Called once per channel at startup.
+func Register(n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifiers = append(notifiers, n)
+	log.Info().Str("notifier", n.Name()).Msg("Notifier registered")
+}
+
+// Dispatch sends the event through every registered channel in the background, so that a slow or
+// failing channel does not hold up the request that triggered it. Errors are logged.
+func Dispatch(event Event) {
+	mu.RLock()
+	targets := notifiers
+	mu.RUnlock()
+
+	for _, n := range targets {
+		go func() {
+			if err := n.Notify(context.Background(), event); err != nil {
+				log.Error().Err(err).Str("notifier", n.Name()).Str("kind", string(event.Kind)).Uint("user_id", event.UserID).Msg("Error sending notification")
+			}
+		}()
+	}
+}
@@ -0,0 +1,349 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "rep-mrbs"
+	testClientSecret = "secret"
+	testRedirectURL  = "http://localhost:8080/api/auth/oidc/callback"
+	rsaKeyID         = "rsa-key"
+	ecKeyID          = "ec-key"
+)
+
+// mockIdP is an identity provider serving discovery, JWKS and the token endpoint. The ID token returned for a
+// code is set by the test, the token endpoint checks the client and the PKCE verifier like a real provider.
+type mockIdP struct {
+	server *httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mu        sync.Mutex
+	codes     map[string]mockCode
+	verifiers []string // code_verifier of every token request
+}
+
+type mockCode struct {
+	challenge string
+	idToken   string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdP{rsaKey: rsaKey, ecKey: ecKey, codes: map[string]mockCode{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.handleDiscovery)
+	mux.HandleFunc("/jwks", idp.handleJWKS)
+	mux.HandleFunc("/token", idp.handleToken)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (p *mockIdP) issuer() string {
+	return p.server.URL
+}
+
+func (p *mockIdP) provider() *Provider {
+	return NewProvider(Config{
+		IssuerURL:    p.issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+}
+
+func (p *mockIdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.issuer(),
+		"authorization_endpoint": p.issuer() + "/authorize",
+		"token_endpoint":         p.issuer() + "/token",
+		"jwks_uri":               p.issuer() + "/jwks",
+	})
+}
+
+func (p *mockIdP) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	writeTestJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{
+		{
+			"kid": rsaKeyID, "kty": "RSA", "use": "sig", "alg": "RS256",
+			"n": encode(p.rsaKey.N.Bytes()),
+			"e": encode(big.NewInt(int64(p.rsaKey.E)).Bytes()),
+		},
+		{
+			"kid": ecKeyID, "kty": "EC", "use": "sig", "alg": "ES256", "crv": "P-256",
+			"x": encode(p.ecKey.X.FillBytes(make([]byte, 32))),
+			"y": encode(p.ecKey.Y.FillBytes(make([]byte, 32))),
+		},
+	}})
+}
+
+func (p *mockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "authorization_code" {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != testClientSecret {
+		writeTestJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	verifier := r.Form.Get("code_verifier")
+	p.mu.Lock()
+	p.verifiers = append(p.verifiers, verifier)
+	code, ok := p.codes[r.Form.Get("code")]
+	delete(p.codes, r.Form.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || r.Form.Get("redirect_uri") != testRedirectURL || CodeChallenge(verifier) != code.challenge {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeTestJSON(w, http.StatusOK, map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": code.idToken})
+}
+
+// authorize plays the user signing in: it reads the PKCE challenge from the authorization URL and returns a code
+// that the token endpoint exchanges for idToken.
+func (p *mockIdP) authorize(t *testing.T, authURL string, idToken string) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL has no S256 PKCE challenge: %s", authURL)
+	}
+	if query.Get("client_id") != testClientID || query.Get("redirect_uri") != testRedirectURL {
+		t.Fatalf("authorization URL has the wrong client: %s", authURL)
+	}
+
+	code, err := RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.codes[code] = mockCode{challenge: query.Get("code_challenge"), idToken: idToken}
+	p.mu.Unlock()
+	return code
+}
+
+// sign returns a JWT with the given claims, signed with the key of alg.
+func (p *mockIdP) sign(t *testing.T, alg string, kid string, claims map[string]any) string {
+	t.Helper()
+
+	encode := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := encode(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, p.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		t.Fatalf("unsupported algorithm %q", alg)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// validClaims returns the claims of an ID token that Exchange accepts for nonce.
+func validClaims(issuer string, nonce string) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":            issuer,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newMockIdP(t)
+
+	tests := []struct {
+		name    string
+		alg     string
+		kid     string
+		claims  func(claims map[string]any)
+		tamper  bool
+		wantErr string
+	}{
+		{name: "valid RS256", alg: "RS256", kid: rsaKeyID},
+		{name: "valid ES256", alg: "ES256", kid: ecKeyID},
+		{
+			name: "audience array with azp", alg: "RS256", kid: rsaKeyID,
+			claims: func(c map[string]any) { c["aud"] = []string{testClientID, "other"}; c["azp"] = testClientID },
+		},
+		{
+			name: "wrong issuer", alg: "RS256", kid: rsaKeyID,
+			claims:  func(c map[string]any) { c["iss"] = "https://evil.example.com" },
+			wantErr: "issuer",
+		},
+		{
+			name: "wrong audience", alg: "RS256", kid: rsaKeyID,
+			claims:  func(c map[string]any) { c["aud"] = "another-client" },
+			wantErr: "not meant for this client",
+		},
+		{
+			name: "audience array without azp", alg: "ES256", kid: ecKeyID,
+			claims:  func(c map[string]any) { c["aud"] = []string{testClientID, "other"} },
+			wantErr: "azp",
+		},
+		{
+			name: "expired", alg: "RS256", kid: rsaKeyID,
+			claims:  func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: "expired",
+		},
+		{
+			name: "expired within clock skew", alg: "ES256", kid: ecKeyID,
+			claims: func(c map[string]any) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		},
+		{
+			name: "bad nonce", alg: "ES256", kid: ecKeyID,
+			claims:  func(c map[string]any) { c["nonce"] = "replayed-nonce" },
+			wantErr: "nonce",
+		},
+		{name: "unknown kid", alg: "RS256", kid: "rotated-key", wantErr: "unknown signing key"},
+		{name: "tampered RS256 signature", alg: "RS256", kid: rsaKeyID, tamper: true, wantErr: "invalid signature"},
+		{name: "tampered ES256 signature", alg: "ES256", kid: ecKeyID, tamper: true, wantErr: "invalid signature"},
+		{name: "algorithm does not match key", alg: "ES256", kid: rsaKeyID, wantErr: "does not match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			provider := idp.provider()
+
+			nonce, _ := RandomString()
+			verifier, _ := RandomString()
+			authURL, err := provider.AuthCodeURL(ctx, "state", nonce, verifier)
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+
+			claims := validClaims(idp.issuer(), nonce)
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+			token := idp.sign(t, tt.alg, tt.kid, claims)
+			if tt.tamper {
+				// Change the claims but keep the signature of the original claims
+				claims["email"] = "admin@example.com"
+				forged := idp.sign(t, tt.alg, tt.kid, claims)
+				parts, forgedParts := strings.Split(token, "."), strings.Split(forged, ".")
+				token = parts[0] + "." + forgedParts[1] + "." + parts[2]
+			}
+
+			got, err := provider.Exchange(ctx, idp.authorize(t, authURL, token), verifier, nonce)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("expected an error containing %q, got claims %+v", tt.wantErr, got)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if got.Subject != "user-123" || got.Email != "alice@example.com" || got.Name != "Alice" {
+				t.Fatalf("unexpected claims %+v", got)
+			}
+			if got.EmailVerified == nil || !*got.EmailVerified {
+				t.Fatalf("expected email_verified to be true, got %v", got.EmailVerified)
+			}
+		})
+	}
+}
+
+func TestExchangeSendsPKCEVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+	ctx := context.Background()
+
+	nonce, _ := RandomString()
+	verifier, _ := RandomString()
+	authURL, err := provider.AuthCodeURL(ctx, "state", nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	token := idp.sign(t, "RS256", rsaKeyID, validClaims(idp.issuer(), nonce))
+
+	if _, err = provider.Exchange(ctx, idp.authorize(t, authURL, token), verifier, nonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if len(idp.verifiers) != 1 || idp.verifiers[0] != verifier {
+		t.Fatalf("expected the token endpoint to receive verifier %q, got %q", verifier, idp.verifiers)
+	}
+
+	// A verifier that does not match the challenge of the authorization request is rejected by the provider
+	other, _ := RandomString()
+	_, err = provider.Exchange(ctx, idp.authorize(t, authURL, token), other, nonce)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("expected invalid_grant for a wrong verifier, got %v", err)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// BASE64URL(SHA256(verifier)) without padding, RFC 7636 section 4.2
+	got := CodeChallenge("rep-mrbs-pkce-verifier")
+	if want := "35F3iLIk_kxE8ucl75ajdC3g2cRiPOcP3XZukdzzbxQ"; got != want {
+		t.Fatalf("CodeChallenge = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret - the SHA-1 secret of RFC 6238 Appendix B, "12345678901234567890" base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes, the 6-digit codes are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d: expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	tests := []struct {
+		name   string
+		step   int64
+		wantOK bool
+	}{
+		{"previous step", current - 1, true},
+		{"current step", current, true},
+		{"next step", current + 1, true},
+		{"two steps behind", current - 2, false},
+		{"two steps ahead", current + 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := Validate(rfcSecret, code, now, 0)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok %v, got %v", tt.wantOK, ok)
+			}
+			if ok && step != tt.step {
+				t.Fatalf("expected step %d, got %d", tt.step, step)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsReplayedStep(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := Code(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := Validate(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("expected the code to be accepted the first time")
+	}
+	if _, ok = Validate(rfcSecret, code, now, step); ok {
+		t.Fatal("expected the code to be rejected once its step has been used")
+	}
+
+	// A code from before the last used step is rejected too, even within the skew window
+	earlier, err := Code(rfcSecret, step-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = Validate(rfcSecret, earlier, now, step); ok {
+		t.Fatal("expected a code older than the last used step to be rejected")
+	}
+
+	// The next step is still accepted
+	next, err := Code(rfcSecret, step+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = Validate(rfcSecret, next, now, step); !ok {
+		t.Fatal("expected the code of the next step to be accepted")
+	}
+}
+
+func TestValidateFormatting(t *testing.T) {
+	now := time.Unix(59, 0)
+	if _, ok := Validate(rfcSecret, " 287 082 ", now, 0); !ok {
+		t.Fatal("expected spaces around and inside the code to be ignored")
+	}
+	if _, ok := Validate(rfcSecret, "28708", now, 0); ok {
+		t.Fatal("expected a code with too few digits to be rejected")
+	}
+}
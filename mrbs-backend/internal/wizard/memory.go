@@ -0,0 +1,77 @@
+package wizard
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"rep-mrbs/internal/models"
+)
+
+// MemoryStore keeps wizards in memory, for tests and local development without the database. Wizards are lost
+// when the process stops.
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	states map[int64]models.BookingState
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, states: make(map[int64]models.BookingState)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, chatID int64) (*models.BookingState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[chatID]
+	if !ok || !state.UpdatedAt.After(time.Now().Add(-s.ttl)) {
+		return nil, ErrNotFound
+	}
+	// A copy, changes only take effect once saved, same as with the database
+	return &state, nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, state *models.BookingState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state.UpdatedAt = time.Now()
+	s.states[state.ChatID] = *state
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, chatID)
+	return nil
+}
+
+func (s *MemoryStore) ListExpired(_ context.Context) ([]models.BookingState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.ttl)
+	var expired []models.BookingState
+	for _, state := range s.states {
+		if !state.UpdatedAt.After(cutoff) {
+			expired = append(expired, state)
+		}
+	}
+	return expired, nil
+}
+
+func (s *MemoryStore) DeleteExpired(_ context.Context, chatID int64) (*models.BookingState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[chatID]
+	if !ok || state.UpdatedAt.After(time.Now().Add(-s.ttl)) {
+		return nil, ErrNotFound
+	}
+	delete(s.states, chatID)
+	return &state, nil
+}
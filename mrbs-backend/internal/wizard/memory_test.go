@@ -0,0 +1,136 @@
+package wizard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rep-mrbs/internal/models"
+)
+
+const testTTL = 50 * time.Millisecond
+
+func TestMemoryStoreGet(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(testTTL)
+
+	if _, err := store.Get(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a chat without a wizard, got %v", err)
+	}
+
+	if err := store.Save(ctx, &models.BookingState{ChatID: 1, MessageID: 10, Step: 2}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if s.MessageID != 10 || s.Step != 2 {
+		t.Fatalf("unexpected wizard %+v", s)
+	}
+
+	// Changes to the returned wizard only take effect once saved
+	s.Step = 4
+	if s, _ = store.Get(ctx, 1); s.Step != 2 {
+		t.Fatalf("expected the stored wizard to be unchanged, got step %d", s.Step)
+	}
+
+	time.Sleep(testTTL + 10*time.Millisecond)
+	if _, err = store.Get(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound once the TTL has passed, got %v", err)
+	}
+}
+
+func TestMemoryStoreSaveRestartsTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(testTTL)
+
+	s := &models.BookingState{ChatID: 1}
+	if err := store.Save(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(testTTL * 3 / 5)
+
+	if err := store.Save(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(testTTL * 3 / 5)
+
+	// More than the TTL since the first save, less since the second
+	if _, err := store.Get(ctx, 1); err != nil {
+		t.Fatalf("expected the wizard to be kept after it was saved again, got %v", err)
+	}
+	if expired, _ := store.ListExpired(ctx); len(expired) != 0 {
+		t.Fatalf("expected no expired wizards, got %d", len(expired))
+	}
+}
+
+func TestMemoryStoreDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(testTTL)
+
+	for _, chatID := range []int64{1, 2} {
+		if err := store.Save(ctx, &models.BookingState{ChatID: chatID, MessageID: int(chatID) * 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.DeleteExpired(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a wizard within the TTL to be kept, got %v", err)
+	}
+
+	time.Sleep(testTTL + 10*time.Millisecond)
+	if err := store.Save(ctx, &models.BookingState{ChatID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := store.ListExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 2 {
+		t.Fatalf("expected chats 1 and 2 to have expired, got %+v", expired)
+	}
+
+	// Chat 2 is used again between listing and deleting, it must be kept
+	if err = store.Save(ctx, &models.BookingState{ChatID: 2, MessageID: 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := store.DeleteExpired(ctx, 1)
+	if err != nil {
+		t.Fatalf("DeleteExpired: %v", err)
+	}
+	if s.MessageID != 10 {
+		t.Fatalf("expected the deleted wizard to be returned, got %+v", s)
+	}
+	if _, err = store.DeleteExpired(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a wizard already deleted, got %v", err)
+	}
+	if _, err = store.DeleteExpired(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a wizard saved again to be kept, got %v", err)
+	}
+	for _, chatID := range []int64{2, 3} {
+		if _, err = store.Get(ctx, chatID); err != nil {
+			t.Fatalf("expected chat %d to keep its wizard, got %v", chatID, err)
+		}
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(testTTL)
+
+	if err := store.Delete(ctx, 1); err != nil {
+		t.Fatalf("deleting a missing wizard: %v", err)
+	}
+	if err := store.Save(ctx, &models.BookingState{ChatID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after Delete, got %v", err)
+	}
+}
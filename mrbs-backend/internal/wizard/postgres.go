@@ -0,0 +1,64 @@
+package wizard
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rep-mrbs/internal/db"
+	"rep-mrbs/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore keeps wizards in mrbs.telegram_wizards, so that they survive restarts and deploys.
+type PostgresStore struct {
+	ttl time.Duration
+}
+
+func NewPostgresStore(ttl time.Duration) *PostgresStore {
+	return &PostgresStore{ttl: ttl}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, chatID int64) (*models.BookingState, error) {
+	state, err := gorm.G[models.BookingState](db.GormDB).
+		Where("chat_id = ? AND updated_at > ?", chatID, time.Now().Add(-s.ttl)).
+		Take(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (s *PostgresStore) Save(ctx context.Context, state *models.BookingState) error {
+	state.UpdatedAt = time.Now()
+	return gorm.G[models.BookingState](db.GormDB, clause.OnConflict{UpdateAll: true}).Create(ctx, state)
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, chatID int64) error {
+	_, err := gorm.G[models.BookingState](db.GormDB).Where("chat_id = ?", chatID).Delete(ctx)
+	return err
+}
+
+func (s *PostgresStore) ListExpired(ctx context.Context) ([]models.BookingState, error) {
+	return gorm.G[models.BookingState](db.GormDB).
+		Where("updated_at <= ?", time.Now().Add(-s.ttl)).
+		Find(ctx)
+}
+
+func (s *PostgresStore) DeleteExpired(ctx context.Context, chatID int64) (*models.BookingState, error) {
+	var expired []models.BookingState
+	err := db.GormDB.WithContext(ctx).Raw("DELETE FROM mrbs.telegram_wizards WHERE chat_id = ? AND updated_at <= ? RETURNING *", chatID, time.Now().Add(-s.ttl)).
+		Scan(&expired).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return nil, ErrNotFound
+	}
+	return &expired[0], nil
+}
@@ -0,0 +1,92 @@
+// Package wizard keeps the progress of the Telegram booking wizard between updates, so that half-finished bookings
+// survive restarts and abandoned ones expire.
+package wizard
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"rep-mrbs/internal/models"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+// TTL - time after which a wizard that has not been touched is discarded. Set with TELEGRAM_WIZARD_TTL (minutes)
+// in config/.env (default 30).
+var TTL = 30 * time.Minute
+
+// ErrNotFound - the chat has no wizard in progress, or it has expired.
+var ErrNotFound = errors.New("no booking wizard in progress")
+
+func init() {
+	_ = godotenv.Load("./config/.env")
+
+	n, err := strconv.Atoi(os.Getenv("TELEGRAM_WIZARD_TTL"))
+	if err != nil || n <= 0 {
+		log.Warn().Dur("default", TTL).Msg("TELEGRAM_WIZARD_TTL not set in /config/.env, using default.")
+		return
+	}
+	TTL = time.Duration(n) * time.Minute
+}
+
+// Store keeps one wizard per chat. Implementations are safe for concurrent use, but a wizard that is loaded, changed
+// and saved again should be locked with Lock for the whole update.
+type Store interface {
+	// Get returns the wizard of the chat, or ErrNotFound.
+	Get(ctx context.Context, chatID int64) (*models.BookingState, error)
+
+	// Save creates or replaces the wizard of state.ChatID and restarts its TTL.
+	Save(ctx context.Context, state *models.BookingState) error
+
+	// Delete removes the wizard of the chat. Deleting a wizard that does not exist is not an error.
+	Delete(ctx context.Context, chatID int64) error
+
+	// ListExpired returns the wizards that have not been saved within the TTL, without removing them.
+	ListExpired(ctx context.Context) ([]models.BookingState, error)
+
+	// DeleteExpired removes the wizard of the chat if it has still not been saved within the TTL, and returns it so
+	// that its message can be cleaned up. Returns ErrNotFound if the wizard is gone or has been saved since. Hold
+	// Lock for the chat, so that a wizard loaded by an update is not removed before the update saves it.
+	DeleteExpired(ctx context.Context, chatID int64) (*models.BookingState, error)
+}
+
+var (
+	locksMu sync.Mutex
+	locks   = make(map[int64]*chatLock)
+)
+
+type chatLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock serialises the updates of a chat, so that two updates handled at the same time (e.g. a double tap on a
+// button) cannot overwrite each other's changes to the wizard. Call the returned function to unlock.
+// Telegram sends every update to one webhook, so locking within the process is enough.
+func Lock(chatID int64) (unlock func()) {
+	locksMu.Lock()
+	l, ok := locks[chatID]
+	if !ok {
+		l = &chatLock{}
+		locks[chatID] = l
+	}
+	l.refs++
+	locksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		locksMu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(locks, chatID)
+		}
+		locksMu.Unlock()
+	}
+}
@@ -0,0 +1,95 @@
+package wizard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rep-mrbs/internal/models"
+)
+
+func TestLockSerialisesChat(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(time.Minute)
+	if err := store.Save(ctx, &models.BookingState{ChatID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every update loads the wizard, waits a little and saves it one step further. Without the lock, updates
+	// would overwrite each other's steps.
+	const updates = 20
+	var wg sync.WaitGroup
+	for range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := Lock(1)
+			defer unlock()
+
+			s, err := store.Get(ctx, 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(time.Millisecond)
+			s.Step++
+			_ = store.Save(ctx, s)
+		}()
+	}
+	wg.Wait()
+
+	s, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Step != updates {
+		t.Fatalf("expected step %d, got %d", updates, s.Step)
+	}
+
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	if len(locks) != 0 {
+		t.Fatalf("expected the locks to be released, %d left", len(locks))
+	}
+}
+
+func TestLockDoesNotBlockOtherChats(t *testing.T) {
+	unlock := Lock(1)
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		Lock(2)()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("locking another chat blocked")
+	}
+}
+
+func TestLockBlocksSameChat(t *testing.T) {
+	unlock := Lock(1)
+
+	locked := make(chan struct{})
+	go func() {
+		Lock(1)()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("chat was locked twice at once")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("lock was not handed over after unlock")
+	}
+}
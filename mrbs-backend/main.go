@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"io"
 	"io/fs"
@@ -9,12 +10,19 @@ import (
 	"strings"
 
 	"rep-mrbs/internal/api"
+	"rep-mrbs/internal/api/areas"
+	"rep-mrbs/internal/api/auditlog"
 	"rep-mrbs/internal/api/auth"
 	"rep-mrbs/internal/api/bookings"
+	"rep-mrbs/internal/api/calendar"
+	"rep-mrbs/internal/api/rooms"
 	"rep-mrbs/internal/api/telegram"
 	"rep-mrbs/internal/api/users"
+	"rep-mrbs/internal/booking"
 	"rep-mrbs/internal/db"
 	"rep-mrbs/internal/models"
+	"rep-mrbs/internal/notify"
+	"rep-mrbs/internal/notify/email"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -39,6 +47,16 @@ func main() {
 
 	// Set up cache
 	models.InitRooms()
+	models.InitAreas()
+
+	// Email notifications for bookings
+	notify.Register(email.Notifier{})
+
+	// Background jobs
+	booking.StartNoShowJob(context.Background())
+	booking.StartWaitlistJob(context.Background())
+	booking.StartPurgeJob(context.Background())
+	booking.StartReminderJob(context.Background())
 
 	// API routes
 	apiGroup := router.Group("/api")
@@ -55,10 +73,25 @@ func main() {
 	bookingGroup := apiGroup.Group("/bookings")
 	bookings.RegisterBookingRoutes(bookingGroup)
 
+	// Calendar feed routes
+	calendarGroup := apiGroup.Group("/calendar")
+	calendar.RegisterCalendarRoutes(calendarGroup)
+
 	// User routes
 	userGroup := apiGroup.Group("/users", api.AuthGuard(2))
 	users.RegisterUserRoutes(userGroup)
 
+	// Room and area routes
+	roomGroup := apiGroup.Group("/rooms", api.AuthGuard(2))
+	rooms.RegisterRoomRoutes(roomGroup)
+
+	areaGroup := apiGroup.Group("/areas", api.AuthGuard(2))
+	areas.RegisterAreaRoutes(areaGroup)
+
+	// Audit log of privileged changes
+	auditGroup := apiGroup.Group("/audit", api.AuthGuard(2))
+	auditlog.RegisterAuditLogRoutes(auditGroup)
+
 	// Static routes
 	distFS, _ := fs.Sub(staticFiles, "dist")
 	router.Use(func(c *gin.Context) {
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mrbs.booking_series
+(
+    series_id serial NOT NULL,
+    user_id integer NOT NULL,
+    room_id integer NOT NULL,
+    frequency text NOT NULL,
+    rep_interval integer NOT NULL DEFAULT 1,
+    rep_until timestamp with time zone,
+    rep_count integer,
+    start_time timestamp with time zone NOT NULL,
+    end_time timestamp with time zone NOT NULL,
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (series_id),
+    CONSTRAINT check_frequency CHECK (frequency IN ('daily', 'weekly')),
+    CONSTRAINT fk_users_booking_series FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_rooms_booking_series FOREIGN KEY (room_id)
+        REFERENCES mrbs.rooms (room_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+ALTER TABLE mrbs.bookings ADD series_id integer NULL
+    REFERENCES mrbs.booking_series (series_id) ON DELETE SET NULL;
+
+CREATE INDEX idx_bookings_series_id ON mrbs.bookings (series_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mrbs.bookings DROP COLUMN IF EXISTS series_id;
+DROP TABLE IF EXISTS mrbs.booking_series;
+-- +goose StatementEnd
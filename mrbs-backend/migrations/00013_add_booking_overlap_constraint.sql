@@ -0,0 +1,45 @@
+-- Enforce in the database that a room can never be double booked, regardless of which code path creates
+-- the booking. Clash checks in the application still run first so that users get a meaningful error;
+-- this constraint only catches bookings that race past the checks in concurrent transactions.
+-- Booking service translates violations (SQLSTATE 23P01) into ErrRoomClash, keep the constraint name in sync.
+--
+-- The constraint cannot be added while double bookings exist. The migration then fails without changing
+-- anything and lists the clashing pairs as (room_id, booking_id, booking_id). To remediate, decide with the
+-- users concerned which booking of each pair keeps the room, then delete or move the other one, e.g.
+--     DELETE FROM mrbs.bookings WHERE booking_id = <booking id>;
+--     UPDATE mrbs.bookings SET room_id = <free room id> WHERE booking_id = <booking id>;
+-- and run the migration again. The query in the DO block below lists the remaining clashes.
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+DO $$
+DECLARE
+    clashes text;
+BEGIN
+    SELECT string_agg(format('(%s, %s, %s)', a.room_id, a.booking_id, b.booking_id), ', '
+                      ORDER BY a.room_id, a.booking_id, b.booking_id)
+    INTO clashes
+    FROM mrbs.bookings a
+    JOIN mrbs.bookings b
+        ON a.room_id = b.room_id
+        AND a.booking_id < b.booking_id
+        AND tstzrange(a.start_time, a.end_time, '[)') && tstzrange(b.start_time, b.end_time, '[)');
+
+    IF clashes IS NOT NULL THEN
+        RAISE EXCEPTION 'mrbs.bookings has overlapping bookings, resolve them before adding no_overlapping_bookings'
+            USING DETAIL = 'Clashing (room_id, booking_id, booking_id): ' || clashes,
+                HINT = 'See the header of 00013_add_booking_overlap_constraint.sql for how to resolve them.';
+    END IF;
+END
+$$;
+
+ALTER TABLE mrbs.bookings
+    ADD CONSTRAINT no_overlapping_bookings
+    EXCLUDE USING gist (room_id WITH =, tstzrange(start_time, end_time, '[)') WITH &&);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mrbs.bookings DROP CONSTRAINT IF EXISTS no_overlapping_bookings;
+-- +goose StatementEnd
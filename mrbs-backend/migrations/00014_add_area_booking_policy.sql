@@ -0,0 +1,20 @@
+-- Booking policy is read from mrbs.areas (see internal/models/area.go).
+-- max_per_day and max_per_week are expressed in HOURS of bookings per user.
+-- max_per_day of the default area is set to 3 to match the limit that was previously hardcoded.
+-- max_per_week of the default area was seeded as 5 when it was not read, set it to NULL (no weekly limit) so that
+-- enforcing weekly quotas does not introduce a 5 hours per week cap. Admins can set a limit in hours per area.
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE mrbs.areas ADD buffer_duration interval NOT NULL DEFAULT '4 H';
+
+UPDATE mrbs.areas SET max_per_day = 3 WHERE display_name = 'REP North Hill Rooms';
+UPDATE mrbs.areas SET max_per_week = NULL WHERE display_name = 'REP North Hill Rooms' AND max_per_week = 5;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE mrbs.areas SET max_per_week = 5 WHERE display_name = 'REP North Hill Rooms' AND max_per_week IS NULL;
+UPDATE mrbs.areas SET max_per_day = 2 WHERE display_name = 'REP North Hill Rooms';
+
+ALTER TABLE mrbs.areas DROP COLUMN IF EXISTS buffer_duration;
+-- +goose StatementEnd
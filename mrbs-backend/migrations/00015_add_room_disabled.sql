@@ -0,0 +1,10 @@
+-- Rooms are disabled rather than deleted, deleting a room cascades to its bookings.
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE mrbs.rooms ADD disabled boolean NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mrbs.rooms DROP COLUMN IF EXISTS disabled;
+-- +goose StatementEnd
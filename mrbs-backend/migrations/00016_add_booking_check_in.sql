@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE mrbs.bookings ADD checked_in_at timestamp with time zone NULL;
+ALTER TABLE mrbs.bookings ADD released_at timestamp with time zone NULL; -- Set when a no-show booking has been shortened
+
+CREATE TABLE mrbs.no_shows
+(
+    no_show_id serial NOT NULL,
+    user_id integer NOT NULL,
+    booking_id integer,
+    room_id integer NOT NULL,
+    start_time timestamp with time zone NOT NULL,
+    released_minutes integer NOT NULL DEFAULT 0,
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (no_show_id),
+    CONSTRAINT fk_users_no_shows FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_bookings_no_shows FOREIGN KEY (booking_id)
+        REFERENCES mrbs.bookings (booking_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_no_shows_user_id ON mrbs.no_shows (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.no_shows;
+ALTER TABLE mrbs.bookings DROP COLUMN IF EXISTS released_at;
+ALTER TABLE mrbs.bookings DROP COLUMN IF EXISTS checked_in_at;
+-- +goose StatementEnd
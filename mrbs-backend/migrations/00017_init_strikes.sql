@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mrbs.strikes
+(
+    strike_id serial NOT NULL,
+    user_id integer NOT NULL,
+    no_show_id integer,
+    reason text NOT NULL,
+    created_by integer, -- NULL: recorded automatically by the no-show job
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    cleared_at timestamp with time zone,
+    cleared_by integer,
+    PRIMARY KEY (strike_id),
+    CONSTRAINT fk_users_strikes FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_no_shows_strikes FOREIGN KEY (no_show_id)
+        REFERENCES mrbs.no_shows (no_show_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE SET NULL,
+    CONSTRAINT fk_users_strikes_created_by FOREIGN KEY (created_by)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE SET NULL,
+    CONSTRAINT fk_users_strikes_cleared_by FOREIGN KEY (cleared_by)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_strikes_user_id ON mrbs.strikes (user_id, time_created);
+
+-- Existing no-shows count as strikes
+INSERT INTO mrbs.strikes (user_id, no_show_id, reason, time_created)
+SELECT user_id, no_show_id, 'no-show', time_created FROM mrbs.no_shows;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.strikes;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mrbs.waitlist
+(
+    waitlist_id serial NOT NULL,
+    user_id integer NOT NULL,
+    room_id integer NOT NULL,
+    start_time timestamp with time zone NOT NULL,
+    end_time timestamp with time zone NOT NULL,
+    title text NOT NULL,
+    status text NOT NULL DEFAULT 'waiting',
+    offered_at timestamp with time zone,
+    offer_expires_at timestamp with time zone,
+    booking_id integer,
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (waitlist_id),
+    CONSTRAINT check_waitlist_status CHECK (status IN ('waiting', 'offered', 'claimed', 'expired', 'cancelled')),
+    CONSTRAINT check_waitlist_range CHECK (end_time > start_time),
+    CONSTRAINT fk_users_waitlist FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_rooms_waitlist FOREIGN KEY (room_id)
+        REFERENCES mrbs.rooms (room_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_bookings_waitlist FOREIGN KEY (booking_id)
+        REFERENCES mrbs.bookings (booking_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_waitlist_room_status ON mrbs.waitlist (room_id, status, start_time);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.waitlist;
+-- +goose StatementEnd
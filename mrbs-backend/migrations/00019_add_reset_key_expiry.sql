@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE mrbs.users ADD reset_key_expires_at timestamp with time zone NULL;
+-- reset_key_hash was never used, make sure no stale values can be used as tokens
+UPDATE mrbs.users SET reset_key_hash = NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mrbs.users DROP COLUMN IF EXISTS reset_key_expires_at;
+-- +goose StatementEnd
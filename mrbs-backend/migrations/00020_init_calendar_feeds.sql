@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mrbs.calendar_tokens
+(
+    user_id integer NOT NULL,
+    token_hash text NOT NULL,
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    last_used timestamp with time zone,
+    PRIMARY KEY (user_id),
+    CONSTRAINT unique_calendar_token_hash UNIQUE (token_hash),
+    CONSTRAINT fk_users_calendar_tokens FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+-- Deleted bookings are kept for a while so that calendar feeds can publish them as cancelled events
+CREATE TABLE mrbs.booking_tombstones
+(
+    booking_id integer NOT NULL,
+    user_id integer NOT NULL,
+    room_id integer NOT NULL,
+    start_time timestamp with time zone NOT NULL,
+    end_time timestamp with time zone NOT NULL,
+    title text NOT NULL,
+    ical_uid text NOT NULL,
+    ical_seq integer NOT NULL,
+    time_deleted timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (booking_id)
+);
+
+CREATE INDEX idx_booking_tombstones_time_deleted ON mrbs.booking_tombstones (time_deleted);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.booking_tombstones;
+DROP TABLE IF EXISTS mrbs.calendar_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Same UID the calendar feeds used for bookings without one, so subscribed calendars do not see new events
+UPDATE mrbs.bookings SET ical_uid = 'booking-' || booking_id || '@rep-mrbs' WHERE ical_uid IS NULL OR ical_uid = '';
+UPDATE mrbs.bookings SET ical_seq = 0 WHERE ical_seq IS NULL;
+
+ALTER TABLE mrbs.bookings ALTER COLUMN ical_uid SET NOT NULL;
+ALTER TABLE mrbs.bookings ALTER COLUMN ical_seq SET DEFAULT 0;
+ALTER TABLE mrbs.bookings ALTER COLUMN ical_seq SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_bookings_ical_uid ON mrbs.bookings (ical_uid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS mrbs.idx_bookings_ical_uid;
+
+ALTER TABLE mrbs.bookings ALTER COLUMN ical_seq DROP NOT NULL;
+ALTER TABLE mrbs.bookings ALTER COLUMN ical_seq DROP DEFAULT;
+ALTER TABLE mrbs.bookings ALTER COLUMN ical_uid DROP NOT NULL;
+-- +goose StatementEnd
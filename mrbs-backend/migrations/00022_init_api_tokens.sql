@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mrbs.api_tokens
+(
+    token_id serial NOT NULL,
+    user_id integer NOT NULL,
+    name text NOT NULL,
+    token_hash text NOT NULL,
+    scope text NOT NULL,
+    expires_at timestamp with time zone,
+    last_used timestamp with time zone,
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (token_id),
+    CONSTRAINT unique_api_token_hash UNIQUE (token_hash),
+    CONSTRAINT check_api_token_scope CHECK (scope IN ('bookings:read', 'bookings:write', 'admin')),
+    CONSTRAINT fk_users_api_tokens FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_tokens_user_id ON mrbs.api_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.api_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- session_key is secret, session_id identifies a session when listing and revoking sessions
+ALTER TABLE mrbs.sessions ADD session_id serial NOT NULL;
+ALTER TABLE mrbs.sessions ADD CONSTRAINT unique_session_id UNIQUE (session_id);
+ALTER TABLE mrbs.sessions ADD user_agent text NOT NULL DEFAULT '';
+ALTER TABLE mrbs.sessions ADD ip_address text NOT NULL DEFAULT '';
+ALTER TABLE mrbs.sessions ADD last_seen timestamp with time zone NOT NULL DEFAULT now();
+
+UPDATE mrbs.sessions SET last_seen = time_created;
+
+CREATE INDEX idx_sessions_user_id ON mrbs.sessions (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS mrbs.idx_sessions_user_id;
+ALTER TABLE mrbs.sessions DROP COLUMN IF EXISTS last_seen;
+ALTER TABLE mrbs.sessions DROP COLUMN IF EXISTS ip_address;
+ALTER TABLE mrbs.sessions DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE mrbs.sessions DROP COLUMN IF EXISTS session_id;
+-- +goose StatementEnd
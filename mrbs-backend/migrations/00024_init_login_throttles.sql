@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Failed login attempts per username ('user:<name>') and per client IP ('ip:<address>')
+CREATE TABLE mrbs.login_throttles
+(
+    throttle_key text NOT NULL,
+    failures integer NOT NULL DEFAULT 0,
+    last_failure timestamp with time zone NOT NULL DEFAULT now(),
+    blocked_until timestamp with time zone,
+    PRIMARY KEY (throttle_key)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.login_throttles;
+-- +goose StatementEnd
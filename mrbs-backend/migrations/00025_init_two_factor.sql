@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- totp_secret without totp_enabled_at is an enrolment that has not been confirmed yet
+ALTER TABLE mrbs.users ADD totp_secret text;
+ALTER TABLE mrbs.users ADD totp_enabled_at timestamp with time zone;
+ALTER TABLE mrbs.users ADD totp_last_step bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE mrbs.recovery_codes
+(
+    code_id serial NOT NULL,
+    user_id integer NOT NULL,
+    code_hash text NOT NULL,
+    used_at timestamp with time zone,
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (code_id),
+    CONSTRAINT fk_users_recovery_codes FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_recovery_codes_user_id ON mrbs.recovery_codes (user_id);
+
+-- Second step of a login, created once the password has been checked
+CREATE TABLE mrbs.login_challenges
+(
+    challenge_hash text NOT NULL,
+    user_id integer NOT NULL,
+    purpose text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    expires_at timestamp with time zone NOT NULL,
+    PRIMARY KEY (challenge_hash),
+    CONSTRAINT check_login_challenge_purpose CHECK (purpose IN ('verify', 'enrol')),
+    CONSTRAINT fk_users_login_challenges FOREIGN KEY (user_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.login_challenges;
+DROP TABLE IF EXISTS mrbs.recovery_codes;
+ALTER TABLE mrbs.users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE mrbs.users DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE mrbs.users DROP COLUMN IF EXISTS totp_secret;
+-- +goose StatementEnd
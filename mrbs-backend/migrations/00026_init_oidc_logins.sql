@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- SSO logins that have been sent to the identity provider and not come back yet
+CREATE TABLE mrbs.oidc_logins
+(
+    state_hash text NOT NULL,
+    nonce text NOT NULL,
+    code_verifier text NOT NULL,
+    redirect text NOT NULL DEFAULT '/',
+    expires_at timestamp with time zone NOT NULL,
+    PRIMARY KEY (state_hash)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.oidc_logins;
+-- +goose StatementEnd
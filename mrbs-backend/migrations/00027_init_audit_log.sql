@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Privileged changes made by admins. actor_name is kept so that entries stay readable after the admin is deleted.
+CREATE TABLE mrbs.audit_log
+(
+    audit_id bigserial NOT NULL,
+    actor_id integer,
+    actor_name text NOT NULL DEFAULT '',
+    action text NOT NULL,
+    target_type text NOT NULL,
+    target_id text NOT NULL DEFAULT '',
+    before jsonb,
+    after jsonb,
+    ip_address text NOT NULL DEFAULT '',
+    time_created timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (audit_id),
+    CONSTRAINT fk_users_audit_log FOREIGN KEY (actor_id)
+        REFERENCES mrbs.users (user_id) MATCH SIMPLE
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_audit_log_time_created ON mrbs.audit_log (time_created);
+CREATE INDEX idx_audit_log_actor_id ON mrbs.audit_log (actor_id);
+CREATE INDEX idx_audit_log_target ON mrbs.audit_log (target_type, target_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.audit_log;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- Deleted bookings are kept for a while so that mistaken deletions can be restored by an admin.
+-- They are purged by the booking service once DELETED_BOOKING_RETENTION_DAYS have passed.
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE mrbs.bookings ADD deleted_at timestamp with time zone NULL;
+ALTER TABLE mrbs.bookings ADD deleted_by integer NULL
+    REFERENCES mrbs.users (user_id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE mrbs.bookings ADD delete_reason text NOT NULL DEFAULT '';
+
+CREATE INDEX idx_bookings_deleted_at ON mrbs.bookings (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Deleted bookings no longer hold the room. Keep the constraint name in sync with the booking service.
+ALTER TABLE mrbs.bookings DROP CONSTRAINT no_overlapping_bookings;
+ALTER TABLE mrbs.bookings
+    ADD CONSTRAINT no_overlapping_bookings
+    EXCLUDE USING gist (room_id WITH =, tstzrange(start_time, end_time, '[)') WITH &&) WHERE (deleted_at IS NULL);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM mrbs.bookings WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE mrbs.bookings DROP CONSTRAINT IF EXISTS no_overlapping_bookings;
+ALTER TABLE mrbs.bookings
+    ADD CONSTRAINT no_overlapping_bookings
+    EXCLUDE USING gist (room_id WITH =, tstzrange(start_time, end_time, '[)') WITH &&);
+
+DROP INDEX IF EXISTS mrbs.idx_bookings_deleted_at;
+ALTER TABLE mrbs.bookings DROP COLUMN IF EXISTS delete_reason;
+ALTER TABLE mrbs.bookings DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE mrbs.bookings DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Progress of the Telegram booking wizard, one row per chat. Rows are removed when the booking is made,
+-- or by the bot once the wizard has not been touched for TELEGRAM_WIZARD_TTL minutes.
+CREATE TABLE mrbs.telegram_wizards
+(
+    chat_id bigint NOT NULL,
+    message_id integer NOT NULL DEFAULT 0,
+    step integer NOT NULL DEFAULT 0,
+    room_id integer NOT NULL DEFAULT 0,
+    start_time timestamp with time zone,
+    num_periods integer NOT NULL DEFAULT 0,
+    title text NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (chat_id)
+);
+
+CREATE INDEX idx_telegram_wizards_updated_at ON mrbs.telegram_wizards (updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mrbs.telegram_wizards;
+-- +goose StatementEnd
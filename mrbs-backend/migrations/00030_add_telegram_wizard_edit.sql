@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Set when the wizard edits an existing booking (Telegram /mine) instead of making a new one
+ALTER TABLE mrbs.telegram_wizards ADD edit_booking_id integer NULL
+    REFERENCES mrbs.bookings (booking_id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mrbs.telegram_wizards DROP COLUMN IF EXISTS edit_booking_id;
+-- +goose StatementEnd
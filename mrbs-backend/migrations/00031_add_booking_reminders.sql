@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Set when the Telegram reminder of the booking has been sent, so that it is only ever sent once
+ALTER TABLE mrbs.bookings ADD reminder_sent_at timestamp with time zone NULL;
+
+CREATE INDEX idx_bookings_reminder_due ON mrbs.bookings (start_time)
+    WHERE reminder_sent_at IS NULL AND deleted_at IS NULL;
+
+-- Minutes before a booking starts to send its reminder. NULL: default lead time, 0: reminders are off
+ALTER TABLE mrbs.telegram_auth ADD reminder_minutes integer NULL
+    CHECK (reminder_minutes BETWEEN 0 AND 1440);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mrbs.telegram_auth DROP COLUMN IF EXISTS reminder_minutes;
+DROP INDEX IF EXISTS mrbs.idx_bookings_reminder_due;
+ALTER TABLE mrbs.bookings DROP COLUMN IF EXISTS reminder_sent_at;
+-- +goose StatementEnd
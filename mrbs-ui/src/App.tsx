@@ -12,6 +12,7 @@ import ForgotPasswordPage from './pages/ForgotPassword';
 import AdminPage from './pages/Admin';
 import ChangelogPage from './pages/Changelog'
 import LinkTelegramPage from './pages/LinkTelegram';
+import TwoFactorPage from './pages/TwoFactor';
 
 function AppContent() {
 
@@ -42,6 +43,7 @@ function AppContent() {
           <Route element={<PrivateRoutes />}>
             <Route path='/logout' element={<Logout />} />
             <Route path='/change-password' element={<ChangePassword />} />
+            <Route path='/two-factor' element={<TwoFactorPage />} />
             <Route path='/link-telegram' element={<LinkTelegramPage />} />
           </Route>
 
@@ -25,6 +25,7 @@ export const changePasswordSchema = z.object({
     current_password: z.string().min(1, "Current password is required"),
     new_password: z.string().min(8, "Password must be at least 8 characters"),
     confirm_password: z.string().min(1, "Please confirm your password"),
+    revoke_other_sessions: z.boolean(),
 })
     .refine((data) => data.new_password === data.confirm_password, {
         message: "Passwords do not match",
@@ -50,6 +51,7 @@ export function ChangePasswordForm({
         formState: { errors, isSubmitting },
     } = useForm<ChangePasswordValues>({
         resolver: zodResolver(changePasswordSchema),
+        defaultValues: { revoke_other_sessions: true },
     })
 
     // 3. Handle Submit
@@ -59,7 +61,7 @@ export function ChangePasswordForm({
             if (res.status != HttpStatusCode.Ok) {
                 toast.error(res.data.error)
             } else {
-                toast.success("Password changed successfully");
+                toast.success(res.data.message);
                 navigate("/"); // Redirect after success
             }
         } catch (error) {
@@ -118,6 +120,17 @@ export function ChangePasswordForm({
                                 )}
                             </Field>
 
+                            {/* Log out other devices */}
+                            <Field orientation="horizontal">
+                                <input
+                                    id="revokeOtherSessions"
+                                    type="checkbox"
+                                    className="h-4 w-4 accent-primary"
+                                    {...register("revoke_other_sessions")}
+                                />
+                                <FieldLabel htmlFor="revokeOtherSessions">Log out of all other devices</FieldLabel>
+                            </Field>
+
                             {/* Global/Server Errors */}
                             {errors.root && (
                                 <FieldError>{errors.root.message}</FieldError>
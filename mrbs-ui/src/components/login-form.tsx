@@ -15,10 +15,11 @@ import {
   FieldLabel,
 } from "@/components/ui/field"
 import { Input } from "@/components/ui/input"
-import { useState, type FormEvent } from "react"
-import { useNavigate } from "react-router-dom"
-import { loginUser } from "@/services/auth-service"
+import { useEffect, useState, type FormEvent } from "react"
+import { useNavigate, useSearchParams } from "react-router-dom"
+import { getOIDCStatus, loginTwoFactor, loginUser, type OIDCStatus } from "@/services/auth-service"
 import { useSetUser } from "@/context/user-context"
+import { AuthenticatorQRCode, RecoveryCodes } from "@/components/two-factor-setup"
 
 export function LoginForm({
   className,
@@ -29,19 +30,64 @@ export function LoginForm({
   const [password, setPassword] = useState("")
   const [errorMessage, setErrorMessage] = useState("")
   const [isLoading, setIsLoading] = useState(false)
+  // Second step for users with 2FA
+  const [challenge, setChallenge] = useState("")
+  const [code, setCode] = useState("")
+  const [setup, setSetup] = useState<{ secret: string, uri: string } | null>(null)
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([])
+  const [oidc, setOIDC] = useState<OIDCStatus | undefined>()
+  const [searchParams] = useSearchParams();
   const navigate = useNavigate();
   const setUserContext = useSetUser();
 
+  useEffect(() => {
+    getOIDCStatus().then(setOIDC)
+
+    // Coming back from an SSO login that failed, or that still needs the 2FA code
+    const ssoError = searchParams.get("sso_error")
+    if (ssoError) {
+      setErrorMessage(ssoError)
+    }
+    const hashChallenge = new URLSearchParams(window.location.hash.slice(1)).get("challenge")
+    if (hashChallenge) {
+      setChallenge(hashChallenge)
+      window.history.replaceState(null, "", window.location.pathname + window.location.search)
+    }
+  }, [])
+
+  function handleSSOLogin() {
+    const baseURL = import.meta.env.VITE_API_URL || "/api"
+    window.location.href = `${baseURL}/auth/oidc/login${redirect ? `?redirect=${encodeURIComponent(redirect)}` : ""}`
+  }
+
+  function goHome() {
+    navigate({ pathname: "/", search: redirect ? `?date=${redirect}` : "" })
+  }
+
   async function handleSubmit(e: FormEvent) {
     e.preventDefault(); // prevent form from refreshing
     setIsLoading(true);
 
+    const res = challenge ? await loginTwoFactor(challenge, code) : await loginUser(user, password)
+    // Password was correct, ask for the code
+    if (res?.two_factor_required) {
+      setChallenge(res.challenge!)
+      setSetup(res.two_factor_setup_required ? { secret: res.secret!, uri: res.otpauth_uri! } : null)
+      setErrorMessage("")
+      setIsLoading(false)
+      return
+    }
 
-    const res = await loginUser(user, password)
     // Login failed
-    if (!res.success) {
-      setErrorMessage("Incorrect username or password")
+    if (!res?.success) {
+      setErrorMessage(res?.error || "Incorrect username or password")
       setIsLoading(false)
+      // The challenge has expired or was used up, start again from the password
+      if (challenge && !res?.error?.startsWith("Invalid code")) {
+        setChallenge("")
+        setSetup(null)
+        setCode("")
+      }
     } else {
       setUserContext({
         name: res.username,
@@ -49,10 +95,88 @@ export function LoginForm({
         email: res.email,
         level: Number(res.level),
       })
-      navigate({ pathname: "/", search: redirect ? `?date=${redirect}` : "" })
+      // Users who just enrolled in 2FA see their recovery codes first
+      if (res.recovery_codes?.length) {
+        setRecoveryCodes(res.recovery_codes)
+        return
+      }
+      goHome()
     }
   }
 
+  if (recoveryCodes.length > 0) {
+    return (
+      <div className={cn("flex flex-col gap-6", className)} {...props}>
+        <Card>
+          <CardHeader className="text-center">
+            <CardTitle className="text-xl">2FA is on</CardTitle>
+          </CardHeader>
+          <CardContent>
+            <FieldGroup>
+              <RecoveryCodes codes={recoveryCodes} />
+              <Field>
+                <Button type="button" className="cursor-pointer" onClick={goHome}>
+                  I have saved my recovery codes
+                </Button>
+              </Field>
+            </FieldGroup>
+          </CardContent>
+        </Card>
+      </div>
+    )
+  }
+
+  if (challenge) {
+    return (
+      <div className={cn("flex flex-col gap-6", className)} {...props}>
+        <Card>
+          <CardHeader className="text-center">
+            <CardTitle className="text-xl">{setup ? "Set up two-factor authentication" : "Two-factor authentication"}</CardTitle>
+          </CardHeader>
+          <CardContent>
+            <form onSubmit={handleSubmit}>
+              <FieldGroup>
+                {setup && (
+                  <>
+                    <FieldDescription className="text-center">
+                      2FA is required for your account. Add it to an authenticator app to continue.
+                    </FieldDescription>
+                    <AuthenticatorQRCode secret={setup.secret} uri={setup.uri} />
+                  </>
+                )}
+                <Field>
+                  <FieldLabel htmlFor="code">{setup ? "Code from your authenticator" : "Authenticator or recovery code"}</FieldLabel>
+                  <Input
+                    id="code"
+                    autoComplete="one-time-code"
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    required
+                    autoFocus
+                  />
+                </Field>
+                <FieldError>{errorMessage}</FieldError>
+                <Field>
+                  <Button type="submit" disabled={isLoading}>
+                    {isLoading ? "Verifying..." : "Verify"}
+                  </Button>
+                  <Button type="button" variant={"outline"} disabled={isLoading} className={"cursor-pointer"} onClick={() => {
+                    setChallenge("")
+                    setSetup(null)
+                    setCode("")
+                    setErrorMessage("")
+                  }}>
+                    Back
+                  </Button>
+                </Field>
+              </FieldGroup>
+            </form>
+          </CardContent>
+        </Card>
+      </div>
+    )
+  }
+
   return (
     <div className={cn("flex flex-col gap-6", className)} {...props}>
       <Card>
@@ -124,6 +248,11 @@ export function LoginForm({
                     "Login"
                   )}
                 </Button>
+                {oidc?.enabled && (
+                  <Button type="button" variant={"outline"} disabled={isLoading} onClick={handleSSOLogin} className={"cursor-pointer"}>
+                    Login with {oidc.name}
+                  </Button>
+                )}
                 <Button type="reset" variant={"outline"} disabled={isLoading} onClick={() => navigate("/")} className={"cursor-pointer"}>
                   Back to home
                 </Button>
@@ -8,7 +8,7 @@ import {
 import { useUser } from "@/context/user-context"
 import { Link, } from "react-router-dom"
 import { DropdownMenu, DropdownMenuContent, DropdownMenuGroup, DropdownMenuItem, DropdownMenuLabel, DropdownMenuTrigger } from "./ui/dropdown-menu";
-import { LogOutIcon, KeyRound, ChevronDown, CircleQuestionMark, ShieldCheck, ListPlus, Send } from "lucide-react";
+import { LogOutIcon, KeyRound, ChevronDown, CircleQuestionMark, ShieldCheck, ListPlus, Send, Smartphone } from "lucide-react";
 import { useState, useEffect } from "react";
 
 export default function Navbar() {
@@ -62,6 +62,12 @@ export default function Navbar() {
                         Change password
                       </Link>
                     </DropdownMenuItem>
+                    <DropdownMenuItem asChild>
+                      <Link className="cursor-pointer" to={"/two-factor"}>
+                        <Smartphone />
+                        Two-factor authentication
+                      </Link>
+                    </DropdownMenuItem>
                     {
                       user.level > 1 &&
                       <DropdownMenuItem asChild>
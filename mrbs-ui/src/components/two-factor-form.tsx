@@ -0,0 +1,169 @@
+import { cn } from "@/lib/utils"
+import { Button } from "@/components/ui/button"
+import {
+    Card,
+    CardContent,
+    CardDescription,
+    CardHeader,
+    CardTitle,
+} from "@/components/ui/card"
+import {
+    Field,
+    FieldDescription,
+    FieldGroup,
+    FieldLabel,
+} from "@/components/ui/field"
+import { Input } from "@/components/ui/input"
+import { useEffect, useState, type FormEvent } from "react"
+import { toast } from "sonner"
+import { HttpStatusCode } from "axios"
+import {
+    confirmTwoFactor,
+    disableTwoFactor,
+    getTwoFactorStatus,
+    newRecoveryCodes,
+    setupTwoFactor,
+    type TwoFactorStatus,
+} from "@/services/auth-service"
+import { AuthenticatorQRCode, RecoveryCodes } from "@/components/two-factor-setup"
+
+export function TwoFactorForm({
+    className,
+    ...props
+}: React.ComponentProps<"div">) {
+    const [status, setStatus] = useState<TwoFactorStatus | undefined>()
+    const [setup, setSetup] = useState<{ secret: string, uri: string } | null>(null)
+    const [recoveryCodes, setRecoveryCodes] = useState<string[]>([])
+    const [code, setCode] = useState("")
+    const [password, setPassword] = useState("")
+    const [isSubmitting, setIsSubmitting] = useState(false)
+
+    async function refreshStatus() {
+        const res = await getTwoFactorStatus()
+        if (!res) {
+            toast.error("Error fetching 2FA status. Please try again later.")
+            return
+        }
+        setStatus(res)
+    }
+
+    useEffect(() => {
+        refreshStatus()
+    }, [])
+
+    async function handleStartSetup() {
+        const res = await setupTwoFactor()
+        if (res.status != HttpStatusCode.Ok) {
+            toast.error(res.data.error)
+            return
+        }
+        setRecoveryCodes([])
+        setSetup({ secret: res.data.secret, uri: res.data.otpauth_uri })
+    }
+
+    // Confirms a new authenticator, or creates new recovery codes once 2FA is on
+    async function handleCodeSubmit(e: FormEvent) {
+        e.preventDefault()
+        setIsSubmitting(true)
+        const res = setup ? await confirmTwoFactor(code) : await newRecoveryCodes(code)
+        setIsSubmitting(false)
+        if (res.status != HttpStatusCode.Ok) {
+            toast.error(res.data.error)
+            return
+        }
+        toast.success(res.data.message)
+        setSetup(null)
+        setCode("")
+        setRecoveryCodes(res.data.recovery_codes)
+        refreshStatus()
+    }
+
+    async function handleDisable(e: FormEvent) {
+        e.preventDefault()
+        setIsSubmitting(true)
+        const res = await disableTwoFactor(password, code)
+        setIsSubmitting(false)
+        if (res.status != HttpStatusCode.Ok) {
+            toast.error(res.data.error)
+            return
+        }
+        toast.success(res.data.message)
+        setCode("")
+        setPassword("")
+        setRecoveryCodes([])
+        refreshStatus()
+    }
+
+    if (!status) {
+        return null
+    }
+
+    return (
+        <div className={cn("flex flex-col gap-6", className)} {...props}>
+            <Card>
+                <CardHeader className="text-center">
+                    <CardTitle className="text-xl">Two-factor authentication</CardTitle>
+                    <CardDescription>
+                        {status.enabled
+                            ? `On, ${status.recovery_codes_remaining} recovery codes left.`
+                            : "Off. Logging in will also need a code from an authenticator app once 2FA is on."}
+                    </CardDescription>
+                </CardHeader>
+                <CardContent>
+                    <FieldGroup>
+                        {recoveryCodes.length > 0 && <RecoveryCodes codes={recoveryCodes} />}
+
+                        {!status.enabled && !setup && (
+                            <Field>
+                                <Button type="button" className="cursor-pointer" onClick={handleStartSetup}>
+                                    Set up 2FA
+                                </Button>
+                            </Field>
+                        )}
+
+                        {(setup || status.enabled) && (
+                            <form onSubmit={handleCodeSubmit}>
+                                <FieldGroup>
+                                    {setup && <AuthenticatorQRCode secret={setup.secret} uri={setup.uri} />}
+                                    <Field>
+                                        <FieldLabel htmlFor="code">Code from your authenticator</FieldLabel>
+                                        <Input id="code" autoComplete="one-time-code" value={code} onChange={(e) => setCode(e.target.value)} required />
+                                    </Field>
+                                    <Field>
+                                        <Button type="submit" className="cursor-pointer" disabled={isSubmitting}>
+                                            {setup ? "Turn on 2FA" : "Create new recovery codes"}
+                                        </Button>
+                                    </Field>
+                                </FieldGroup>
+                            </form>
+                        )}
+
+                        {status.enabled && !status.required && (
+                            <form onSubmit={handleDisable}>
+                                <FieldGroup>
+                                    <Field>
+                                        <FieldLabel htmlFor="password">Password</FieldLabel>
+                                        <Input id="password" type="password" value={password} onChange={(e) => setPassword(e.target.value)} required />
+                                        <FieldDescription>
+                                            Enter your password and a code above to turn 2FA off.
+                                        </FieldDescription>
+                                    </Field>
+                                    <Field>
+                                        <Button type="submit" variant="destructive" className="cursor-pointer" disabled={isSubmitting}>
+                                            Turn off 2FA
+                                        </Button>
+                                    </Field>
+                                </FieldGroup>
+                            </form>
+                        )}
+                        {status.enabled && status.required && (
+                            <FieldDescription className="text-center">
+                                2FA is mandatory for admins.
+                            </FieldDescription>
+                        )}
+                    </FieldGroup>
+                </CardContent>
+            </Card>
+        </div>
+    )
+}
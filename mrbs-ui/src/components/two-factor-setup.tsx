@@ -0,0 +1,79 @@
+import { useEffect, useRef } from "react";
+import QRCodeStyling from "qr-code-styling";
+import { toast } from "sonner";
+import { Copy } from "lucide-react";
+import { Button } from "@/components/ui/button";
+
+// Shows the secret of a new authenticator as a QR code, with the secret itself for apps that cannot scan
+export function AuthenticatorQRCode({ secret, uri }: { secret: string, uri: string }) {
+  const ref = useRef<HTMLDivElement>(null);
+
+  useEffect(() => {
+    const qrCode = new QRCodeStyling({
+      width: 200,
+      height: 200,
+      type: "svg",
+      data: uri,
+      dotsOptions: { color: "#181C62", type: "square" },
+      backgroundOptions: { color: "#ffffff" },
+    });
+
+    if (ref.current) {
+      ref.current.innerHTML = "";
+      qrCode.append(ref.current);
+    }
+  }, [uri]);
+
+  const handleCopy = async () => {
+    try {
+      await navigator.clipboard.writeText(secret);
+      toast.success("Secret copied to clipboard!");
+    } catch (err) {
+      console.error("Copy failed", err);
+      toast.error("Failed to copy secret.");
+    }
+  };
+
+  return (
+    <div className="flex flex-col items-center gap-3">
+      <div ref={ref} className="rounded-xl bg-white p-2 ring-1 ring-gray-100" />
+      <p className="text-xs text-muted-foreground text-center">
+        Scan with your authenticator app, or enter the secret manually:
+      </p>
+      <div className="flex items-center gap-2">
+        <code className="rounded bg-muted px-2 py-1 text-xs break-all">{secret}</code>
+        <Button type="button" variant="outline" size="sm" className="cursor-pointer" onClick={handleCopy}>
+          <Copy size={16} />
+        </Button>
+      </div>
+    </div>
+  );
+}
+
+// Recovery codes are only shown once, right after they are created
+export function RecoveryCodes({ codes }: { codes: string[] }) {
+  const handleCopy = async () => {
+    try {
+      await navigator.clipboard.writeText(codes.join("\n"));
+      toast.success("Recovery codes copied to clipboard!");
+    } catch (err) {
+      console.error("Copy failed", err);
+      toast.error("Failed to copy recovery codes.");
+    }
+  };
+
+  return (
+    <div className="flex flex-col items-center gap-3">
+      <p className="text-sm text-muted-foreground text-center">
+        Store these recovery codes somewhere safe. Each code can be used once to log in if you lose your authenticator. They will not be shown again.
+      </p>
+      <div className="grid grid-cols-2 gap-x-6 gap-y-1 rounded bg-muted px-4 py-2 font-mono text-sm">
+        {codes.map((code) => <span key={code}>{code}</span>)}
+      </div>
+      <Button type="button" variant="outline" size="sm" className="cursor-pointer" onClick={handleCopy}>
+        <Copy size={16} />
+        Copy codes
+      </Button>
+    </div>
+  );
+}
@@ -3,17 +3,23 @@ import { Card, CardTitle, CardHeader, CardContent } from "@/components/ui/card"
 import { Field, FieldGroup, FieldLabel } from "@/components/ui/field"
 import { Input } from "@/components/ui/input"
 import { useUser } from "@/context/user-context"
-import { resetPassword } from "@/services/auth-service"
+import { confirmResetPassword, resetPassword } from "@/services/auth-service"
 import { HttpStatusCode } from "axios"
 import { useState, type FormEvent } from "react"
-import { useNavigate } from "react-router-dom"
+import { useNavigate, useSearchParams } from "react-router-dom"
 import { toast } from "sonner"
 
 export default function ForgotPasswordPage() {
     const [email, setEmail] = useState("");
+    const [newPassword, setNewPassword] = useState("");
+    const [confirmPassword, setConfirmPassword] = useState("");
     const navigate = useNavigate();
     const user = useUser();
 
+    // Set when the user opens the link from the reset email
+    const [searchParams] = useSearchParams();
+    const token = searchParams.get("token");
+
     // Redirect if user is already logged in 
     if (user) {
         navigate("/")
@@ -22,6 +28,11 @@ export default function ForgotPasswordPage() {
     async function handleSubmit(e: FormEvent) {
         e.preventDefault();
 
+        if (token) {
+            await handleConfirm();
+            return;
+        }
+
         try {
             const res = await resetPassword(email)
             if (res.status == HttpStatusCode.Ok) {
@@ -35,6 +46,28 @@ export default function ForgotPasswordPage() {
         }
     }
 
+    async function handleConfirm() {
+        if (newPassword.length < 8) {
+            toast.error("Password must be at least 8 characters");
+            return;
+        }
+        if (newPassword !== confirmPassword) {
+            toast.error("Passwords do not match");
+            return;
+        }
+
+        try {
+            const res = await confirmResetPassword(token!, newPassword)
+            if (res.status == HttpStatusCode.Ok) {
+                toast.success(res.data.message);
+                navigate("/login");
+            } else {
+                toast.error(res.data.error);
+            }
+        } catch (err) {
+            console.error(err);
+        }
+    }
 
     return (
         <div className="flex h-svh flex-col items-center justify-center gap-6 p-6 md:p-10">
@@ -50,27 +83,60 @@ export default function ForgotPasswordPage() {
                     <Card>
                         <CardHeader className="text-center">
                             <CardTitle className="text-xl">Reset password</CardTitle>
-                            <div>Enter your NTU email address to reset your password.</div>
+                            <div>{token ? "Choose a new password for your account." : "Enter your NTU email address to reset your password."}</div>
                         </CardHeader>
                         <CardContent>
                             <form className="flex flex-col gap-6" onSubmit={handleSubmit}>
-                                <FieldGroup>
-                                    <Field>
-                                        <FieldLabel htmlFor="email">
-                                            Email
-                                        </FieldLabel>
-                                        <Input
-                                            id="email"
-                                            type="email"
-                                            placeholder="abc@e.ntu.edu.sg"
-                                            value={email}
-                                            onChange={(e) => setEmail(e.target.value)}
-                                            required
-                                        />
-                                    </Field>
-                                    <Button type="submit">Reset password</Button>
-                                    <Button type="reset" variant={"outline"} className={"cursor-pointer"} onClick={() => navigate("/login")}>Back</Button>
-                                </FieldGroup>
+                                {token ? (
+                                    <FieldGroup>
+                                        <Field>
+                                            <FieldLabel htmlFor="newPassword">
+                                                New password (min. 8 characters)
+                                            </FieldLabel>
+                                            <Input
+                                                id="newPassword"
+                                                type="password"
+                                                placeholder="Min. 8 characters"
+                                                value={newPassword}
+                                                onChange={(e) => setNewPassword(e.target.value)}
+                                                required
+                                            />
+                                        </Field>
+                                        <Field>
+                                            <FieldLabel htmlFor="confirmPassword">
+                                                Confirm new password
+                                            </FieldLabel>
+                                            <Input
+                                                id="confirmPassword"
+                                                type="password"
+                                                placeholder="Re-enter new password"
+                                                value={confirmPassword}
+                                                onChange={(e) => setConfirmPassword(e.target.value)}
+                                                required
+                                            />
+                                        </Field>
+                                        <Button type="submit">Set new password</Button>
+                                        <Button type="reset" variant={"outline"} className={"cursor-pointer"} onClick={() => navigate("/login")}>Back</Button>
+                                    </FieldGroup>
+                                ) : (
+                                    <FieldGroup>
+                                        <Field>
+                                            <FieldLabel htmlFor="email">
+                                                Email
+                                            </FieldLabel>
+                                            <Input
+                                                id="email"
+                                                type="email"
+                                                placeholder="abc@e.ntu.edu.sg"
+                                                value={email}
+                                                onChange={(e) => setEmail(e.target.value)}
+                                                required
+                                            />
+                                        </Field>
+                                        <Button type="submit">Reset password</Button>
+                                        <Button type="reset" variant={"outline"} className={"cursor-pointer"} onClick={() => navigate("/login")}>Back</Button>
+                                    </FieldGroup>
+                                )}
                             </form>
                         </CardContent>
                     </Card>
@@ -9,6 +9,20 @@ interface LoginResponse {
     display_name: string;
     email: string;
     level: string;
+    // Set when the password was correct but a 2FA code is needed
+    two_factor_required?: boolean;
+    two_factor_setup_required?: boolean;
+    challenge?: string;
+    secret?: string;
+    otpauth_uri?: string;
+    recovery_codes?: string[];
+}
+
+export interface TwoFactorStatus {
+    enabled: boolean;
+    enabled_at: string | null;
+    required: boolean;
+    recovery_codes_remaining: number;
 }
 
 export async function loginUser(user: string, password: string): Promise<LoginResponse> {
@@ -18,7 +32,9 @@ export async function loginUser(user: string, password: string): Promise<LoginRe
     }, {
         headers: {
             "Content-Type": "multipart/form-data"
-        }
+        },
+        // 429 is returned after too many failed logins, with the wait in the error message
+        validateStatus: (status) => status < 500
     }).then(async (res) => res.data).catch((err) => {
         console.error(err);
 
@@ -27,6 +43,57 @@ export async function loginUser(user: string, password: string): Promise<LoginRe
 
 }
 
+// Second step of the login for users with 2FA
+export async function loginTwoFactor(challenge: string, code: string): Promise<LoginResponse> {
+    return await axiosInstance.post("/auth/login/2fa", { challenge: challenge, code: code }, {
+        headers: { "Content-Type": "application/json" },
+        validateStatus: (status) => status < 500
+    }).then(async (res) => res.data).catch((err) => {
+        console.error(err);
+
+        return;
+    })
+}
+
+export async function getTwoFactorStatus(): Promise<TwoFactorStatus | undefined> {
+    return await axiosInstance.get("/auth/2fa")
+        .then(async (res) => res.data)
+        .catch((err) => {
+            console.error(err);
+            return
+        })
+}
+
+export async function setupTwoFactor(): Promise<AxiosResponse> {
+    return await axiosInstance.post("/auth/2fa/setup", null, { validateStatus: (status) => status < 501 })
+}
+
+export async function confirmTwoFactor(code: string): Promise<AxiosResponse> {
+    return await axiosInstance.post("/auth/2fa/confirm", { code: code }, { headers: { "Content-Type": "application/json" }, validateStatus: (status) => status < 501 })
+}
+
+export async function newRecoveryCodes(code: string): Promise<AxiosResponse> {
+    return await axiosInstance.post("/auth/2fa/recovery-codes", { code: code }, { headers: { "Content-Type": "application/json" }, validateStatus: (status) => status < 501 })
+}
+
+export async function disableTwoFactor(password: string, code: string): Promise<AxiosResponse> {
+    return await axiosInstance.post("/auth/2fa/disable", { password: password, code: code }, { headers: { "Content-Type": "application/json" }, validateStatus: (status) => status < 501 })
+}
+
+export interface OIDCStatus {
+    enabled: boolean;
+    name: string;
+}
+
+export async function getOIDCStatus(): Promise<OIDCStatus | undefined> {
+    return await axiosInstance.get("/auth/oidc")
+        .then(async (res) => res.data)
+        .catch((err) => {
+            console.error(err);
+            return
+        })
+}
+
 export async function logoutUser(): Promise<string> {
     return await axiosInstance.post("/auth/logout")
         .then(async (res) => res.data.message)
@@ -51,6 +118,10 @@ export async function resetPassword(email: string): Promise<AxiosResponse> {
     return await axiosInstance.post("/auth/reset-password", { email: email }, { headers: { "Content-Type": "multipart/form-data" }, validateStatus: (status) => status < 501 })
 }
 
+export async function confirmResetPassword(token: string, newPassword: string): Promise<AxiosResponse> {
+    return await axiosInstance.post("/auth/reset-password/confirm", { token: token, new_password: newPassword }, { headers: { "Content-Type": "application/json" }, validateStatus: (status) => status < 501 })
+}
+
 // Ensures current user cookie is valid 
 export async function getCurrentUser(): Promise<LoginResponse> {
     return await axiosInstance.get("/auth/me", { validateStatus: (status) => status < 501 })